@@ -1,5 +1,5 @@
 module github.com/tdx/goa
 
-go 1.13
+go 1.18
 
 require github.com/emirpasic/gods v1.12.0
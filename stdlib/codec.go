@@ -0,0 +1,179 @@
+package stdlib
+
+//
+// Codecs of terms. Terms crossing the process boundary, messages to other
+// nodes and keys and values of disk-backed tables and snapshots, are encoded
+// by Codec. Types of terms, other than basic types and types built of them,
+// are registered with RegisterTerm under the same name on both sides.
+//
+// Pid and Ref are encoded with the node and the environment they belong to.
+// Pid can be encoded only if its environment is a node
+//
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+//
+// Codec is the interface that defines functions to encode and decode terms
+//
+type Codec interface {
+	Encode(t Term) ([]byte, error)
+	Decode(data []byte) (Term, error)
+}
+
+//
+// codecVia is implemented by codecs decoding pids with the local node they
+//  are received by
+//
+type codecVia interface {
+	decodeVia(data []byte, via *Node) (Term, error)
+}
+
+//
+// decodeVia decodes term received by the local node via
+//
+func decodeVia(c Codec, data []byte, via *Node) (Term, error) {
+	if c, ok := c.(codecVia); ok {
+		return c.decodeVia(data, via)
+	}
+	return c.Decode(data)
+}
+
+//
+// registered types of terms
+//
+var termTypes = struct {
+	mu     sync.RWMutex
+	byName map[string]reflect.Type
+	byType map[reflect.Type]string
+}{
+	byName: make(map[string]reflect.Type),
+	byType: make(map[reflect.Type]string),
+}
+
+func init() {
+	RegisterTerm(&Pid{})
+	RegisterTerm(Ref{})
+	RegisterTerm(&LinkPidReq{})
+	RegisterTerm(&UnlinkPidReq{})
+	RegisterTerm(&ExitPidReq{})
+	RegisterTerm(&StopPidReq{})
+	RegisterTerm(&ProcessLinksReq{})
+}
+
+//
+// RegisterTerm registers type of the value for all codecs under the name
+//  used by gob.Register
+//
+func RegisterTerm(v Term) {
+	RegisterTermName(termTypeName(reflect.TypeOf(v)), v)
+}
+
+//
+// RegisterTermName registers type of the value for all codecs under the
+//  name. Registration of pointer type registers type it points to too
+//
+func RegisterTermName(name string, v Term) {
+
+	t := reflect.TypeOf(v)
+	if name == "" || t == nil {
+		panic("stdlib: RegisterTermName with empty name or nil value")
+	}
+
+	gob.RegisterName(name, v)
+
+	termTypes.mu.Lock()
+	defer termTypes.mu.Unlock()
+
+	registerTermType(name, t)
+	if t.Kind() == reflect.Ptr && t.Name() == "" && name[0] == '*' {
+		registerTermType(name[1:], t.Elem())
+	}
+}
+
+func registerTermType(name string, t reflect.Type) {
+	if n, ok := termTypes.byType[t]; ok && n != name {
+		panic(fmt.Sprintf("stdlib: type %s registered as %s and %s", t, n, name))
+	}
+	if u, ok := termTypes.byName[name]; ok && u != t {
+		panic(fmt.Sprintf("stdlib: name %s registered for %s and %s", name, u, t))
+	}
+	termTypes.byName[name] = t
+	termTypes.byType[t] = name
+}
+
+func termTypeByName(name string) (reflect.Type, bool) {
+	termTypes.mu.RLock()
+	defer termTypes.mu.RUnlock()
+
+	t, ok := termTypes.byName[name]
+	return t, ok
+}
+
+func termTypeNameOf(t reflect.Type) (string, bool) {
+	termTypes.mu.RLock()
+	defer termTypes.mu.RUnlock()
+
+	name, ok := termTypes.byType[t]
+	return name, ok
+}
+
+//
+// termTypeName returns name of the type as gob.Register does
+//
+func termTypeName(t reflect.Type) string {
+
+	star := ""
+	if t.Name() == "" && t.Kind() == reflect.Ptr {
+		star = "*"
+		t = t.Elem()
+	}
+	if t.Name() == "" {
+		return t.String()
+	}
+	if t.PkgPath() == "" {
+		return star + t.Name()
+	}
+	return star + t.PkgPath() + "." + t.Name()
+}
+
+// ---------------------------------------------------------------------------
+// Gob
+// ---------------------------------------------------------------------------
+
+//
+// GobCodec returns codec based on encoding/gob
+//
+func GobCodec() Codec {
+	return gobCodec{}
+}
+
+type gobCodec struct{}
+
+//
+// term is boxed to encode interface value
+//
+type gobTerm struct {
+	T Term
+}
+
+func (gobCodec) Encode(t Term) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&gobTerm{t}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gobCodec) Decode(data []byte) (Term, error) {
+	var t gobTerm
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&t); err != nil {
+		return nil, err
+	}
+	return t.T, nil
+}
@@ -0,0 +1,750 @@
+package stdlib
+
+//
+// Compact binary codec. Value is encoded as the description of its type and
+// the value without field names and type ids:
+//
+//  - integers are varints, floats are IEEE 754 bits in little endian
+//  - strings, byte slices, slices and maps are prefixed by length
+//  - structs are exported fields in order of declaration
+//  - pointers and interfaces are prefixed by nil flag or type
+//  - types implementing encoding.BinaryMarshaler are encoded by it
+//  - named types are encoded by name registered with RegisterTerm
+//
+// Both sides must have the same definitions of registered types
+//
+
+import (
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+)
+
+//
+// BinaryCodec returns compact binary codec
+//
+func BinaryCodec() Codec {
+	return binaryCodec{}
+}
+
+type binaryCodec struct{}
+
+const binaryCodecVersion byte = 1
+
+// type tags
+const (
+	binNil byte = iota
+	binBool
+	binInt
+	binInt8
+	binInt16
+	binInt32
+	binInt64
+	binUint
+	binUint8
+	binUint16
+	binUint32
+	binUint64
+	binFloat32
+	binFloat64
+	binString
+	binBytes
+	binSlice
+	binArray
+	binMap
+	binPtr
+	binTerm
+	binPid
+	binRef
+	binNamed
+)
+
+// limits of decoded data
+const (
+	binMaxDepth = 64
+	binMaxArray = 1 << 20
+)
+
+var (
+	binTermType        = reflect.TypeOf((*Term)(nil)).Elem()
+	binPidType         = reflect.TypeOf((*Pid)(nil))
+	binRefType         = reflect.TypeOf(Ref{})
+	binBytesType       = reflect.TypeOf([]byte(nil))
+	binMarshalerType   = reflect.TypeOf((*encoding.BinaryMarshaler)(nil)).Elem()
+	binUnmarshalerType = reflect.TypeOf(
+		(*encoding.BinaryUnmarshaler)(nil)).Elem()
+
+	binBasicTypes = map[byte]reflect.Type{
+		binBool:    reflect.TypeOf(false),
+		binInt:     reflect.TypeOf(int(0)),
+		binInt8:    reflect.TypeOf(int8(0)),
+		binInt16:   reflect.TypeOf(int16(0)),
+		binInt32:   reflect.TypeOf(int32(0)),
+		binInt64:   reflect.TypeOf(int64(0)),
+		binUint:    reflect.TypeOf(uint(0)),
+		binUint8:   reflect.TypeOf(uint8(0)),
+		binUint16:  reflect.TypeOf(uint16(0)),
+		binUint32:  reflect.TypeOf(uint32(0)),
+		binUint64:  reflect.TypeOf(uint64(0)),
+		binFloat32: reflect.TypeOf(float32(0)),
+		binFloat64: reflect.TypeOf(float64(0)),
+		binString:  reflect.TypeOf(""),
+	}
+	binBasicTags = make(map[reflect.Type]byte)
+
+	errBinaryShort = errors.New("binary codec: unexpected end of data")
+)
+
+func init() {
+	for tag, t := range binBasicTypes {
+		binBasicTags[t] = tag
+	}
+}
+
+func (binaryCodec) Encode(t Term) ([]byte, error) {
+
+	e := &binEncoder{buf: []byte{binaryCodecVersion}}
+
+	if t == nil {
+		e.buf = append(e.buf, binNil)
+		return e.buf, nil
+	}
+
+	v := reflect.ValueOf(t)
+	if err := e.typ(v.Type(), 0); err != nil {
+		return nil, err
+	}
+	if err := e.value(v); err != nil {
+		return nil, err
+	}
+
+	return e.buf, nil
+}
+
+func (c binaryCodec) Decode(data []byte) (Term, error) {
+	return c.decodeVia(data, nil)
+}
+
+func (binaryCodec) decodeVia(data []byte, via *Node) (Term, error) {
+
+	if len(data) == 0 {
+		return nil, errBinaryShort
+	}
+	if data[0] != binaryCodecVersion {
+		return nil, fmt.Errorf("binary codec: unknown version %d", data[0])
+	}
+
+	d := &binDecoder{data: data[1:], via: via}
+
+	t, err := d.typ(0)
+	if err != nil {
+		return nil, err
+	}
+
+	var term Term
+	if t != nil {
+		v := reflect.New(t).Elem()
+		if err = d.value(v, 0); err != nil {
+			return nil, err
+		}
+		term = v.Interface()
+	}
+
+	if len(d.data) != 0 {
+		return nil, fmt.Errorf("binary codec: %d bytes after term", len(d.data))
+	}
+
+	return term, nil
+}
+
+// ---------------------------------------------------------------------------
+// Encoder
+// ---------------------------------------------------------------------------
+
+type binEncoder struct {
+	buf []byte
+}
+
+func (e *binEncoder) uvarint(x uint64) {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(b[:], x)
+	e.buf = append(e.buf, b[:n]...)
+}
+
+func (e *binEncoder) varint(x int64) {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutVarint(b[:], x)
+	e.buf = append(e.buf, b[:n]...)
+}
+
+func (e *binEncoder) uint32(x uint32) {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], x)
+	e.buf = append(e.buf, b[:]...)
+}
+
+func (e *binEncoder) uint64(x uint64) {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], x)
+	e.buf = append(e.buf, b[:]...)
+}
+
+func (e *binEncoder) bytes(b []byte) {
+	e.uvarint(uint64(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+func (e *binEncoder) typ(t reflect.Type, depth int) error {
+
+	if depth > binMaxDepth {
+		return fmt.Errorf("binary codec: type %s is too deep", t)
+	}
+
+	switch t {
+	case binPidType:
+		e.buf = append(e.buf, binPid)
+		return nil
+	case binRefType:
+		e.buf = append(e.buf, binRef)
+		return nil
+	case binTermType:
+		e.buf = append(e.buf, binTerm)
+		return nil
+	case binBytesType:
+		e.buf = append(e.buf, binBytes)
+		return nil
+	}
+
+	if name, ok := termTypeNameOf(t); ok {
+		e.buf = append(e.buf, binNamed)
+		e.bytes([]byte(name))
+		return nil
+	}
+	if tag, ok := binBasicTags[t]; ok {
+		e.buf = append(e.buf, tag)
+		return nil
+	}
+	if t.Name() != "" {
+		return fmt.Errorf("binary codec: type %s is not registered", t)
+	}
+
+	switch t.Kind() {
+	case reflect.Slice:
+		e.buf = append(e.buf, binSlice)
+		return e.typ(t.Elem(), depth+1)
+	case reflect.Array:
+		e.buf = append(e.buf, binArray)
+		e.uvarint(uint64(t.Len()))
+		return e.typ(t.Elem(), depth+1)
+	case reflect.Map:
+		e.buf = append(e.buf, binMap)
+		if err := e.typ(t.Key(), depth+1); err != nil {
+			return err
+		}
+		return e.typ(t.Elem(), depth+1)
+	case reflect.Ptr:
+		e.buf = append(e.buf, binPtr)
+		return e.typ(t.Elem(), depth+1)
+	}
+
+	return fmt.Errorf("binary codec: type %s is not supported", t)
+}
+
+func (e *binEncoder) value(v reflect.Value) error {
+
+	t := v.Type()
+
+	switch t {
+	case binPidType:
+		if v.IsNil() {
+			e.buf = append(e.buf, 0)
+			return nil
+		}
+		id, err := v.Interface().(*Pid).nodeID()
+		if err != nil {
+			return err
+		}
+		e.buf = append(e.buf, 1)
+		e.bytes(id)
+		return nil
+
+	case binRefType:
+		e.bytes(v.Interface().(Ref).nodeID())
+		return nil
+	}
+
+	if t.Kind() != reflect.Ptr && t.Kind() != reflect.Interface &&
+		t.Implements(binMarshalerType) &&
+		reflect.PtrTo(t).Implements(binUnmarshalerType) {
+
+		b, err := v.Interface().(encoding.BinaryMarshaler).MarshalBinary()
+		if err != nil {
+			return err
+		}
+		e.bytes(b)
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			e.buf = append(e.buf, 1)
+		} else {
+			e.buf = append(e.buf, 0)
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64:
+		e.varint(v.Int())
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64, reflect.Uintptr:
+		e.uvarint(v.Uint())
+
+	case reflect.Float32:
+		e.uint32(math.Float32bits(float32(v.Float())))
+
+	case reflect.Float64:
+		e.uint64(math.Float64bits(v.Float()))
+
+	case reflect.String:
+		e.uvarint(uint64(v.Len()))
+		e.buf = append(e.buf, v.String()...)
+
+	case reflect.Slice:
+		if v.IsNil() {
+			e.uvarint(0)
+			return nil
+		}
+		e.uvarint(uint64(v.Len()) + 1)
+		if t.Elem().Kind() == reflect.Uint8 {
+			e.buf = append(e.buf, v.Bytes()...)
+			return nil
+		}
+		for i := 0; i < v.Len(); i++ {
+			if err := e.value(v.Index(i)); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := e.value(v.Index(i)); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Map:
+		if v.IsNil() {
+			e.uvarint(0)
+			return nil
+		}
+		e.uvarint(uint64(v.Len()) + 1)
+		iter := v.MapRange()
+		for iter.Next() {
+			if err := e.value(iter.Key()); err != nil {
+				return err
+			}
+			if err := e.value(iter.Value()); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Ptr:
+		if v.IsNil() {
+			e.buf = append(e.buf, 0)
+			return nil
+		}
+		e.buf = append(e.buf, 1)
+		return e.value(v.Elem())
+
+	case reflect.Interface:
+		if v.IsNil() {
+			e.buf = append(e.buf, binNil)
+			return nil
+		}
+		v = v.Elem()
+		if err := e.typ(v.Type(), 0); err != nil {
+			return err
+		}
+		return e.value(v)
+
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if t.Field(i).PkgPath != "" {
+				continue
+			}
+			if err := e.value(v.Field(i)); err != nil {
+				return err
+			}
+		}
+
+	default:
+		return fmt.Errorf("binary codec: type %s is not supported", t)
+	}
+
+	return nil
+}
+
+// ---------------------------------------------------------------------------
+// Decoder
+// ---------------------------------------------------------------------------
+
+type binDecoder struct {
+	data []byte
+	// local node pids are received by
+	via *Node
+}
+
+func (d *binDecoder) byte() (byte, error) {
+	if len(d.data) == 0 {
+		return 0, errBinaryShort
+	}
+	b := d.data[0]
+	d.data = d.data[1:]
+	return b, nil
+}
+
+func (d *binDecoder) uvarint() (uint64, error) {
+	x, n := binary.Uvarint(d.data)
+	if n <= 0 {
+		return 0, errBinaryShort
+	}
+	d.data = d.data[n:]
+	return x, nil
+}
+
+func (d *binDecoder) varint() (int64, error) {
+	x, n := binary.Varint(d.data)
+	if n <= 0 {
+		return 0, errBinaryShort
+	}
+	d.data = d.data[n:]
+	return x, nil
+}
+
+func (d *binDecoder) next(n uint64) ([]byte, error) {
+	if n > uint64(len(d.data)) {
+		return nil, errBinaryShort
+	}
+	b := d.data[:n]
+	d.data = d.data[n:]
+	return b, nil
+}
+
+func (d *binDecoder) bytes() ([]byte, error) {
+	n, err := d.uvarint()
+	if err != nil {
+		return nil, err
+	}
+	return d.next(n)
+}
+
+//
+// count reads length of slice or map, length is limited by size of data
+//
+func (d *binDecoder) count() (int, bool, error) {
+	n, err := d.uvarint()
+	if err != nil {
+		return 0, false, err
+	}
+	if n == 0 {
+		return 0, true, nil
+	}
+	if n-1 > uint64(len(d.data)) {
+		return 0, false, errBinaryShort
+	}
+	return int(n - 1), false, nil
+}
+
+func (d *binDecoder) typ(depth int) (reflect.Type, error) {
+
+	if depth > binMaxDepth {
+		return nil, errors.New("binary codec: type is too deep")
+	}
+
+	tag, err := d.byte()
+	if err != nil {
+		return nil, err
+	}
+
+	if t, ok := binBasicTypes[tag]; ok {
+		return t, nil
+	}
+
+	switch tag {
+	case binNil:
+		if depth > 0 {
+			return nil, errors.New("binary codec: nil type of element")
+		}
+		return nil, nil
+
+	case binBytes:
+		return binBytesType, nil
+	case binTerm:
+		return binTermType, nil
+	case binPid:
+		return binPidType, nil
+	case binRef:
+		return binRefType, nil
+
+	case binSlice:
+		elem, err := d.typ(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return reflect.SliceOf(elem), nil
+
+	case binArray:
+		n, err := d.uvarint()
+		if err != nil {
+			return nil, err
+		}
+		if n > binMaxArray {
+			return nil, fmt.Errorf("binary codec: array of %d elements", n)
+		}
+		elem, err := d.typ(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return reflect.ArrayOf(int(n), elem), nil
+
+	case binMap:
+		key, err := d.typ(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if !key.Comparable() {
+			return nil, fmt.Errorf("binary codec: map key %s", key)
+		}
+		elem, err := d.typ(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return reflect.MapOf(key, elem), nil
+
+	case binPtr:
+		elem, err := d.typ(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return reflect.PtrTo(elem), nil
+
+	case binNamed:
+		name, err := d.bytes()
+		if err != nil {
+			return nil, err
+		}
+		t, ok := termTypeByName(string(name))
+		if !ok {
+			return nil, fmt.Errorf("binary codec: type %s is not registered",
+				name)
+		}
+		return t, nil
+	}
+
+	return nil, fmt.Errorf("binary codec: unknown type tag %d", tag)
+}
+
+func (d *binDecoder) value(v reflect.Value, depth int) error {
+
+	if depth > binMaxDepth {
+		return errors.New("binary codec: value is too deep")
+	}
+
+	t := v.Type()
+
+	switch t {
+	case binPidType:
+		flag, err := d.byte()
+		if err != nil || flag == 0 {
+			return err
+		}
+		id, err := d.bytes()
+		if err != nil {
+			return err
+		}
+		pid := new(Pid)
+		if err = pid.setNodeID(id, d.via); err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(pid))
+		return nil
+
+	case binRefType:
+		id, err := d.bytes()
+		if err != nil {
+			return err
+		}
+		var r Ref
+		if err = r.setNodeID(id); err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(r))
+		return nil
+	}
+
+	if t.Kind() != reflect.Ptr && t.Kind() != reflect.Interface &&
+		t.Implements(binMarshalerType) &&
+		reflect.PtrTo(t).Implements(binUnmarshalerType) {
+
+		b, err := d.bytes()
+		if err != nil {
+			return err
+		}
+		u := v.Addr().Interface().(encoding.BinaryUnmarshaler)
+		return u.UnmarshalBinary(b)
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		b, err := d.byte()
+		if err != nil {
+			return err
+		}
+		if b > 1 {
+			return fmt.Errorf("binary codec: bool %d", b)
+		}
+		v.SetBool(b == 1)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64:
+		x, err := d.varint()
+		if err != nil {
+			return err
+		}
+		if v.OverflowInt(x) {
+			return fmt.Errorf("binary codec: %d overflows %s", x, t)
+		}
+		v.SetInt(x)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64, reflect.Uintptr:
+		x, err := d.uvarint()
+		if err != nil {
+			return err
+		}
+		if v.OverflowUint(x) {
+			return fmt.Errorf("binary codec: %d overflows %s", x, t)
+		}
+		v.SetUint(x)
+
+	case reflect.Float32:
+		b, err := d.next(4)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(float64(math.Float32frombits(
+			binary.LittleEndian.Uint32(b))))
+
+	case reflect.Float64:
+		b, err := d.next(8)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(math.Float64frombits(binary.LittleEndian.Uint64(b)))
+
+	case reflect.String:
+		b, err := d.bytes()
+		if err != nil {
+			return err
+		}
+		v.SetString(string(b))
+
+	case reflect.Slice:
+		n, isNil, err := d.count()
+		if err != nil || isNil {
+			return err
+		}
+		if t.Elem().Kind() == reflect.Uint8 {
+			b, err := d.next(uint64(n))
+			if err != nil {
+				return err
+			}
+			s := reflect.MakeSlice(t, n, n)
+			reflect.Copy(s, reflect.ValueOf(b))
+			v.Set(s)
+			return nil
+		}
+		s := reflect.MakeSlice(t, n, n)
+		for i := 0; i < n; i++ {
+			if err = d.value(s.Index(i), depth+1); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := d.value(v.Index(i), depth+1); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Map:
+		n, isNil, err := d.count()
+		if err != nil || isNil {
+			return err
+		}
+		m := reflect.MakeMapWithSize(t, n)
+		for i := 0; i < n; i++ {
+			key := reflect.New(t.Key()).Elem()
+			if err = d.value(key, depth+1); err != nil {
+				return err
+			}
+			elem := reflect.New(t.Elem()).Elem()
+			if err = d.value(elem, depth+1); err != nil {
+				return err
+			}
+			if k := key; k.Kind() == reflect.Interface && !k.IsNil() &&
+				!k.Elem().Type().Comparable() {
+				return fmt.Errorf("binary codec: map key %s", k.Elem().Type())
+			}
+			m.SetMapIndex(key, elem)
+		}
+		v.Set(m)
+
+	case reflect.Ptr:
+		flag, err := d.byte()
+		if err != nil || flag == 0 {
+			return err
+		}
+		p := reflect.New(t.Elem())
+		if err = d.value(p.Elem(), depth+1); err != nil {
+			return err
+		}
+		v.Set(p)
+
+	case reflect.Interface:
+		et, err := d.typ(0)
+		if err != nil || et == nil {
+			return err
+		}
+		if !et.AssignableTo(t) {
+			return fmt.Errorf("binary codec: %s is not %s", et, t)
+		}
+		ev := reflect.New(et).Elem()
+		if err = d.value(ev, depth+1); err != nil {
+			return err
+		}
+		v.Set(ev)
+
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if t.Field(i).PkgPath != "" {
+				continue
+			}
+			if err := d.value(v.Field(i), depth+1); err != nil {
+				return err
+			}
+		}
+
+	default:
+		return fmt.Errorf("binary codec: type %s is not supported", t)
+	}
+
+	return nil
+}
@@ -0,0 +1,217 @@
+package stdlib
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type codecTestMsg struct {
+	A int
+	B string
+	C []Term
+	T time.Time
+	R Ref
+	P *codecTestMsg
+	n int
+}
+
+type codecTestUnreg struct {
+	A int
+}
+
+func init() {
+	RegisterTerm(&codecTestMsg{})
+}
+
+func TestCodec(t *testing.T) {
+
+	msg := &codecTestMsg{
+		A: -1,
+		B: "b",
+		C: []Term{1, "c", nil},
+		T: time.Unix(1600000000, 5).UTC(),
+		R: MakeRef(),
+		P: &codecTestMsg{A: 2},
+	}
+
+	terms := []Term{
+		nil, true, 0, -100, int64(-1 << 40), uint8(255), uint64(1 << 63),
+		float32(1.5), 3.25, "", "string", []byte("bytes"), []int{1, 2},
+		MakeRef(), msg,
+	}
+	binTerms := []Term{
+		[]Term{1, "a", []Term{}}, map[string]int{"a": 1}, map[Term]Term{1: "a"},
+		[3]int{1, 2, 3}, &msg.A, [][]string{{"a"}, nil}, msg.C, *msg.P,
+	}
+
+	pid, err := NewEnv().GenServerStart(new(ts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pid.Stop()
+
+	codecs := map[string]Codec{
+		"gob":    GobCodec(),
+		"binary": BinaryCodec(),
+	}
+
+	for name, codec := range codecs {
+		t.Run(name, func(t *testing.T) {
+
+			all := terms
+			if name == "binary" {
+				all = append(all, binTerms...)
+			}
+
+			for _, term := range all {
+				data, err := codec.Encode(term)
+				if err != nil {
+					t.Fatalf("encode %#v: %v", term, err)
+				}
+				term2, err := codec.Decode(data)
+				if err != nil {
+					t.Fatalf("decode %#v: %v", term, err)
+				}
+				if !reflect.DeepEqual(term, term2) {
+					t.Fatalf("expected %#v, actual %#v", term, term2)
+				}
+			}
+
+			if _, err := codec.Encode(&codecTestUnreg{}); err == nil {
+				t.Fatal("expected error of unregistered type")
+			}
+			if _, err := codec.Encode(pid); err == nil {
+				t.Fatal("expected error of pid of environment without node")
+			}
+		})
+	}
+}
+
+func TestBinaryCodecBadData(t *testing.T) {
+
+	codec := BinaryCodec()
+
+	data, err := codec.Encode(&codecTestMsg{
+		A: 1, B: "b", C: []Term{"c", []byte{1}}, P: &codecTestMsg{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < len(data); i++ {
+		if _, err = codec.Decode(data[:i]); err == nil {
+			t.Fatalf("expected error of %d bytes of %d", i, len(data))
+		}
+	}
+	if _, err = codec.Decode(append(data, 0)); err == nil {
+		t.Fatal("expected error of trailing byte")
+	}
+
+	bad := [][]byte{
+		{binaryCodecVersion + 1, binNil},
+		{binaryCodecVersion, 0xff},
+		{binaryCodecVersion, binBool, 2},
+		{binaryCodecVersion, binUint8, 0x80, 0x02},
+		{binaryCodecVersion, binMap, binBytes, binInt, 1},
+		{binaryCodecVersion, binSlice, binInt, 0xff, 0xff, 0xff, 0x0f},
+		{binaryCodecVersion, binNamed, 1, 'x'},
+	}
+	for _, b := range bad {
+		if _, err = codec.Decode(b); err == nil {
+			t.Fatalf("expected error of %v", b)
+		}
+	}
+}
+
+func TestCodecNode(t *testing.T) {
+
+	opts := NewNodeOpts().WithCodec(BinaryCodec())
+
+	eA, eB := NewEnv(), NewEnv()
+
+	nA, err := eA.StartNodeOpts("a@codec", "127.0.0.1:0", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nA.Stop()
+	nB, err := eB.StartNodeOpts("b@codec", "127.0.0.1:0", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nB.Stop()
+
+	if _, err = nA.Connect(nB.Addr()); err != nil {
+		t.Fatal(err)
+	}
+
+	srv, err := eB.GenServerStart(new(ts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Stop()
+	if err = srv.Register("srv"); err != nil {
+		t.Fatal(err)
+	}
+
+	remote, err := eA.WhereisName(RemoteName("b@codec", "srv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply, err := remote.Call("ping"); err != nil || reply != "pong" {
+		t.Fatalf("expected pong, actual %v, %v", reply, err)
+	}
+
+	// pid of the node is decoded as the local pid
+	codec := BinaryCodec()
+	data, err := codec.Encode(srv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	term, err := codec.Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pid, ok := term.(*Pid); !ok || !pid.Equal(srv) || pid.Alive() != nil {
+		t.Fatalf("expected %s, actual %v", srv, term)
+	}
+
+	// pid decoded by the connection knows the local node it is received by
+	if term, err = decodeVia(codec, data, nA); err != nil {
+		t.Fatal(err)
+	}
+	if pid, ok := term.(*Pid); !ok || pid.remote == nil || pid.remote.via != nA {
+		t.Fatalf("expected pid received by %s, actual %#v", nA.name, term)
+	}
+	if reply, err := term.(*Pid).Call("ping"); err != nil || reply != "pong" {
+		t.Fatalf("expected pong, actual %v, %v", reply, err)
+	}
+}
+
+func TestCodecGtsSnapshot(t *testing.T) {
+
+	dir := gtsTestDir(t)
+	defer os.RemoveAll(dir)
+
+	opts := NewGtsOpts().WithCodec(BinaryCodec())
+
+	tab := NewSet()
+	for i := 1; i <= 10; i++ {
+		tab.Insert(i, &codecTestMsg{A: i})
+	}
+
+	path := filepath.Join(dir, "binary")
+	if err := GtsTab2File(tab, path, opts); err != nil {
+		t.Fatal(err)
+	}
+	tab2, err := GtsFile2Tab(path, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v, ok := tab2.Lookup(5).(*codecTestMsg); tab2.Size() != 10 || !ok ||
+		v.A != 5 {
+		t.Fatalf("expected 10 objects, actual %d, %#v", tab2.Size(), v)
+	}
+}
@@ -17,6 +17,9 @@ type Env struct {
 	gs  *Pid   // GenServer for Env
 	eGs *envGs // gs state to direct fast access
 
+	// node of the environment, nil until StartNode
+	node atomic.Value
+
 	syncMsgPool   sync.Pool
 	sysMsgPool    sync.Pool
 	replyChanPool sync.Pool
@@ -123,6 +126,12 @@ func (e *Env) spawnObjOpts(
 		return nil, NameEmptyError
 	}
 
+	if opts.regNameErr != nil {
+		return nil, opts.regNameErr
+	}
+
+	opts.behaviour = fmt.Sprintf("%T", gp)
+
 	pid, newPid, err := e.newPid(opts)
 	if err != nil {
 		return nil, err
@@ -132,6 +141,13 @@ func (e *Env) spawnObjOpts(
 		return pid, err
 	}
 
+	if opts.via != nil {
+		if err = opts.via.register(pid); err != nil {
+			e.releasePid(pid, opts)
+			return nil, err
+		}
+	}
+
 	gp.setPid(pid)
 	gp.InitPrepare()
 	gp.SetTracer(opts.tracer)
@@ -145,6 +161,24 @@ func (e *Env) spawnObjOpts(
 	return pid, nil
 }
 
+//
+// releasePid releases pid of the process which is not started: unregisters
+//  its name and makes it not alive
+//
+func (e *Env) releasePid(pid *Pid, opts *SpawnOpts) {
+
+	if opts.Name != nil {
+		if found, err := e.eGs.whereis(opts.Prefix, opts.Name); err == nil &&
+			found == pid {
+
+			_ = e.eGs.unregPidName(opts.Prefix, opts.Name)
+		}
+	}
+
+	pid.onStop(NoProc)
+	close(pid.exitChan)
+}
+
 func (e *Env) newPid(opts *SpawnOpts) (*Pid, bool, error) {
 
 	if e.gs != nil {
@@ -161,7 +195,10 @@ func (e *Env) makeEnvPid(opts *SpawnOpts) *Pid {
 }
 
 func (e *Env) makeEnvPidOpts(opts *SpawnOpts) *Pid {
-	return newPid(0, e, opts.UsrChanSize, opts.SysChanSize)
+	pid := newPid(0, e, opts.UsrChanSize, opts.SysChanSize)
+	pid.setTrace(opts.tracer, opts.traceFlags)
+
+	return pid
 }
 
 //
@@ -174,9 +211,6 @@ func (e *Env) makePid(opts *SpawnOpts) (*Pid, bool, error) {
 		opts = NewSpawnOpts()
 	}
 
-	e.eGs.mu.Lock()
-	defer e.eGs.mu.Unlock()
-
 	return e.eGs.regNewPid(opts)
 }
 
@@ -193,17 +227,12 @@ func (e *Env) regPidName(prefix string, name Term, pid *Pid) error {
 		return NameEmptyError
 	}
 
-	e.eGs.mu.Lock()
-	defer e.eGs.mu.Unlock()
-
-	if prefix == "" {
-		if isNew, _ := e.eGs.regPidName(name, pid); isNew {
-			return nil
-		}
-		return AlreadyRegError
+	// processes of other nodes are registered on their nodes
+	if pid.remote != nil {
+		return BadArgError
 	}
 
-	if isNew, _ := e.eGs.regPidPrefixName(prefix, name, pid); isNew {
+	if isNew, _ := e.eGs.regPidName(prefix, name, pid); isNew {
 		return nil
 	}
 
@@ -217,14 +246,7 @@ func (e *Env) unregPidName(prefix string, name Term) error {
 		return NameEmptyError
 	}
 
-	e.eGs.mu.Lock()
-	defer e.eGs.mu.Unlock()
-
-	if prefix == "" {
-		return e.eGs.unregPidName(name)
-	}
-
-	return e.eGs.unregPidPrefixName(prefix, name)
+	return e.eGs.unregPidName(prefix, name)
 }
 
 func (e *Env) whereis(name Term) (pid *Pid, err error) {
@@ -232,11 +254,7 @@ func (e *Env) whereis(name Term) (pid *Pid, err error) {
 		return nil, NameEmptyError
 	}
 
-	e.eGs.mu.RLock()
-	pid, err = e.eGs.whereis("", name)
-	e.eGs.mu.RUnlock()
-
-	return
+	return e.eGs.whereis("", name)
 }
 
 //
@@ -249,11 +267,7 @@ func (e *Env) whereisPrefix(prefix string, name Term) (pid *Pid, err error) {
 		return nil, PrefixEmptyError
 	}
 
-	e.eGs.mu.RLock()
-	pid, err = e.eGs.whereis(prefix, name)
-	e.eGs.mu.RUnlock()
-
-	return
+	return e.eGs.whereis(prefix, name)
 }
 
 //
@@ -262,11 +276,7 @@ func (e *Env) whereare(prefix string) (RegMap, error) {
 		return nil, PrefixEmptyError
 	}
 
-	e.eGs.mu.RLock()
-	regs, err := e.eGs.whereare(prefix)
-	e.eGs.mu.RUnlock()
-
-	return regs, err
+	return e.eGs.whereare(prefix)
 }
 
 //
@@ -275,9 +285,5 @@ func (e *Env) whereareCount(prefix string) (int, error) {
 		return 0, PrefixEmptyError
 	}
 
-	e.eGs.mu.RLock()
-	n, err := e.eGs.whereareCount(prefix)
-	e.eGs.mu.RUnlock()
-
-	return n, err
+	return e.eGs.whereareCount(prefix)
 }
@@ -23,29 +23,24 @@ type refReg struct {
 type envGs struct {
 	GenServerSys
 
-	// mu guard all envGs fields
-	mu sync.RWMutex
-
 	envUID  uint32
 	nextPid uint64
 	ref     uint64
 
-	// reg names
-	regPrefix map[string]RegMap
-	regName   RegMap
+	// reg names, each shard has own lock
+	reg regShards
 
-	regNamesCount         uint64
-	recreateRegNamesCount uint64
+	// subscribers to register and unregister events
+	subsMu   sync.RWMutex
+	nameSubs map[Ref]*nameSub
 
-	// monitored regName, regPrefix processes
-	regNameByRef map[Ref]*Pid
-	regNameByPid map[*Pid]*refReg
+	// process groups and processes monitored because of groups
+	pgMu       sync.RWMutex
+	pgGroups   map[string]*pgGroup
+	pgPids     map[*Pid]*pgPid
+	pgPidByRef map[Ref]*Pid
 }
 
-const (
-	maxRegNameCount = 10000
-)
-
 //
 // API
 //
@@ -65,50 +60,28 @@ func (gs *envGs) Stat(w io.Writer) {
 }
 
 func (gs *envGs) StatDump(w io.Writer, dumpNames int) {
-	regPrefixLens := make(map[string]int)
-
-	gs.mu.RLock()
-
-	var (
-		regPrefixLen = len(gs.regPrefix)
-		regName      = len(gs.regName)
-		regNameByRef = len(gs.regNameByRef)
-		regNameByPid = len(gs.regNameByPid)
-		links        = len(gs.links)
-	)
-
-	for k, v := range gs.regPrefix {
-		regPrefixLens[k] = len(v)
-	}
 
-	gs.mu.RUnlock()
+	st := gs.reg.stat(dumpNames)
 
-	fmt.Fprintln(w, "regNamesCount        :", gs.regNamesCount)
-	fmt.Fprintln(w, "recreateRegNamesCount:", gs.recreateRegNamesCount)
-	fmt.Fprintln(w, "env links:", links)
-	fmt.Fprintln(w, "regPrefix:", regPrefixLen)
-	for k, v := range regPrefixLens {
+	fmt.Fprintln(w, "regNamesCount        :", st.names+st.prefixNames)
+	// maps of shards are not recreated, line is kept for readers of the dump
+	fmt.Fprintln(w, "recreateRegNamesCount:", 0)
+	fmt.Fprintln(w, "env links:", len(gs.links))
+	fmt.Fprintln(w, "regPrefix:", len(st.prefixes))
+	for k, v := range st.prefixes {
 		fmt.Fprintln(w, "regPrefix:", k, v)
 	}
-	fmt.Fprintln(w, "regName:", regName)
-	fmt.Fprintln(w, "regNameByRef:", regNameByRef)
-	fmt.Fprintln(w, "regNameByPid:", regNameByPid)
+	fmt.Fprintln(w, "regName:", st.names)
+	// one monitor ref of each process with names
+	fmt.Fprintln(w, "regNameByRef:", st.pids)
+	fmt.Fprintln(w, "regNameByPid:", st.pids)
 	fmt.Fprintln(w, "monitors    :", len(gs.Self().monitors))
 	fmt.Fprintln(w, "monitorsByMe:", len(gs.Self().monitorsByMe))
 
 	if dumpNames > 0 {
-		gs.mu.RLock()
-		defer gs.mu.RUnlock()
-
 		fmt.Fprintln(w, dumpNames, "names:")
-
-		i := 0
-		for k := range gs.regName {
-			fmt.Fprintln(w, k)
-			i++
-			if i > dumpNames {
-				break
-			}
+		for _, name := range st.dump {
+			fmt.Fprintln(w, name)
 		}
 	}
 }
@@ -125,19 +98,6 @@ func (gs *envGs) Init(args ...Term) Term {
 	return gs.InitOk()
 }
 
-//
-// func (gs *envGs) HandleInfo(r Term) Term {
-
-// 	switch r := r.(type) {
-
-// 	case *MonitorDownReq:
-// 		gs.unregNameByRef(r.MonitorRef)
-// 		// gs.Self().demonitorByMe(r.MonitorRef)
-// 	}
-
-// 	return gs.NoReply()
-// }
-
 //
 func (gs *envGs) Terminate(reason string) {
 	fmt.Println("env_gs", gs.Self().String(), "terminated:", reason)
@@ -147,44 +107,44 @@ func (gs *envGs) Terminate(reason string) {
 // Locals
 //
 func (gs *envGs) monitorDown(ref Ref, reason string) {
-	gs.unregNameByRef(ref)
+	gs.unregNameByRef(ref, reason)
+	gs.pgDown(ref, reason)
 }
 
 func (gs *envGs) regNewPid(
 	opts *SpawnOpts) (pid *Pid, isNewPid bool, err error) {
-	//
-	// spawn + register
-	//
-	if opts.Name != nil {
-		oldReg, oldPid := gs.isRegisteredPrefixName(opts.Prefix, opts.Name)
-		if oldReg {
-			if opts.returnPidIfRegistered == true {
-				pid = oldPid
-			} else {
-				pid = nil
-				err = AlreadyRegError
-			}
-			return
-		}
+
+	newPid := func() *Pid {
+		pid := newPid(atomic.AddUint64(&gs.nextPid, 1), gs.Self().env,
+			opts.UsrChanSize, opts.SysChanSize)
+		pid.setTrace(opts.tracer, opts.traceFlags)
+		pid.behaviour = opts.behaviour
+		pid.spawnPrefix = opts.Prefix
+		pid.spawnName = spawnName(opts.Prefix, opts.Name)
+		return pid
 	}
 
-	pid = newPid(
-		gs.nextPid+1, gs.Self().env, opts.UsrChanSize, opts.SysChanSize)
+	if opts.Name == nil {
+		return newPid(), true, nil
+	}
 
-	if opts.Name != nil {
-		if opts.Prefix == "" {
-			gs.regPidName(opts.Name, pid)
-		} else {
-			gs.regPidPrefixName(opts.Prefix, opts.Name, pid)
+	//
+	// spawn + register under lock of the name shard
+	//
+	names, unlock := gs.reg.lock(opts.Prefix, opts.Name)
+	defer unlock()
+
+	if oldPid, ok := names[opts.Name]; ok {
+		if opts.returnPidIfRegistered == true {
+			return oldPid, false, nil
 		}
+		return nil, false, AlreadyRegError
 	}
 
-	gs.nextPid++
-
-	isNewPid = true
-	err = nil
+	pid = newPid()
+	gs.regLocked(names, opts.Prefix, opts.Name, pid)
 
-	return
+	return pid, true, nil
 }
 
 //
@@ -196,164 +156,107 @@ func (gs *envGs) newRef() Ref {
 }
 
 //
-// Reg name
+// Reg name, empty prefix for names without prefix
 //
-func (gs *envGs) regPidName(name Term, pid *Pid) (bool, *Pid) {
+func (gs *envGs) regPidName(prefix string, name Term, pid *Pid) (bool, *Pid) {
 
-	if gs.regNamesCount >= maxRegNameCount {
-		// periodically recreate maps
-		gs.recreateRegNames()
-		gs.regNamesCount = 0
-		gs.recreateRegNamesCount++
-	} else {
-		if gs.regName == nil {
-			gs.regName = make(RegMap)
+	names, unlock := gs.reg.lock(prefix, name)
+	defer unlock()
 
-		}
-		if gs.regNameByRef == nil {
-			gs.regNameByRef = make(map[Ref]*Pid)
-			gs.regNameByPid = make(map[*Pid]*refReg)
-		}
+	if oldPid, ok := names[name]; ok {
+		return false, oldPid
 	}
 
-	oldPid, ok := gs.regName[name]
-	if !ok {
-		gs.regName[name] = pid
-
-		gs.monitorPid(pid, "", name)
-		gs.dumpRegs("regPidName")
+	gs.regLocked(names, prefix, name, pid)
 
-		gs.regNamesCount++
-
-		return true, pid
-	}
-
-	return false, oldPid
+	return true, pid
 }
 
-func (gs *envGs) recreateRegNames() {
-	regName := make(RegMap, len(gs.regName))
-	for k, v := range gs.regName {
-		regName[k] = v
-		delete(gs.regName, k)
-	}
-	gs.regName = regName
-
-	regNameByRef := make(map[Ref]*Pid, len(gs.regNameByRef))
-	for k, v := range gs.regNameByRef {
-		regNameByRef[k] = v
-		delete(gs.regNameByRef, k)
-	}
-	gs.regNameByRef = regNameByRef
-
-	regNameByPid := make(map[*Pid]*refReg, len(gs.regNameByPid))
-	for k, v := range gs.regNameByPid {
-		regNameByPid[k] = v
-		delete(gs.regNameByPid, k)
-	}
-	gs.regNameByPid = regNameByPid
+//
+// regLocked registers the name in the locked shard
+//
+func (gs *envGs) regLocked(names RegMap, prefix string, name Term, pid *Pid) {
+	names[name] = pid
 
+	gs.monitorPid(pid, prefix, name)
+	gs.registered(pid, prefix, name)
 }
 
 //
-// Returns BadArgError if name is not a registered name
+// Returns NotRegError if name is not a registered name
 //
-func (gs *envGs) unregPidName(name Term) error {
+func (gs *envGs) unregPidName(prefix string, name Term) error {
 
-	if gs.regName == nil {
-		return NotRegError
-	}
+	names, unlock := gs.reg.lock(prefix, name)
+	defer unlock()
 
-	pid, ok := gs.regName[name]
+	pid, ok := names[name]
 	if !ok {
 		return NotRegError
 	}
 
-	delete(gs.regName, name)
+	delete(names, name)
 
-	gs.demonitorName(pid, "", name)
-	gs.dumpRegs("unregPidName")
+	gs.demonitorName(pid, prefix, name)
+	gs.unregistered(pid, prefix, name, "")
 
 	return nil
 }
 
-func (gs *envGs) unregNameByRef(ref Ref) {
+func (gs *envGs) unregNameByRef(ref Ref, reason string) {
 
-	gs.mu.Lock()
-	defer gs.mu.Unlock()
-
-	if pid, ok := gs.regNameByRef[ref]; ok {
+	reg := gs.reg.takePid(ref)
+	if reg == nil {
+		return
+	}
 
-		if refReg, ok := gs.regNameByPid[pid]; ok {
-			for _, nameReg := range refReg.names {
-				if nameReg.prefix == "" {
-					delete(gs.regName, nameReg.name)
-				} else {
-					delete(gs.regPrefix[nameReg.prefix], nameReg.name)
-				}
-			}
+	for _, nr := range reg.names {
+		names, unlock := gs.reg.lock(nr.prefix, nr.name)
+		if pid, ok := names[nr.name]; ok && pid == reg.pid {
+			delete(names, nr.name)
+			gs.unregistered(pid, nr.prefix, nr.name, reason)
 		}
-
-		delete(gs.regNameByRef, ref)
-		delete(gs.regNameByPid, pid)
+		unlock()
 	}
-
-	gs.dumpRegs("unregNameByRef")
 }
 
 func (gs *envGs) whereis(prefix string, name Term) (*Pid, error) {
 
-	// find by name
-	if prefix == "" {
-
-		if gs.regName == nil {
-			return nil, NotRegError
-		}
-
-		if pid, ok := gs.regName[name]; ok {
-			return pid, nil
-		}
-
-		return nil, NotRegError
-	}
-
-	// find by prefix+name
-	if gs.regPrefix == nil {
-		return nil, NotRegError
-	}
+	names, unlock := gs.reg.rlock(prefix, name)
+	defer unlock()
 
-	if pids, ok := gs.regPrefix[prefix]; ok {
-		if pid, pidOk := pids[name]; pidOk {
-			return pid, nil
-		}
+	if pid, ok := names[name]; ok {
+		return pid, nil
 	}
 
 	return nil, NotRegError
 }
 
-//
 func (gs *envGs) whereare(prefix string) (RegMap, error) {
-	if gs.regPrefix == nil {
+
+	s := gs.reg.prefixShard(prefix)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	pids, ok := s.prefixes[prefix]
+	if !ok {
 		return nil, NotRegError
 	}
 
-	if pids, ok := gs.regPrefix[prefix]; ok {
-		regs := make(RegMap)
-		for k, v := range pids {
-			regs[k] = v
-		}
-		return regs, nil
+	regs := make(RegMap, len(pids))
+	for k, v := range pids {
+		regs[k] = v
 	}
-
-	return nil, NotRegError
+	return regs, nil
 }
 
 func (gs *envGs) whereareCount(prefix string) (int, error) {
-	if gs.regPrefix == nil {
-		return 0, NotRegError
-	}
 
-	if pids, ok := gs.regPrefix[prefix]; ok {
+	s := gs.reg.prefixShard(prefix)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if pids, ok := s.prefixes[prefix]; ok {
 		return len(pids), nil
 	}
 
@@ -361,143 +264,91 @@ func (gs *envGs) whereareCount(prefix string) (int, error) {
 }
 
 //
-// Reg prefix + name
+// Monitor pid, called under lock of the name shard
 //
-func (gs *envGs) regPidPrefixName(
-	prefix string, name Term, pid *Pid) (bool, *Pid) {
-
-	gs.dumpRegs("regPidPrefixName")
-
-	if gs.regPrefix == nil {
-		gs.regPrefix = make(map[string]RegMap)
+func (gs *envGs) monitorPid(pid *Pid, prefix string, name Term) {
 
-	}
-	if _, ok := gs.regPrefix[prefix]; !ok {
-		gs.regPrefix[prefix] = make(RegMap)
-	}
-	if gs.regNameByRef == nil {
-		gs.regNameByRef = make(map[Ref]*Pid)
-		gs.regNameByPid = make(map[*Pid]*refReg)
-	}
+	s := gs.reg.pidShard(pid)
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	oldPid, ok := gs.regPrefix[prefix][name]
+	reg, ok := s.pids[pid]
 	if !ok {
-		gs.regPrefix[prefix][name] = pid
-
-		gs.monitorPid(pid, prefix, name)
-		gs.dumpRegs("regPidPrefixName")
-
-		return true, pid
-	}
+		reg = &regPid{refReg: refReg{ref: gs.newRef()}, pid: pid}
+		// monitor is not added to monitors of envGs process to avoid lock
+		//  contention on it, callback is called anyway
+		pid.monitorMe(gs.pid, reg.ref)
 
-	return false, oldPid
-}
-
-func (gs *envGs) isRegisteredPrefixName(prefix string, name Term) (bool, *Pid) {
-	if name == nil {
-		return false, nil
-	}
-	if prefix == "" {
-		if gs.regName == nil {
-			return false, nil
+		if s.pids == nil {
+			s.pids = make(map[*Pid]*regPid)
 		}
-		pid, ok := gs.regName[name]
-		return ok, pid
+		s.pids[pid] = reg
+		gs.reg.byRef.Store(reg.ref, pid)
 	}
 
-	if gs.regPrefix == nil {
-		return false, nil
-	}
-
-	if _, ok := gs.regPrefix[prefix]; !ok {
-		return false, nil
-	}
-	pid, ok := gs.regPrefix[prefix][name]
-	return ok, pid
+	reg.names = append(reg.names, &nameReg{prefix, name})
 }
 
 //
-// Returns BadArgError if name is not a registered name
+// regName returns first registered name of the process with prefix, "" if
+//  the process has no names
 //
-func (gs *envGs) unregPidPrefixName(prefix string, name Term) error {
+func (gs *envGs) regName(pid *Pid) string {
 
-	if gs.regPrefix == nil {
-		return NotRegError
-	}
+	s := gs.reg.pidShard(pid)
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	pid, ok := gs.regPrefix[prefix][name]
-	if !ok {
-		return NotRegError
+	if reg, ok := s.pids[pid]; ok && len(reg.names) > 0 {
+		nr := reg.names[0]
+		return spawnName(nr.prefix, nr.name)
 	}
 
-	delete(gs.regPrefix[prefix], name)
-
-	gs.demonitorName(pid, prefix, name)
-	gs.dumpRegs("unregPidPrefixName")
-
-	return nil
+	return ""
 }
 
 //
-// Monitor pid
+// Demonitor pid, called under lock of the name shard
 //
-func (gs *envGs) monitorPid(pid *Pid, prefix string, name Term) {
-
-	reg, ok := gs.regNameByPid[pid]
-	if !ok {
-		ref := gs.newRef()
-		pid.monitorMe(gs.pid, ref)
-		gs.pid.monitorByMe(pid, ref)
-
-		gs.regNameByRef[ref] = pid
-
-		reg = &refReg{ref: ref}
-	}
-
-	reg.names = append(reg.names, &nameReg{prefix, name})
-
-	gs.regNameByPid[pid] = reg
-}
-
 func (gs *envGs) demonitorName(pid *Pid, prefix string, name Term) {
 
-	refReg, ok := gs.regNameByPid[pid]
+	s := gs.reg.pidShard(pid)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	reg, ok := s.pids[pid]
 	if !ok {
 		return
 	}
 
-	matchedItem := -1
-	for i := range refReg.names {
-		nameReg := refReg.names[i]
-		if nameReg.prefix == prefix && nameReg.name == name {
-			matchedItem = i
+	for i, nr := range reg.names {
+		if nr.prefix == prefix && nr.name == name {
+			last := len(reg.names) - 1
+			reg.names[i] = reg.names[last]
+			reg.names[last] = nil
+			reg.names = reg.names[:last]
 			break
 		}
 	}
-	if matchedItem != -1 {
-		namesLen := len(refReg.names)
-		if namesLen > 1 {
-			refReg.names[matchedItem] = refReg.names[namesLen-1]
-			refReg.names = refReg.names[:namesLen-1]
-		} else {
-			refReg.names = nil
-		}
+
+	if len(reg.names) == 0 {
+		pid.demonitorMe(reg.ref)
+		gs.reg.byRef.Delete(reg.ref)
+		delete(s.pids, pid)
 	}
+}
 
-	if len(refReg.names) == 0 {
-		gs.DemonitorProcessPid(refReg.ref)
-		delete(gs.regNameByRef, refReg.ref)
-		delete(gs.regNameByPid, pid)
-	} else {
-		gs.regNameByPid[pid] = refReg
+//
+// Trace
+//
+func (pid *Pid) traceRegister(prefix string, name Term) {
+	if t := pid.tracerFor(TraceFlagNames); t != nil {
+		t.Event(&RegisterEvent{pid.traceEvent(), prefix, name})
 	}
 }
 
-func (gs *envGs) dumpRegs(tag string) {
-	if t := gs.Tracer(); t != nil {
-		TraceCall(t, gs.Self(), tag, fmt.Sprintf(
-			"%s:\n regName: %v\n regPrefix: %v\n"+
-				" regNameByRef: %v\n regNameByPid: %v\n",
-			tag, gs.regName, gs.regPrefix, gs.regNameByRef, gs.regNameByPid))
+func (pid *Pid) traceUnregister(prefix string, name Term) {
+	if t := pid.tracerFor(TraceFlagNames); t != nil {
+		t.Event(&UnregisterEvent{pid.traceEvent(), prefix, name})
 	}
 }
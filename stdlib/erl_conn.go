@@ -0,0 +1,358 @@
+package stdlib
+
+//
+// Connection to Erlang node. Packets are prefixed by 4 bytes length, empty
+// packet is a tick. Message is a control message and optional payload
+// encoded by Erlang External Term Format. Call is sent as gen_server call
+// with monitor of the process, the monitor reference is the tag of reply
+//
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// operations of control messages
+const (
+	erlOpSend         = 2
+	erlOpRegSend      = 6
+	erlOpMonitorP     = 19
+	erlOpDemonitorP   = 20
+	erlOpMonitorPExit = 21
+)
+
+const (
+	erlPassThrough  = 112
+	erlTickInterval = 15 * time.Second
+
+	erlGenCall = Atom("$gen_call")
+	erlGenCast = Atom("$gen_cast")
+	// unused field of control messages
+	erlUnused = Atom("")
+)
+
+type erlConn struct {
+	node *Node
+	conn net.Conn
+	r    *bufio.Reader
+	peer string
+
+	wmu sync.Mutex
+
+	mu     sync.Mutex
+	closed bool
+	done   chan struct{}
+	// calls waiting reply by tag
+	calls map[Ref]chan erlReply
+	// monitors of Erlang processes by local processes
+	monitors map[Ref]*Pid
+}
+
+type erlReply struct {
+	term Term
+	err  error
+}
+
+//
+// send sends message to Erlang process, cast is sent as gen_server cast
+//
+func (c *erlConn) send(to *erlProc, ct callType, data Term) error {
+
+	switch ct {
+	case callTypeSys:
+		return BadArgError
+	case callTypeCast:
+		data = Tuple{erlGenCast, data}
+	}
+
+	if to.name != "" {
+		return c.write(
+			Tuple{erlOpRegSend, c.node.pid, erlUnused, to.name}, data)
+	}
+	return c.write(Tuple{erlOpSend, erlUnused, to.pid}, data)
+}
+
+//
+// call calls gen_server, the process is monitored until reply
+//
+func (c *erlConn) call(to *erlProc, ct callType, data Term) (Term, error) {
+
+	if ct != callTypeUsr {
+		return nil, BadArgError
+	}
+
+	from := c.node.pid
+	ref := c.node.env.makeRef()
+	replyChan := make(chan erlReply, 1)
+
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil, NoConnectionError
+	}
+	c.calls[ref] = replyChan
+	c.mu.Unlock()
+
+	err := c.write(Tuple{erlOpMonitorP, from, to.target(), ref})
+	if err == nil {
+		err = c.send(to, callTypeUsr, Tuple{erlGenCall, Tuple{from, ref}, data})
+	}
+	if err != nil {
+		c.mu.Lock()
+		delete(c.calls, ref)
+		c.mu.Unlock()
+		return nil, err
+	}
+
+	var reply erlReply
+	select {
+	case reply = <-replyChan:
+	case <-c.done:
+		return nil, NoConnectionError
+	}
+
+	if reply.err == nil {
+		_ = c.write(Tuple{erlOpDemonitorP, from, to.target(), ref})
+	}
+
+	return reply.term, reply.err
+}
+
+func (c *erlConn) monitor(to *erlProc, by *Pid, ref Ref) {
+
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		go by.demonitorByMe(true, ref, NoConnection)
+		return
+	}
+	c.monitors[ref] = by
+	c.mu.Unlock()
+
+	_ = c.write(Tuple{erlOpMonitorP, by, to.target(), ref})
+}
+
+func (c *erlConn) demonitor(to *erlProc, ref Ref) {
+
+	c.mu.Lock()
+	by, ok := c.monitors[ref]
+	delete(c.monitors, ref)
+	c.mu.Unlock()
+
+	if ok {
+		_ = c.write(Tuple{erlOpDemonitorP, by, to.target(), ref})
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Handlers
+// ---------------------------------------------------------------------------
+
+func (c *erlConn) readLoop() {
+	defer c.close()
+
+	for {
+		var size [4]byte
+		if _, err := io.ReadFull(c.r, size[:]); err != nil {
+			return
+		}
+		n := binary.BigEndian.Uint32(size[:])
+		if n == 0 {
+			continue
+		}
+		if n > nodeMaxFrame {
+			return
+		}
+		packet := make([]byte, n)
+		if _, err := io.ReadFull(c.r, packet); err != nil {
+			return
+		}
+
+		c.handle(packet)
+	}
+}
+
+//
+// handle handles the message, messages of unsupported operations and terms
+//  are dropped
+//
+func (c *erlConn) handle(packet []byte) {
+
+	if packet[0] != erlPassThrough {
+		return
+	}
+
+	ctl, rest, err := etfDecodeTerm(packet[1:], c.node)
+	var msg Term
+	if err == nil && len(rest) > 0 {
+		msg, err = etfDecode(rest, c.node)
+	}
+
+	t, ok := ctl.(Tuple)
+	if err != nil || !ok || len(t) < 3 {
+		return
+	}
+
+	switch op, _ := t[0].(int); op {
+	case erlOpSend:
+		c.handleSend(t[2], msg)
+
+	case erlOpRegSend:
+		if len(t) == 4 {
+			if name, ok := t[3].(Atom); ok {
+				if pid, err := c.node.env.whereis(string(name)); err == nil {
+					_ = pid.Send(msg)
+				}
+			}
+		}
+
+	case erlOpMonitorPExit:
+		if len(t) == 5 {
+			if ref, ok := t[3].(Ref); ok {
+				c.handleDown(ref, t[4])
+			}
+		}
+	}
+}
+
+func (c *erlConn) handleSend(to Term, msg Term) {
+
+	pid, ok := to.(*Pid)
+	if !ok {
+		return
+	}
+
+	if pid.Equal(c.node.pid) {
+		// reply of call
+		if reply, ok := msg.(Tuple); ok && len(reply) == 2 {
+			if ref, ok := reply[0].(Ref); ok {
+				c.reply(ref, erlReply{term: reply[1]})
+			}
+		}
+		return
+	}
+
+	_ = pid.Send(msg)
+}
+
+func (c *erlConn) reply(ref Ref, reply erlReply) bool {
+
+	c.mu.Lock()
+	replyChan, ok := c.calls[ref]
+	delete(c.calls, ref)
+	c.mu.Unlock()
+
+	if ok {
+		replyChan <- reply
+	}
+
+	return ok
+}
+
+func (c *erlConn) handleDown(ref Ref, reason Term) {
+
+	if c.reply(ref, erlReply{err: NoProcError}) {
+		return
+	}
+
+	c.mu.Lock()
+	by, ok := c.monitors[ref]
+	delete(c.monitors, ref)
+	c.mu.Unlock()
+
+	if ok {
+		by.demonitorByMe(true, ref, erlReason(reason))
+	}
+}
+
+func erlReason(reason Term) string {
+	if a, ok := reason.(Atom); ok {
+		return string(a)
+	}
+	return fmt.Sprint(reason)
+}
+
+//
+// tickLoop sends ticks to keep connection alive
+//
+func (c *erlConn) tickLoop() {
+
+	ticker := time.NewTicker(erlTickInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if c.writePacket(make([]byte, 4)) != nil {
+				return
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+func (c *erlConn) close() {
+
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return
+	}
+	c.closed = true
+	close(c.done)
+
+	monitors := c.monitors
+	c.monitors = nil
+	c.calls = nil
+	c.mu.Unlock()
+
+	_ = c.conn.Close()
+	c.node.removeErlConn(c)
+
+	for ref, pid := range monitors {
+		pid.demonitorByMe(true, ref, NoConnection)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Packets
+// ---------------------------------------------------------------------------
+
+//
+// write writes control message with optional payload
+//
+func (c *erlConn) write(ctl Tuple, msg ...Term) error {
+
+	packet := []byte{0, 0, 0, 0, erlPassThrough}
+
+	for _, t := range append([]Term{ctl}, msg...) {
+		data, err := EtfEncode(t)
+		if err != nil {
+			return err
+		}
+		packet = append(packet, data...)
+	}
+	binary.BigEndian.PutUint32(packet, uint32(len(packet)-4))
+
+	return c.writePacket(packet)
+}
+
+func (c *erlConn) writePacket(packet []byte) error {
+
+	c.wmu.Lock()
+	_, err := c.conn.Write(packet)
+	c.wmu.Unlock()
+
+	if err != nil {
+		c.close()
+		return NoConnectionError
+	}
+
+	return nil
+}
@@ -0,0 +1,301 @@
+package stdlib
+
+//
+// Erlang distribution. Node connects to Erlang node as a hidden node: it is
+// not published to other nodes of the Erlang cluster and is not connected to
+// them. Nodes are authenticated by the challenge/response handshake with the
+// cookie shared by nodes.
+//
+// Processes of Erlang node are addressed by ErlName and by pids received from
+// the node. Send sends the message, Cast sends {'$gen_cast', Msg} and Call
+// calls gen_server. Erlang processes send messages to registered processes
+// and to pids of processes of the node they received.
+//
+// Terms are encoded by Erlang External Term Format, see EtfEncode. Links and
+// monitors of local processes by Erlang processes are not supported
+//
+
+import (
+	"bufio"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// distribution flags
+const (
+	erlFlagPublished          = 0x1
+	erlFlagExtendedReferences = 0x4
+	erlFlagDistMonitor        = 0x8
+	erlFlagFunTags            = 0x10
+	erlFlagNewFunTags         = 0x80
+	erlFlagExtendedPidsPorts  = 0x100
+	erlFlagExportPtrTag       = 0x200
+	erlFlagBitBinaries        = 0x400
+	erlFlagNewFloats          = 0x800
+	erlFlagUtf8Atoms          = 0x10000
+	erlFlagMapTag             = 0x20000
+	erlFlagBigCreation        = 0x40000
+	erlFlagHandshake23        = 0x1000000
+	erlFlagUnlinkID           = 0x2000000
+	erlFlagMandatory25Digest  = 0x4000000
+	erlFlagV4NC               = 1 << 34
+
+	// flags of the hidden node
+	erlFlags = erlFlagExtendedReferences | erlFlagDistMonitor |
+		erlFlagFunTags | erlFlagNewFunTags | erlFlagExtendedPidsPorts |
+		erlFlagExportPtrTag | erlFlagBitBinaries | erlFlagNewFloats |
+		erlFlagUtf8Atoms | erlFlagMapTag | erlFlagBigCreation |
+		erlFlagHandshake23 | erlFlagUnlinkID | erlFlagMandatory25Digest |
+		erlFlagV4NC
+
+	// flags of terms encoded by EtfEncode
+	erlRequiredFlags = erlFlagExtendedReferences | erlFlagExtendedPidsPorts |
+		erlFlagNewFloats | erlFlagUtf8Atoms | erlFlagMapTag |
+		erlFlagBigCreation
+)
+
+var errErlHandshake = errors.New("erlang handshake failed")
+
+//
+// ErlConnect connects the node to Erlang node listening distribution port
+//  addr, see epmd -names. Returns name of Erlang node
+//
+func (n *Node) ErlConnect(addr, cookie string) (string, error) {
+
+	conn, err := net.DialTimeout("tcp", addr, nodeHandshakeTimeout)
+	if err != nil {
+		return "", err
+	}
+
+	c := &erlConn{
+		node:     n,
+		conn:     conn,
+		r:        bufio.NewReader(conn),
+		done:     make(chan struct{}),
+		calls:    make(map[Ref]chan erlReply),
+		monitors: make(map[Ref]*Pid),
+	}
+
+	_ = conn.SetDeadline(time.Now().Add(nodeHandshakeTimeout))
+	c.peer, err = c.handshake(cookie)
+	_ = conn.SetDeadline(time.Time{})
+	if err != nil {
+		_ = conn.Close()
+		return "", err
+	}
+
+	if err = n.addErlConn(c); err != nil {
+		_ = conn.Close()
+		return "", err
+	}
+
+	go c.readLoop()
+	go c.tickLoop()
+
+	return c.peer, nil
+}
+
+//
+// ErlDisconnect closes connection to Erlang node
+//
+func (n *Node) ErlDisconnect(node string) error {
+	c := n.erlConn(node)
+	if c == nil {
+		return NoConnectionError
+	}
+	c.close()
+	return nil
+}
+
+//
+// ErlNodes returns names of connected Erlang nodes
+//
+func (n *Node) ErlNodes() []string {
+
+	n.mu.RLock()
+	names := make([]string, 0, len(n.erlConns))
+	for name := range n.erlConns {
+		names = append(names, name)
+	}
+	n.mu.RUnlock()
+
+	sort.Strings(names)
+
+	return names
+}
+
+// ---------------------------------------------------------------------------
+// Locals
+// ---------------------------------------------------------------------------
+
+func (n *Node) addErlConn(c *erlConn) error {
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.stopped {
+		return NoConnectionError
+	}
+	if _, ok := n.erlConns[c.peer]; ok {
+		return AlreadyRegError
+	}
+	n.erlConns[c.peer] = c
+
+	return nil
+}
+
+func (n *Node) removeErlConn(c *erlConn) {
+	n.mu.Lock()
+	if n.erlConns[c.peer] == c {
+		delete(n.erlConns, c.peer)
+	}
+	n.mu.Unlock()
+}
+
+func (n *Node) erlConn(name string) *erlConn {
+	n.mu.RLock()
+	c := n.erlConns[name]
+	n.mu.RUnlock()
+	return c
+}
+
+//
+// erlNodeFor returns local node connected to Erlang node
+//
+func erlNodeFor(name string) *Node {
+
+	nodes.mu.RLock()
+	defer nodes.mu.RUnlock()
+
+	for _, n := range nodes.byName {
+		if n.erlConn(name) != nil {
+			return n
+		}
+	}
+	return nil
+}
+
+//
+// handshake sends name of the node, answers challenge of Erlang node and
+//  checks answer to own challenge
+//
+func (c *erlConn) handshake(cookie string) (string, error) {
+
+	n := c.node
+
+	// send_name
+	m := make([]byte, 15, 15+len(n.name))
+	m[0] = 'N'
+	binary.BigEndian.PutUint64(m[1:], erlFlags)
+	binary.BigEndian.PutUint32(m[9:], n.env.uid)
+	binary.BigEndian.PutUint16(m[13:], uint16(len(n.name)))
+	m = append(m, n.name...)
+	if err := c.writeHandshake(m); err != nil {
+		return "", err
+	}
+
+	// recv_status
+	m, err := c.readHandshake()
+	if err != nil {
+		return "", err
+	}
+	if len(m) < 1 || m[0] != 's' {
+		return "", errErlHandshake
+	}
+	if status := string(m[1:]); status != "ok" && status != "ok_simultaneous" {
+		return "", fmt.Errorf("%v: status %s", errErlHandshake, status)
+	}
+
+	// recv_challenge
+	if m, err = c.readHandshake(); err != nil {
+		return "", err
+	}
+	peer, flags, challenge, err := erlParseChallenge(m)
+	if err != nil {
+		return "", err
+	}
+	if flags&erlRequiredFlags != erlRequiredFlags {
+		return "", fmt.Errorf("%v: flags %#x", errErlHandshake, flags)
+	}
+
+	// send_challenge_reply
+	var own [4]byte
+	if _, err = rand.Read(own[:]); err != nil {
+		return "", err
+	}
+	m = append([]byte{'r'}, own[:]...)
+	m = append(m, erlDigest(cookie, challenge)...)
+	if err = c.writeHandshake(m); err != nil {
+		return "", err
+	}
+
+	// recv_challenge_ack
+	if m, err = c.readHandshake(); err != nil {
+		return "", errErlHandshake
+	}
+	digest := erlDigest(cookie, binary.BigEndian.Uint32(own[:]))
+	if len(m) != 1+len(digest) || m[0] != 'a' ||
+		subtle.ConstantTimeCompare(m[1:], digest) != 1 {
+		return "", errErlHandshake
+	}
+
+	return peer, nil
+}
+
+//
+// erlParseChallenge parses challenge of the node of version 6 or 5
+//
+func erlParseChallenge(m []byte) (string, uint64, uint32, error) {
+
+	switch {
+	case len(m) >= 19 && m[0] == 'N':
+		size := int(binary.BigEndian.Uint16(m[17:]))
+		if len(m) != 19+size {
+			break
+		}
+		return string(m[19:]), binary.BigEndian.Uint64(m[1:]),
+			binary.BigEndian.Uint32(m[9:]), nil
+
+	case len(m) >= 11 && m[0] == 'n':
+		return string(m[11:]), uint64(binary.BigEndian.Uint32(m[3:])),
+			binary.BigEndian.Uint32(m[7:]), nil
+	}
+
+	return "", 0, 0, errErlHandshake
+}
+
+//
+// erlDigest returns digest of the challenge with the cookie
+//
+func erlDigest(cookie string, challenge uint32) []byte {
+	sum := md5.Sum([]byte(cookie + strconv.FormatUint(uint64(challenge), 10)))
+	return sum[:]
+}
+
+func (c *erlConn) writeHandshake(m []byte) error {
+	b := make([]byte, 2, 2+len(m))
+	binary.BigEndian.PutUint16(b, uint16(len(m)))
+	_, err := c.conn.Write(append(b, m...))
+	return err
+}
+
+func (c *erlConn) readHandshake() ([]byte, error) {
+	var size [2]byte
+	if _, err := io.ReadFull(c.r, size[:]); err != nil {
+		return nil, err
+	}
+	m := make([]byte, binary.BigEndian.Uint16(size[:]))
+	if _, err := io.ReadFull(c.r, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
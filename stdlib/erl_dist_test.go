@@ -0,0 +1,383 @@
+package stdlib
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"reflect"
+	"testing"
+)
+
+const testErlPeerCreation = 7
+
+func TestErlDist(t *testing.T) {
+
+	e := NewEnv()
+	n, err := e.StartNode("goa@127.0.0.1", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Stop()
+
+	peer := testErlPeerStart(t, "erl@127.0.0.1", "secret", n.pid)
+	defer peer.ln.Close()
+
+	if _, err = n.ErlConnect(peer.ln.Addr().String(), "wrong"); err == nil {
+		t.Fatal("expected error of wrong cookie")
+	}
+	name, err := n.ErlConnect(peer.ln.Addr().String(), "secret")
+	if err != nil || name != "erl@127.0.0.1" {
+		t.Fatalf("expected erl@127.0.0.1, actual %s, %v", name, err)
+	}
+	if nodes := n.ErlNodes(); !reflect.DeepEqual(nodes, []string{name}) {
+		t.Fatalf("expected [%s], actual %v", name, nodes)
+	}
+	if flags := testErlRecv(t, peer.out); flags.(uint64)&erlFlagPublished != 0 {
+		t.Fatalf("expected hidden node, actual flags %#x", flags)
+	}
+
+	//
+	// call and cast of gen_server
+	//
+	echo, err := e.WhereisName(ErlName(name, "echo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := echo.String(); s != "{echo,erl@127.0.0.1}" {
+		t.Fatalf("expected {echo,erl@127.0.0.1}, actual %s", s)
+	}
+	if reply, err := echo.Call(Atom("hi")); err != nil || reply != Atom("hi") {
+		t.Fatalf("expected hi, actual %v, %v", reply, err)
+	}
+	req := Tuple{1, "a", []Term{2.5}}
+	reply, err := e.CallName(ErlName(name, "echo"), req)
+	if err != nil || !reflect.DeepEqual(reply, req) {
+		t.Fatalf("expected %v, actual %v, %v", req, reply, err)
+	}
+	if err = echo.Cast("c"); err != nil {
+		t.Fatal(err)
+	}
+	if m := testErlRecv(t, peer.out); m != "c" {
+		t.Fatalf("expected cast c, actual %#v", m)
+	}
+	if _, err = e.CallName(ErlName(name, "none"), "hi"); !IsNoProcError(err) {
+		t.Fatalf("expected NoProcError, actual %v", err)
+	}
+	if _, err = echo.CallSys("hi"); !IsBadArgError(err) {
+		t.Fatalf("expected BadArgError, actual %v", err)
+	}
+
+	//
+	// messages from Erlang processes to pid and registered process
+	//
+	out := make(chan Term, 4)
+	fwd, err := e.Spawn(testForwardFunc, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fwd.Stop()
+	if err = fwd.Register("fwd"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = echo.Send(Tuple{Atom("ping"), fwd}); err != nil {
+		t.Fatal(err)
+	}
+	if m := testErlRecv(t, out); m != Atom("pong") {
+		t.Fatalf("expected pong, actual %#v", m)
+	}
+	if err = echo.Send(Tuple{Atom("reg_send"), Atom("fwd")}); err != nil {
+		t.Fatal(err)
+	}
+	if m := testErlRecv(t, out); m != Atom("hello") {
+		t.Fatalf("expected hello, actual %#v", m)
+	}
+
+	// pid of Erlang process
+	if err = echo.Send(Tuple{Atom("whoami"), fwd}); err != nil {
+		t.Fatal(err)
+	}
+	erlPid, ok := testErlRecv(t, out).(*Pid)
+	if !ok || erlPid.Alive() != nil {
+		t.Fatalf("expected pid of Erlang process, actual %v", erlPid)
+	}
+	if err = erlPid.Send("direct"); err != nil {
+		t.Fatal(err)
+	}
+	if m := testErlRecv(t, peer.out); m != "direct" {
+		t.Fatalf("expected direct, actual %#v", m)
+	}
+
+	//
+	// lost connection
+	//
+	mon, err := e.Spawn(testErlMonitorFunc, echo, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mon.Stop()
+	if m := testErlRecv(t, peer.out); m != "monitor" {
+		t.Fatalf("expected monitor, actual %#v", m)
+	}
+
+	if err = n.ErlDisconnect(name); err != nil {
+		t.Fatal(err)
+	}
+	if m := testErlRecv(t, out); m != NoConnection {
+		t.Fatalf("expected down %s, actual %#v", NoConnection, m)
+	}
+	if _, err = echo.Call("hi"); !IsNoConnectionError(err) {
+		t.Fatalf("expected NoConnectionError, actual %v", err)
+	}
+	if err = erlPid.Alive(); !IsNoConnectionError(err) {
+		t.Fatalf("expected NoConnectionError, actual %v", err)
+	}
+}
+
+func testErlMonitorFunc(gp GenProc, args ...Term) error {
+
+	to := args[0].(*Pid)
+	out := args[1].(chan Term)
+
+	gps := gp.(*GenProcSys)
+	gps.Self().RegisterMonitorDownFunc(func(ref Ref, reason string) {
+		out <- reason
+	})
+	gps.MonitorProcessPid(to)
+
+	return testForwardFunc(gp, out)
+}
+
+func testErlRecv(t *testing.T, out chan Term) Term {
+	t.Helper()
+	return testNodeRecv(t, out)
+}
+
+// ---------------------------------------------------------------------------
+// Stand-in Erlang node
+// ---------------------------------------------------------------------------
+
+//
+// testErlPeer is the Erlang node with registered gen_server echo. Flags of
+//  connected nodes, casts, monitors by processes other than caller and
+//  messages to its pid are sent to out
+//
+type testErlPeer struct {
+	t      *testing.T
+	ln     net.Listener
+	name   string
+	cookie string
+	caller *Pid
+	out    chan Term
+}
+
+func testErlPeerStart(
+	t *testing.T, name, cookie string, caller *Pid) *testErlPeer {
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := &testErlPeer{t: t, ln: ln, name: name, cookie: cookie,
+		caller: caller, out: make(chan Term, 16)}
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go p.serve(conn)
+		}
+	}()
+
+	return p
+}
+
+func (p *testErlPeer) serve(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	if !p.handshake(conn, r) {
+		return
+	}
+
+	for {
+		var size [4]byte
+		if _, err := io.ReadFull(r, size[:]); err != nil {
+			return
+		}
+		packet := make([]byte, binary.BigEndian.Uint32(size[:]))
+		if _, err := io.ReadFull(r, packet); err != nil {
+			return
+		}
+		if len(packet) == 0 {
+			continue
+		}
+
+		ctl, rest, err := etfDecodeTerm(packet[1:], nil)
+		if err != nil {
+			p.t.Error(err)
+			return
+		}
+		var msg Term
+		if len(rest) > 0 {
+			if msg, err = EtfDecode(rest); err != nil {
+				p.t.Error(err)
+				return
+			}
+		}
+
+		if !p.handle(conn, ctl.(Tuple), msg) {
+			return
+		}
+	}
+}
+
+func (p *testErlPeer) handshake(conn net.Conn, r *bufio.Reader) bool {
+
+	read := func() []byte {
+		var size [2]byte
+		if _, err := io.ReadFull(r, size[:]); err != nil {
+			return nil
+		}
+		m := make([]byte, binary.BigEndian.Uint16(size[:]))
+		if _, err := io.ReadFull(r, m); err != nil {
+			return nil
+		}
+		return m
+	}
+	write := func(m []byte) {
+		b := make([]byte, 2, 2+len(m))
+		binary.BigEndian.PutUint16(b, uint16(len(m)))
+		conn.Write(append(b, m...))
+	}
+
+	// send_name
+	m := read()
+	if len(m) < 15 || m[0] != 'N' {
+		p.t.Errorf("bad send_name %v", m)
+		return false
+	}
+	flags := binary.BigEndian.Uint64(m[1:])
+	write([]byte("sok"))
+
+	// challenge
+	const challenge = 123456789
+	m = make([]byte, 19)
+	m[0] = 'N'
+	binary.BigEndian.PutUint64(m[1:], erlFlags)
+	binary.BigEndian.PutUint32(m[9:], challenge)
+	binary.BigEndian.PutUint32(m[13:], testErlPeerCreation)
+	binary.BigEndian.PutUint16(m[17:], uint16(len(p.name)))
+	write(append(m, p.name...))
+
+	// challenge reply
+	m = read()
+	if len(m) != 21 || m[0] != 'r' ||
+		!bytes.Equal(m[5:], erlDigest(p.cookie, challenge)) {
+		return false
+	}
+	write(append([]byte{'a'},
+		erlDigest(p.cookie, binary.BigEndian.Uint32(m[1:]))...))
+
+	p.out <- flags
+
+	return true
+}
+
+func (p *testErlPeer) handle(conn net.Conn, ctl Tuple, msg Term) bool {
+
+	send := func(ctl Tuple, msg ...Term) {
+		packet := []byte{0, 0, 0, 0, erlPassThrough}
+		for _, t := range append([]Term{ctl}, msg...) {
+			data, err := EtfEncode(t)
+			if err != nil {
+				p.t.Error(err)
+				return
+			}
+			packet = append(packet, data...)
+		}
+		binary.BigEndian.PutUint32(packet, uint32(len(packet)-4))
+		conn.Write(packet)
+	}
+
+	self := ErlPid{Node: Atom(p.name), ID: 42, Creation: testErlPeerCreation}
+
+	switch ctl[0] {
+	case erlOpMonitorP:
+		if ctl[2] == Atom("none") {
+			send(Tuple{erlOpMonitorPExit, ctl[2], ctl[1], ctl[3], Atom("noproc")})
+		} else if !ctl[1].(*Pid).Equal(p.caller) {
+			p.out <- "monitor"
+		}
+
+	case erlOpDemonitorP:
+
+	case erlOpSend:
+		// message to pid of the node
+		if to := testErlPeerPid(ctl[2]); !reflect.DeepEqual(to, self) {
+			p.t.Errorf("unexpected message to %#v", ctl[2])
+			return false
+		}
+		p.out <- msg
+
+	case erlOpRegSend:
+		if ctl[3] != Atom("echo") {
+			return true
+		}
+		m := msg.(Tuple)
+		switch m[0] {
+		case erlGenCall:
+			from := m[1].(Tuple)
+			send(Tuple{erlOpSend, erlUnused, from[0]}, Tuple{from[1], m[2]})
+		case erlGenCast:
+			p.out <- m[1]
+		case Atom("ping"):
+			send(Tuple{erlOpSend, erlUnused, m[1]}, Atom("pong"))
+		case Atom("reg_send"):
+			send(Tuple{erlOpRegSend, self, erlUnused, m[1]}, Atom("hello"))
+		case Atom("whoami"):
+			send(Tuple{erlOpSend, erlUnused, m[1]}, self)
+		}
+
+	default:
+		p.t.Errorf("unexpected control message %#v", ctl)
+		return false
+	}
+
+	return true
+}
+
+//
+// testErlPeerPid returns pid of Erlang process, it is decoded as *Pid while
+//  the node is connected to the peer in the same process
+//
+func testErlPeerPid(t Term) Term {
+	if pid, ok := t.(*Pid); ok && pid.remote != nil && pid.remote.erl != nil {
+		return pid.remote.erl.pid
+	}
+	return t
+}
+
+func TestErlParseChallenge(t *testing.T) {
+
+	// version 5 of OTP before 23
+	m := []byte{'n', 0, 5, 0, 0, 0, 4, 0, 0, 0, 9}
+	m = append(m, "old@host"...)
+	name, flags, challenge, err := erlParseChallenge(m)
+	if err != nil || name != "old@host" || flags != 4 || challenge != 9 {
+		t.Fatalf("expected old@host, 4, 9, actual %s, %d, %d, %v",
+			name, flags, challenge, err)
+	}
+
+	bad := [][]byte{nil, {'n', 0, 5}, {'N', 0}, {'x', 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}}
+	for _, m := range bad {
+		if _, _, _, err = erlParseChallenge(m); err == nil {
+			t.Fatalf("expected error of %v", m)
+		}
+	}
+}
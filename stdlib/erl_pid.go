@@ -0,0 +1,91 @@
+package stdlib
+
+//
+// Processes of Erlang nodes. Pid of Erlang process is a remote pid of the
+// Erlang node, the process is addressed by the pid or by registered name
+//
+
+//
+// erlProc is the process of Erlang node
+//
+type erlProc struct {
+	pid  ErlPid
+	name Atom
+}
+
+//
+// target returns the process as the field of control message
+//
+func (p *erlProc) target() Term {
+	if p.name != "" {
+		return p.name
+	}
+	return p.pid
+}
+
+//
+// erlPidOf makes pid of process of Erlang node connected to the local node
+//
+func erlPidOf(pid ErlPid, n *Node) *Pid {
+	return &Pid{
+		id: uint64(pid.Serial)<<32 | uint64(pid.ID),
+		remote: &remotePid{
+			node:  string(pid.Node),
+			envID: pid.Creation,
+			via:   n,
+			erl:   &erlProc{pid: pid},
+		},
+	}
+}
+
+func (pid *Pid) erlName() Atom {
+	if pid.remote != nil && pid.remote.erl != nil {
+		return pid.remote.erl.name
+	}
+	return ""
+}
+
+func (r *remotePid) erlConn() (*erlConn, error) {
+	n := r.via
+	if n == nil {
+		n = erlNodeFor(r.node)
+	}
+	if n == nil {
+		return nil, NoConnectionError
+	}
+	if c := n.erlConn(r.node); c != nil {
+		return c, nil
+	}
+	return nil, NoConnectionError
+}
+
+func (r *remotePid) erlSend(ct callType, data Term) error {
+	c, err := r.erlConn()
+	if err != nil {
+		return err
+	}
+	return c.send(r.erl, ct, data)
+}
+
+func (r *remotePid) erlCall(ct callType, data Term) (Term, error) {
+	c, err := r.erlConn()
+	if err != nil {
+		return nil, err
+	}
+	return c.call(r.erl, ct, data)
+}
+
+func (r *remotePid) erlMonitor(by *Pid, ref Ref) {
+	c, err := r.erlConn()
+	if err != nil {
+		go by.demonitorByMe(true, ref, err.Error())
+		return
+	}
+	c.monitor(r.erl, by, ref)
+}
+
+func (r *remotePid) erlDemonitor(ref Ref) {
+	if c, _ := r.erlConn(); c != nil {
+		c.demonitor(r.erl, ref)
+	}
+}
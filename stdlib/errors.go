@@ -7,6 +7,8 @@ type notRegError int
 type alreadyRegError int
 type nameEmptyError int
 type prefixEmptyError int
+type badArgError int
+type noConnectionError int
 
 // Errors constants
 const (
@@ -17,8 +19,12 @@ const (
 	NotRegError      notRegError      = 6
 	NameEmptyError   nameEmptyError   = 7
 	PrefixEmptyError prefixEmptyError = 8
+	BadArgError      badArgError      = 9
 
-	NoProc string = "no_proc"
+	NoConnectionError noConnectionError = 10
+
+	NoProc       string = "no_proc"
+	NoConnection string = "noconnection"
 )
 
 //
@@ -108,6 +114,30 @@ func (e prefixEmptyError) Error() string {
 	return "prefix_empty"
 }
 
+//
+// IsBadArgError checks if error is a BadArgError
+//
+func IsBadArgError(err error) bool {
+	_, ok := err.(badArgError)
+	return ok
+}
+
+func (e badArgError) Error() string {
+	return "badarg"
+}
+
+//
+// IsNoConnectionError checks if error is a NoConnectionError
+//
+func IsNoConnectionError(err error) bool {
+	_, ok := err.(noConnectionError)
+	return ok
+}
+
+func (e noConnectionError) Error() string {
+	return NoConnection
+}
+
 //
 // IsExitNormalError checks if error is an ExitNormalError
 //
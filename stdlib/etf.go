@@ -0,0 +1,1007 @@
+package stdlib
+
+//
+// Erlang External Term Format. Terms are encoded as Erlang terms:
+//
+//  nil                     atom nil
+//  bool                    atoms true and false
+//  Atom                    atom
+//  integers, *big.Int      integer
+//  float32, float64        float
+//  string                  string, list of bytes
+//  []byte                  binary
+//  Tuple                   tuple
+//  other slices, arrays    list
+//  maps                    map
+//  *Pid, ErlPid            pid
+//  Ref, ErlRef             reference
+//
+// Erlang terms are decoded to nil, bool, Atom, int or *big.Int, float64,
+// string, []byte, Tuple, []Term and map[Term]Term. Erlang encodes lists of
+// bytes as strings, so they are decoded to string. Pids and references of
+// the nodes known to this process are decoded to *Pid and Ref, others to
+// ErlPid and ErlRef. Pids of connected Erlang nodes are decoded to *Pid.
+//
+// Compressed terms are decoded. Improper lists, funs, exports, ports and
+// bit binaries are not supported
+//
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"unicode/utf8"
+)
+
+//
+// Atom is the Erlang atom
+//
+type Atom string
+
+//
+// Tuple is the Erlang tuple
+//
+type Tuple []Term
+
+//
+// ErlPid is the pid of Erlang node
+//
+type ErlPid struct {
+	Node     Atom
+	ID       uint32
+	Serial   uint32
+	Creation uint32
+}
+
+//
+// ErlRef is the reference of Erlang node
+//
+type ErlRef struct {
+	Node     Atom
+	Creation uint32
+	ID       []uint32
+}
+
+// tags of terms
+const (
+	etfVersion        = 131
+	etfCompressed     = 80
+	etfNewFloat       = 70
+	etfNewPid         = 88
+	etfNewerReference = 90
+	etfSmallInteger   = 97
+	etfInteger        = 98
+	etfFloat          = 99
+	etfAtom           = 100
+	etfReference      = 101
+	etfPid            = 103
+	etfSmallTuple     = 104
+	etfLargeTuple     = 105
+	etfNil            = 106
+	etfString         = 107
+	etfList           = 108
+	etfBinary         = 109
+	etfSmallBig       = 110
+	etfLargeBig       = 111
+	etfNewReference   = 114
+	etfSmallAtom      = 115
+	etfMap            = 116
+	etfAtomUtf8       = 118
+	etfSmallAtomUtf8  = 119
+)
+
+const (
+	// node of refs of environment without node
+	etfNoNode = "nonode@nohost"
+	// max number of words of reference id
+	etfMaxRefID = 5
+	// bits of pid id in ID field, others are in Serial
+	etfPidIDBits = 15
+	// bits of ref id in the first and the second words
+	etfRefIDBits = 18
+	// max nesting of tuples, lists and maps of decoded term
+	etfMaxDepth = 1024
+)
+
+var errEtfShort = errors.New("etf: unexpected end of data")
+
+//
+// EtfEncode encodes term to Erlang External Term Format
+//
+func EtfEncode(t Term) ([]byte, error) {
+
+	e := &etfEncoder{buf: []byte{etfVersion}}
+	if err := e.term(t); err != nil {
+		return nil, err
+	}
+
+	return e.buf, nil
+}
+
+//
+// EtfDecode decodes term of Erlang External Term Format
+//
+func EtfDecode(data []byte) (Term, error) {
+	return etfDecode(data, nil)
+}
+
+//
+// etfDecode decodes term received by the local node via
+//
+func etfDecode(data []byte, via *Node) (Term, error) {
+
+	t, rest, err := etfDecodeTerm(data, via)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("etf: %d bytes after term", len(rest))
+	}
+
+	return t, nil
+}
+
+//
+// etfDecodeTerm decodes term at the start of data and returns the rest of
+//  data. Compressed term takes the rest of data
+//
+func etfDecodeTerm(data []byte, via *Node) (Term, []byte, error) {
+
+	if len(data) == 0 {
+		return nil, nil, errEtfShort
+	}
+	if data[0] != etfVersion {
+		return nil, nil, fmt.Errorf("etf: unknown version %d", data[0])
+	}
+
+	d := &etfDecoder{data: data[1:], via: via}
+	compressed := len(d.data) > 0 && d.data[0] == etfCompressed
+	if compressed {
+		if err := d.uncompress(); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	t, err := d.term()
+	if err != nil {
+		return nil, nil, err
+	}
+	if compressed && len(d.data) != 0 {
+		return nil, nil, fmt.Errorf("etf: %d bytes after term", len(d.data))
+	}
+
+	return t, d.data, nil
+}
+
+//
+// EtfCodec returns codec of Erlang External Term Format
+//
+func EtfCodec() Codec {
+	return etfCodec{}
+}
+
+type etfCodec struct{}
+
+func (etfCodec) Encode(t Term) ([]byte, error) {
+	return EtfEncode(t)
+}
+
+func (etfCodec) Decode(data []byte) (Term, error) {
+	return EtfDecode(data)
+}
+
+func (etfCodec) decodeVia(data []byte, via *Node) (Term, error) {
+	return etfDecode(data, via)
+}
+
+// ---------------------------------------------------------------------------
+// Encoder
+// ---------------------------------------------------------------------------
+
+type etfEncoder struct {
+	buf []byte
+}
+
+func (e *etfEncoder) uint16(v uint16) {
+	var b [2]byte
+	binary.BigEndian.PutUint16(b[:], v)
+	e.buf = append(e.buf, b[:]...)
+}
+
+func (e *etfEncoder) uint32(v uint32) {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], v)
+	e.buf = append(e.buf, b[:]...)
+}
+
+func (e *etfEncoder) term(t Term) error {
+
+	switch v := t.(type) {
+	case nil:
+		return e.atom("nil")
+	case bool:
+		if v {
+			return e.atom("true")
+		}
+		return e.atom("false")
+	case Atom:
+		return e.atom(string(v))
+	case int:
+		e.int64(int64(v))
+	case int64:
+		e.int64(v)
+	case uint64:
+		e.uint64(v)
+	case *big.Int:
+		if v == nil {
+			return errors.New("etf: nil *big.Int")
+		}
+		e.big(v.Sign() < 0, v.Bytes())
+	case float64:
+		return e.float(v)
+	case string:
+		e.string(v)
+	case []byte:
+		e.buf = append(e.buf, etfBinary)
+		e.uint32(uint32(len(v)))
+		e.buf = append(e.buf, v...)
+	case Tuple:
+		return e.tuple(v)
+	case []Term:
+		return e.list(reflect.ValueOf(v))
+	case map[Term]Term:
+		return e.dict(reflect.ValueOf(v))
+	case *Pid:
+		return e.pid(v)
+	case ErlPid:
+		return e.erlPid(v)
+	case Ref:
+		return e.ref(v)
+	case ErlRef:
+		return e.erlRef(v)
+	default:
+		return e.value(reflect.ValueOf(t))
+	}
+
+	return nil
+}
+
+//
+// value encodes terms of other types by kind
+//
+func (e *etfEncoder) value(v reflect.Value) error {
+
+	switch v.Kind() {
+	case reflect.Bool:
+		return e.term(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64:
+		e.int64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64, reflect.Uintptr:
+		e.uint64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return e.float(v.Float())
+	case reflect.String:
+		e.string(v.String())
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return e.term(v.Bytes())
+		}
+		return e.list(v)
+	case reflect.Array:
+		return e.list(v)
+	case reflect.Map:
+		return e.dict(v)
+	default:
+		return fmt.Errorf("etf: type %s is not supported", v.Type())
+	}
+
+	return nil
+}
+
+func (e *etfEncoder) atom(s string) error {
+
+	if !utf8.ValidString(s) {
+		return fmt.Errorf("etf: atom %q is not utf-8", s)
+	}
+
+	switch {
+	case len(s) <= math.MaxUint8:
+		e.buf = append(e.buf, etfSmallAtomUtf8, byte(len(s)))
+	case len(s) <= math.MaxUint16:
+		e.buf = append(e.buf, etfAtomUtf8)
+		e.uint16(uint16(len(s)))
+	default:
+		return fmt.Errorf("etf: atom of %d bytes", len(s))
+	}
+	e.buf = append(e.buf, s...)
+
+	return nil
+}
+
+func (e *etfEncoder) int64(v int64) {
+
+	switch {
+	case v >= 0 && v <= math.MaxUint8:
+		e.buf = append(e.buf, etfSmallInteger, byte(v))
+	case v >= math.MinInt32 && v <= math.MaxInt32:
+		e.buf = append(e.buf, etfInteger)
+		e.uint32(uint32(v))
+	default:
+		abs := uint64(v)
+		if v < 0 {
+			abs = uint64(-v)
+		}
+		var b [8]byte
+		binary.BigEndian.PutUint64(b[:], abs)
+		e.big(v < 0, b[:])
+	}
+}
+
+func (e *etfEncoder) uint64(v uint64) {
+
+	if v <= math.MaxInt64 {
+		e.int64(int64(v))
+		return
+	}
+
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], v)
+	e.big(false, b[:])
+}
+
+//
+// big encodes integer by sign and big-endian bytes of absolute value
+//
+func (e *etfEncoder) big(neg bool, abs []byte) {
+
+	abs = bytes.TrimLeft(abs, "\x00")
+
+	if len(abs) <= 4 {
+		v := new(big.Int).SetBytes(abs)
+		if neg {
+			v.Neg(v)
+		}
+		if v.Int64() >= math.MinInt32 && v.Int64() <= math.MaxInt32 {
+			e.int64(v.Int64())
+			return
+		}
+	}
+
+	if len(abs) <= math.MaxUint8 {
+		e.buf = append(e.buf, etfSmallBig, byte(len(abs)))
+	} else {
+		e.buf = append(e.buf, etfLargeBig)
+		e.uint32(uint32(len(abs)))
+	}
+	if neg {
+		e.buf = append(e.buf, 1)
+	} else {
+		e.buf = append(e.buf, 0)
+	}
+	for i := len(abs) - 1; i >= 0; i-- {
+		e.buf = append(e.buf, abs[i])
+	}
+}
+
+func (e *etfEncoder) float(v float64) error {
+
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return fmt.Errorf("etf: float %v", v)
+	}
+
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], math.Float64bits(v))
+	e.buf = append(append(e.buf, etfNewFloat), b[:]...)
+
+	return nil
+}
+
+//
+// string encodes string as list of bytes
+//
+func (e *etfEncoder) string(s string) {
+
+	if len(s) <= math.MaxUint16 {
+		e.buf = append(e.buf, etfString)
+		e.uint16(uint16(len(s)))
+		e.buf = append(e.buf, s...)
+		return
+	}
+
+	e.buf = append(e.buf, etfList)
+	e.uint32(uint32(len(s)))
+	for i := 0; i < len(s); i++ {
+		e.buf = append(e.buf, etfSmallInteger, s[i])
+	}
+	e.buf = append(e.buf, etfNil)
+}
+
+func (e *etfEncoder) tuple(t Tuple) error {
+
+	if len(t) <= math.MaxUint8 {
+		e.buf = append(e.buf, etfSmallTuple, byte(len(t)))
+	} else {
+		e.buf = append(e.buf, etfLargeTuple)
+		e.uint32(uint32(len(t)))
+	}
+
+	for _, el := range t {
+		if err := e.term(el); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (e *etfEncoder) list(v reflect.Value) error {
+
+	if v.Len() == 0 {
+		e.buf = append(e.buf, etfNil)
+		return nil
+	}
+
+	e.buf = append(e.buf, etfList)
+	e.uint32(uint32(v.Len()))
+	for i := 0; i < v.Len(); i++ {
+		if err := e.term(v.Index(i).Interface()); err != nil {
+			return err
+		}
+	}
+	e.buf = append(e.buf, etfNil)
+
+	return nil
+}
+
+func (e *etfEncoder) dict(v reflect.Value) error {
+
+	e.buf = append(e.buf, etfMap)
+	e.uint32(uint32(v.Len()))
+
+	iter := v.MapRange()
+	for iter.Next() {
+		if err := e.term(iter.Key().Interface()); err != nil {
+			return err
+		}
+		if err := e.term(iter.Value().Interface()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//
+// pid encodes process id of the node by ID and Serial, creation is id of
+//  the environment
+//
+func (e *etfEncoder) pid(pid *Pid) error {
+
+	if pid == nil {
+		return errors.New("etf: nil *Pid")
+	}
+	if pid.remote != nil && pid.remote.erl != nil {
+		if pid.remote.erl.name != "" {
+			return fmt.Errorf("etf: %s is not a pid", pid)
+		}
+		return e.erlPid(pid.remote.erl.pid)
+	}
+
+	node, envID, err := pid.nodeOf()
+	if err != nil {
+		return err
+	}
+
+	return e.erlPid(ErlPid{
+		Node:     Atom(node),
+		ID:       uint32(pid.id & (1<<etfPidIDBits - 1)),
+		Serial:   uint32(pid.id >> etfPidIDBits),
+		Creation: envID,
+	})
+}
+
+func (e *etfEncoder) erlPid(pid ErlPid) error {
+
+	e.buf = append(e.buf, etfNewPid)
+	if err := e.atom(string(pid.Node)); err != nil {
+		return err
+	}
+	e.uint32(pid.ID)
+	e.uint32(pid.Serial)
+	e.uint32(pid.Creation)
+
+	return nil
+}
+
+func (e *etfEncoder) ref(r Ref) error {
+
+	node := r.nodeName()
+	if node == "" {
+		node = etfNoNode
+	}
+
+	return e.erlRef(ErlRef{
+		Node:     Atom(node),
+		Creation: r.envID,
+		ID: []uint32{
+			uint32(r.id & (1<<etfRefIDBits - 1)),
+			uint32(r.id >> etfRefIDBits),
+			uint32(r.id >> (etfRefIDBits + 32)),
+		},
+	})
+}
+
+func (e *etfEncoder) erlRef(r ErlRef) error {
+
+	if len(r.ID) == 0 || len(r.ID) > etfMaxRefID {
+		return fmt.Errorf("etf: reference of %d words", len(r.ID))
+	}
+
+	e.buf = append(e.buf, etfNewerReference)
+	e.uint16(uint16(len(r.ID)))
+	if err := e.atom(string(r.Node)); err != nil {
+		return err
+	}
+	e.uint32(r.Creation)
+	for _, id := range r.ID {
+		e.uint32(id)
+	}
+
+	return nil
+}
+
+// ---------------------------------------------------------------------------
+// Decoder
+// ---------------------------------------------------------------------------
+
+type etfDecoder struct {
+	data  []byte
+	depth int
+	// local node pids are received by
+	via *Node
+}
+
+func (d *etfDecoder) next(n uint64) ([]byte, error) {
+	if n > uint64(len(d.data)) {
+		return nil, errEtfShort
+	}
+	b := d.data[:n]
+	d.data = d.data[n:]
+	return b, nil
+}
+
+func (d *etfDecoder) byte() (byte, error) {
+	b, err := d.next(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+func (d *etfDecoder) uint16() (uint16, error) {
+	b, err := d.next(2)
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint16(b), nil
+}
+
+func (d *etfDecoder) uint32() (uint32, error) {
+	b, err := d.next(4)
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint32(b), nil
+}
+
+//
+// count reads number of elements, each element takes at least one byte
+//
+func (d *etfDecoder) count() (int, error) {
+	n, err := d.uint32()
+	if err != nil {
+		return 0, err
+	}
+	if uint64(n) > uint64(len(d.data)) {
+		return 0, errEtfShort
+	}
+	return int(n), nil
+}
+
+func (d *etfDecoder) uncompress() error {
+
+	d.data = d.data[1:]
+	size, err := d.uint32()
+	if err != nil {
+		return err
+	}
+	if size > nodeMaxFrame {
+		return fmt.Errorf("etf: uncompressed size %d is too large", size)
+	}
+
+	zr, err := zlib.NewReader(bytes.NewReader(d.data))
+	if err != nil {
+		return fmt.Errorf("etf: %v", err)
+	}
+	defer zr.Close()
+
+	data, err := io.ReadAll(io.LimitReader(zr, int64(size)+1))
+	if err != nil {
+		return fmt.Errorf("etf: %v", err)
+	}
+	if uint64(len(data)) != uint64(size) {
+		return fmt.Errorf("etf: uncompressed %d bytes of %d", len(data), size)
+	}
+	d.data = data
+
+	return nil
+}
+
+func (d *etfDecoder) term() (Term, error) {
+
+	if d.depth++; d.depth > etfMaxDepth {
+		return nil, errors.New("etf: term is too deep")
+	}
+	defer func() { d.depth-- }()
+
+	tag, err := d.byte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tag {
+	case etfSmallInteger:
+		b, err := d.byte()
+		return int(b), err
+
+	case etfInteger:
+		v, err := d.uint32()
+		return int(int32(v)), err
+
+	case etfSmallBig, etfLargeBig:
+		return d.big(tag)
+
+	case etfNewFloat:
+		b, err := d.next(8)
+		if err != nil {
+			return nil, err
+		}
+		return etfFloatTerm(math.Float64frombits(binary.BigEndian.Uint64(b)))
+
+	case etfFloat:
+		b, err := d.next(31)
+		if err != nil {
+			return nil, err
+		}
+		v, err := strconv.ParseFloat(string(bytes.TrimRight(b, "\x00")), 64)
+		if err != nil {
+			return nil, fmt.Errorf("etf: float %q", b)
+		}
+		return etfFloatTerm(v)
+
+	case etfAtom, etfSmallAtom, etfAtomUtf8, etfSmallAtomUtf8:
+		s, err := d.atom(tag)
+		if err != nil {
+			return nil, err
+		}
+		switch s {
+		case "nil":
+			return nil, nil
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+		return Atom(s), nil
+
+	case etfSmallTuple, etfLargeTuple:
+		var n int
+		if tag == etfSmallTuple {
+			b, err := d.byte()
+			if err != nil {
+				return nil, err
+			}
+			n = int(b)
+		} else if n, err = d.count(); err != nil {
+			return nil, err
+		}
+		t := make(Tuple, n)
+		for i := range t {
+			if t[i], err = d.term(); err != nil {
+				return nil, err
+			}
+		}
+		return t, nil
+
+	case etfNil:
+		return []Term{}, nil
+
+	case etfString:
+		n, err := d.uint16()
+		if err != nil {
+			return nil, err
+		}
+		b, err := d.next(uint64(n))
+		return string(b), err
+
+	case etfList:
+		n, err := d.count()
+		if err != nil {
+			return nil, err
+		}
+		l := make([]Term, n)
+		for i := range l {
+			if l[i], err = d.term(); err != nil {
+				return nil, err
+			}
+		}
+		if tail, err := d.byte(); err != nil || tail != etfNil {
+			if err == nil {
+				err = errors.New("etf: improper list is not supported")
+			}
+			return nil, err
+		}
+		return l, nil
+
+	case etfBinary:
+		n, err := d.uint32()
+		if err != nil {
+			return nil, err
+		}
+		b, err := d.next(uint64(n))
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte{}, b...), nil
+
+	case etfMap:
+		return d.dict()
+
+	case etfPid, etfNewPid:
+		return d.pid(tag)
+
+	case etfReference, etfNewReference, etfNewerReference:
+		return d.ref(tag)
+	}
+
+	return nil, fmt.Errorf("etf: tag %d is not supported", tag)
+}
+
+func etfFloatTerm(v float64) (Term, error) {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return nil, fmt.Errorf("etf: float %v", v)
+	}
+	return v, nil
+}
+
+//
+// big decodes integer to int if it fits, otherwise to *big.Int
+//
+func (d *etfDecoder) big(tag byte) (Term, error) {
+
+	var n uint64
+	if tag == etfSmallBig {
+		b, err := d.byte()
+		if err != nil {
+			return nil, err
+		}
+		n = uint64(b)
+	} else {
+		v, err := d.uint32()
+		if err != nil {
+			return nil, err
+		}
+		n = uint64(v)
+	}
+
+	sign, err := d.byte()
+	if err != nil {
+		return nil, err
+	}
+	le, err := d.next(n)
+	if err != nil {
+		return nil, err
+	}
+
+	abs := make([]byte, len(le))
+	for i, b := range le {
+		abs[len(abs)-1-i] = b
+	}
+
+	v := new(big.Int).SetBytes(abs)
+	if sign != 0 {
+		v.Neg(v)
+	}
+	if v.IsInt64() && v.Int64() >= math.MinInt && v.Int64() <= math.MaxInt {
+		return int(v.Int64()), nil
+	}
+
+	return v, nil
+}
+
+//
+// atom decodes atom of tag to utf-8 string
+//
+func (d *etfDecoder) atom(tag byte) (string, error) {
+
+	var (
+		n   uint64
+		err error
+	)
+	switch tag {
+	case etfSmallAtom, etfSmallAtomUtf8:
+		var b byte
+		b, err = d.byte()
+		n = uint64(b)
+	case etfAtom, etfAtomUtf8:
+		var v uint16
+		v, err = d.uint16()
+		n = uint64(v)
+	default:
+		return "", fmt.Errorf("etf: tag %d is not atom", tag)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	b, err := d.next(n)
+	if err != nil {
+		return "", err
+	}
+
+	if tag == etfAtom || tag == etfSmallAtom {
+		// latin-1
+		r := make([]rune, len(b))
+		for i, c := range b {
+			r[i] = rune(c)
+		}
+		return string(r), nil
+	}
+
+	if !utf8.Valid(b) {
+		return "", fmt.Errorf("etf: atom %q is not utf-8", b)
+	}
+
+	return string(b), nil
+}
+
+func (d *etfDecoder) node() (string, error) {
+	tag, err := d.byte()
+	if err != nil {
+		return "", err
+	}
+	return d.atom(tag)
+}
+
+func (d *etfDecoder) dict() (Term, error) {
+
+	n, err := d.count()
+	if err != nil {
+		return nil, err
+	}
+
+	m := make(map[Term]Term, n)
+	for i := 0; i < n; i++ {
+		k, err := d.term()
+		if err != nil {
+			return nil, err
+		}
+		if t := reflect.TypeOf(k); t != nil &&
+			(!t.Comparable() || t.Kind() == reflect.Ptr) {
+			return nil, fmt.Errorf("etf: map key of type %s is not supported", t)
+		}
+		if m[k], err = d.term(); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+func (d *etfDecoder) pid(tag byte) (Term, error) {
+
+	node, err := d.node()
+	if err != nil {
+		return nil, err
+	}
+
+	var pid ErlPid
+	pid.Node = Atom(node)
+	if pid.ID, err = d.uint32(); err != nil {
+		return nil, err
+	}
+	if pid.Serial, err = d.uint32(); err != nil {
+		return nil, err
+	}
+	if tag == etfPid {
+		var b byte
+		b, err = d.byte()
+		pid.Creation = uint32(b)
+	} else {
+		pid.Creation, err = d.uint32()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if d.via != nil && d.via.erlConn(node) != nil {
+		return erlPidOf(pid, d.via), nil
+	}
+	if n := erlNodeFor(node); n != nil {
+		return erlPidOf(pid, n), nil
+	}
+	if nodeFor(node) == nil {
+		return pid, nil
+	}
+
+	p := new(Pid)
+	p.setNode(node, pid.Creation,
+		uint64(pid.Serial)<<etfPidIDBits|uint64(pid.ID&(1<<etfPidIDBits-1)),
+		d.via)
+
+	return p, nil
+}
+
+func (d *etfDecoder) ref(tag byte) (Term, error) {
+
+	n := uint16(1)
+	if tag != etfReference {
+		var err error
+		if n, err = d.uint16(); err != nil {
+			return nil, err
+		}
+		if n == 0 || n > etfMaxRefID {
+			return nil, fmt.Errorf("etf: reference of %d words", n)
+		}
+	}
+
+	node, err := d.node()
+	if err != nil {
+		return nil, err
+	}
+
+	r := ErlRef{Node: Atom(node), ID: make([]uint32, n)}
+
+	if tag == etfReference {
+		if r.ID[0], err = d.uint32(); err != nil {
+			return nil, err
+		}
+	}
+	if tag == etfNewerReference {
+		r.Creation, err = d.uint32()
+	} else {
+		var b byte
+		b, err = d.byte()
+		r.Creation = uint32(b)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if tag != etfReference {
+		for i := range r.ID {
+			if r.ID[i], err = d.uint32(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if len(r.ID) != 3 || nodeFor(node) == nil {
+		return r, nil
+	}
+
+	var ref Ref
+	ref.setNode(node, r.Creation,
+		uint64(r.ID[0]&(1<<etfRefIDBits-1))|uint64(r.ID[1])<<etfRefIDBits|
+			uint64(r.ID[2])<<(etfRefIDBits+32))
+
+	return ref, nil
+}
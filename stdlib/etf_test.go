@@ -0,0 +1,328 @@
+package stdlib
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"math"
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+//
+// etfGolden are terms as encoded by term_to_binary/1 of Erlang/OTP 26, old
+//  formats are decoded only
+//
+var etfGolden = []struct {
+	name   string
+	data   []byte
+	term   Term
+	decode bool
+}{
+	{"small integer", []byte{131, 97, 1}, 1, false},
+	{"integer", []byte{131, 98, 0, 0, 1, 44}, 300, false},
+	{"negative integer", []byte{131, 98, 255, 255, 255, 255}, -1, false},
+	{"small big",
+		[]byte{131, 110, 6, 0, 0, 0, 0, 0, 0, 1}, 1 << 40, false},
+	{"bigint",
+		[]byte{131, 110, 9, 1, 0, 0, 0, 0, 0, 0, 0, 0, 1},
+		new(big.Int).Neg(new(big.Int).Lsh(big.NewInt(1), 64)), false},
+	{"float",
+		[]byte{131, 70, 63, 248, 0, 0, 0, 0, 0, 0}, 1.5, false},
+	{"atom", []byte{131, 119, 2, 'o', 'k'}, Atom("ok"), false},
+	{"true", []byte{131, 119, 4, 't', 'r', 'u', 'e'}, true, false},
+	{"nil", []byte{131, 119, 3, 'n', 'i', 'l'}, nil, false},
+	{"utf-8 atom",
+		[]byte{131, 119, 2, 0xc3, 0xa9}, Atom("é"), false},
+	{"tuple",
+		[]byte{131, 104, 2, 119, 2, 'o', 'k', 97, 1},
+		Tuple{Atom("ok"), 1}, false},
+	{"string", []byte{131, 107, 0, 3, 'a', 'b', 'c'}, "abc", false},
+	{"binary",
+		[]byte{131, 109, 0, 0, 0, 3, 1, 2, 3}, []byte{1, 2, 3}, false},
+	{"list",
+		[]byte{131, 108, 0, 0, 0, 2, 97, 1, 119, 1, 'a', 106},
+		[]Term{1, Atom("a")}, false},
+	{"empty list", []byte{131, 106}, []Term{}, false},
+	{"list of bytes",
+		[]byte{131, 104, 2, 107, 0, 3, 1, 2, 3, 109, 0, 0, 0, 0},
+		Tuple{"\x01\x02\x03", []byte{}}, false},
+	{"map",
+		[]byte{131, 116, 0, 0, 0, 1, 119, 1, 'a', 97, 1},
+		map[Term]Term{Atom("a"): 1}, false},
+	{"pid",
+		append(append([]byte{131, 88, 119, 13}, etfNoNode...),
+			0, 0, 0, 80, 0, 0, 0, 0, 0, 0, 0, 0),
+		ErlPid{Node: etfNoNode, ID: 80}, false},
+	{"reference",
+		append(append([]byte{131, 90, 0, 3, 119, 13}, etfNoNode...),
+			0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 2, 0, 0, 0, 3),
+		ErlRef{Node: etfNoNode, ID: []uint32{1, 2, 3}}, false},
+
+	// formats of older releases
+	{"latin-1 atom", []byte{131, 100, 0, 1, 0xe9}, Atom("é"), true},
+	{"small latin-1 atom", []byte{131, 115, 2, 'o', 'k'}, Atom("ok"), true},
+	{"old float",
+		append([]byte("\x83c1.50000000000000000000e+00"), 0, 0, 0, 0, 0),
+		1.5, true},
+	{"old pid",
+		append(append([]byte{131, 103, 100, 0, 13}, etfNoNode...),
+			0, 0, 0, 80, 0, 0, 0, 0, 0),
+		ErlPid{Node: etfNoNode, ID: 80}, true},
+	{"new reference",
+		append(append([]byte{131, 114, 0, 1, 100, 0, 13}, etfNoNode...),
+			0, 0, 0, 0, 7),
+		ErlRef{Node: etfNoNode, ID: []uint32{7}}, true},
+}
+
+func TestEtfGolden(t *testing.T) {
+
+	for _, g := range etfGolden {
+		term, err := EtfDecode(g.data)
+		if err != nil {
+			t.Fatalf("%s: %v", g.name, err)
+		}
+		if !reflect.DeepEqual(term, g.term) {
+			t.Fatalf("%s: expected %#v, actual %#v", g.name, g.term, term)
+		}
+		if g.decode {
+			continue
+		}
+
+		data, err := EtfEncode(g.term)
+		if err != nil {
+			t.Fatalf("%s: %v", g.name, err)
+		}
+		if !bytes.Equal(data, g.data) {
+			t.Fatalf("%s: expected %v, actual %v", g.name, g.data, data)
+		}
+	}
+}
+
+func TestEtfEncode(t *testing.T) {
+
+	type myInt int
+
+	long := make([]Term, math.MaxUint16+1)
+	for i := range long {
+		long[i] = 0
+	}
+
+	terms := []struct {
+		term, decoded Term
+	}{
+		{false, false},
+		{int8(-5), -5},
+		{uint8(255), 255},
+		{myInt(256), 256},
+		{int64(math.MinInt32), math.MinInt32},
+		{int64(math.MinInt32 - 1), math.MinInt32 - 1},
+		{int64(math.MinInt64), math.MinInt64},
+		{uint64(math.MaxUint64),
+			new(big.Int).SetUint64(math.MaxUint64)},
+		{big.NewInt(-5), -5},
+		{float32(0.5), 0.5},
+		{"", ""},
+		{[]int{1, 2}, []Term{1, 2}},
+		{[2]string{"a", "b"}, []Term{"a", "b"}},
+		{[]Term(nil), []Term{}},
+		{map[string]int{"a": 1}, map[Term]Term{"a": 1}},
+		{Tuple{}, Tuple{}},
+		{Tuple{Atom("a"), Tuple{nil, []byte{}}},
+			Tuple{Atom("a"), Tuple{nil, []byte{}}}},
+		{string(make([]byte, len(long))), long},
+	}
+
+	for _, tt := range terms {
+		data, err := EtfEncode(tt.term)
+		if err != nil {
+			t.Fatalf("encode %#v: %v", tt.term, err)
+		}
+		term, err := EtfDecode(data)
+		if err != nil {
+			t.Fatalf("decode %#v: %v", tt.term, err)
+		}
+		if !reflect.DeepEqual(term, tt.decoded) {
+			t.Fatalf("expected %#v, actual %#v", tt.decoded, term)
+		}
+	}
+
+	bad := []Term{
+		math.NaN(), math.Inf(1), Atom("\xff"), struct{}{}, (*Pid)(nil),
+		ErlRef{Node: "a"}, []Term{make(chan int)},
+	}
+	for _, term := range bad {
+		if _, err := EtfEncode(term); err == nil {
+			t.Fatalf("expected error of %#v", term)
+		}
+	}
+}
+
+func TestEtfPid(t *testing.T) {
+
+	e := NewEnv()
+
+	pid, err := e.GenServerStart(new(ts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pid.Stop()
+
+	if _, err = EtfEncode(pid); err == nil {
+		t.Fatal("expected error of pid of environment without node")
+	}
+
+	n, err := e.StartNode("etf@test", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Stop()
+
+	ref := e.MakeRef()
+	codec := EtfCodec()
+
+	data, err := codec.Encode(Tuple{pid, ref})
+	if err != nil {
+		t.Fatal(err)
+	}
+	term, err := codec.Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tuple, ok := term.(Tuple)
+	if !ok || len(tuple) != 2 {
+		t.Fatalf("expected tuple, actual %#v", term)
+	}
+	if pid2, ok := tuple[0].(*Pid); !ok || !pid2.Equal(pid) {
+		t.Fatalf("expected %s, actual %#v", pid, tuple[0])
+	}
+	if reply, err := tuple[0].(*Pid).Call("ping"); err != nil || reply != "pong" {
+		t.Fatalf("expected pong, actual %v, %v", reply, err)
+	}
+	if tuple[1] != ref {
+		t.Fatalf("expected %s, actual %#v", ref, tuple[1])
+	}
+
+	// pids and refs of unknown nodes
+	erl := Tuple{
+		ErlPid{Node: "erl@host", ID: 1, Serial: 2, Creation: 3},
+		ErlRef{Node: "erl@host", Creation: 3, ID: []uint32{1, 2, 3}},
+	}
+	if data, err = EtfEncode(erl); err != nil {
+		t.Fatal(err)
+	}
+	if term, err = EtfDecode(data); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(term, erl) {
+		t.Fatalf("expected %#v, actual %#v", erl, term)
+	}
+}
+
+func TestEtfCompressed(t *testing.T) {
+
+	term := []Term{Atom("a"), Atom("a"), Atom("a"), Atom("a")}
+
+	data, err := EtfEncode(term)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	zw.Write(data[1:])
+	zw.Close()
+
+	compressed := []byte{etfVersion, etfCompressed, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(compressed[2:], uint32(len(data)-1))
+	compressed = append(compressed, buf.Bytes()...)
+
+	term2, err := EtfDecode(compressed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(term, term2) {
+		t.Fatalf("expected %#v, actual %#v", term, term2)
+	}
+
+	binary.BigEndian.PutUint32(compressed[2:], uint32(len(data)))
+	if _, err = EtfDecode(compressed); err == nil {
+		t.Fatal("expected error of uncompressed size")
+	}
+}
+
+func TestEtfBadData(t *testing.T) {
+
+	for _, g := range etfGolden {
+		for i := 0; i < len(g.data); i++ {
+			if _, err := EtfDecode(g.data[:i]); err == nil {
+				t.Fatalf("%s: expected error of %d bytes", g.name, i)
+			}
+		}
+	}
+
+	bad := [][]byte{
+		{130, 97, 1},
+		{131, 97, 1, 0},
+		{131, 255},
+		{131, 108, 0, 0, 0, 1, 97, 1, 97, 2},
+		{131, 108, 255, 255, 255, 255, 106},
+		{131, 116, 0, 0, 0, 1, 106, 97, 1},
+		{131, 119, 1, 0xff},
+		{131, 70, 127, 240, 0, 0, 0, 0, 0, 0},
+		{131, 90, 0, 0, 119, 1, 'a', 0, 0, 0, 0},
+	}
+	for _, b := range bad {
+		if _, err := EtfDecode(b); err == nil {
+			t.Fatalf("expected error of %v", b)
+		}
+	}
+
+	// nested tuples
+	deep := []byte{etfVersion}
+	for i := 0; i < etfMaxDepth; i++ {
+		deep = append(deep, etfSmallTuple, 1)
+	}
+	if _, err := EtfDecode(append(deep, etfNil)); err == nil {
+		t.Fatal("expected error of deep term")
+	}
+	deep = append([]byte{etfVersion}, deep[3:]...)
+	if _, err := EtfDecode(append(deep, etfNil)); err != nil {
+		t.Fatal(err)
+	}
+
+	// uncompressed size
+	if _, err := EtfDecode([]byte{etfVersion, etfCompressed,
+		255, 255, 255, 255, 0x78, 0x9c}); err == nil {
+		t.Fatal("expected error of uncompressed size")
+	}
+}
+
+func FuzzEtf(f *testing.F) {
+
+	for _, g := range etfGolden {
+		f.Add(g.data)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+
+		term, err := EtfDecode(data)
+		if err != nil {
+			return
+		}
+
+		data2, err := EtfEncode(term)
+		if err != nil {
+			t.Fatalf("encode %#v: %v", term, err)
+		}
+		term2, err := EtfDecode(data2)
+		if err != nil {
+			t.Fatalf("decode %v: %v", data2, err)
+		}
+		if !reflect.DeepEqual(term, term2) {
+			t.Fatalf("expected %#v, actual %#v", term, term2)
+		}
+	})
+}
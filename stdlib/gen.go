@@ -9,7 +9,8 @@ type Term interface{}
 // AsyncReq is an async message to process
 //
 type AsyncReq struct {
-	Data Term
+	Data  Term
+	Token *SeqTraceToken
 }
 
 //
@@ -18,6 +19,7 @@ type AsyncReq struct {
 type SyncReq struct {
 	Data      Term
 	ReplyChan chan<- Term
+	Token     *SeqTraceToken
 }
 
 //
@@ -26,6 +28,7 @@ type SyncReq struct {
 type SysReq struct {
 	Data      Term
 	ReplyChan chan<- Term
+	Token     *SeqTraceToken
 }
 
 type callType int
@@ -57,7 +60,38 @@ func (pid *Pid) Cast(data Term) error {
 	return pid.send(callTypeCast, data)
 }
 
-func (pid *Pid) send(ct callType, data Term) (err error) {
+//
+// SendFrom sends async message from process from, the send is traced by
+//  tracer of the sender
+//
+func (pid *Pid) SendFrom(from *Pid, data Term) error {
+	return pid.sendFrom(from, callTypeUsr, data)
+}
+
+//
+// CastFrom sends *AsyncMsg message from process from, the send is traced by
+//  tracer of the sender
+//
+func (pid *Pid) CastFrom(from *Pid, data Term) error {
+	return pid.sendFrom(from, callTypeCast, data)
+}
+
+func (pid *Pid) send(ct callType, data Term) error {
+	return pid.sendFrom(nil, ct, data)
+}
+
+func (pid *Pid) sendFrom(from *Pid, ct callType, data Term) (err error) {
+
+	if from == nil {
+		from = currentPid()
+	}
+
+	if pid.isRemote() {
+		if err = pid.remote.send(pid, ct, data); err == nil {
+			from.traceSend(pid, data, ct == callTypeSys)
+		}
+		return err
+	}
 
 	defer func() {
 		if r := recover(); r != nil {
@@ -78,8 +112,12 @@ func (pid *Pid) send(ct callType, data Term) (err error) {
 		err = pid.sendSys(data)
 
 	case callTypeCast:
-		err = pid.sendUsr(&AsyncReq{data})
+		err = pid.sendUsr(&AsyncReq{data, seqTraceSend(from, pid, data)})
+
+	}
 
+	if err == nil {
+		from.traceSend(pid, data, ct == callTypeSys)
 	}
 
 	return
@@ -99,7 +137,39 @@ func (pid *Pid) CallSys(data Term) (Term, error) {
 	return pid.call(callTypeSys, data)
 }
 
-func (pid *Pid) call(ct callType, data Term) (reply Term, err error) {
+//
+// CallFrom sends sync message from process from, the send is traced by
+//  tracer of the sender
+//
+func (pid *Pid) CallFrom(from *Pid, data Term) (Term, error) {
+	return pid.callFrom(from, callTypeUsr, data)
+}
+
+//
+// CallSysFrom sends sync sys message from process from, the send is traced
+//  by tracer of the sender
+//
+func (pid *Pid) CallSysFrom(from *Pid, data Term) (Term, error) {
+	return pid.callFrom(from, callTypeSys, data)
+}
+
+func (pid *Pid) call(ct callType, data Term) (Term, error) {
+	return pid.callFrom(nil, ct, data)
+}
+
+func (pid *Pid) callFrom(
+	from *Pid, ct callType, data Term) (reply Term, err error) {
+
+	if from == nil {
+		from = currentPid()
+	}
+
+	if pid.isRemote() {
+		if reply, err = pid.remote.call(pid, ct, data); err == nil {
+			from.traceSend(pid, data, ct == callTypeSys)
+		}
+		return reply, err
+	}
 
 	defer func() {
 		if r := recover(); r != nil {
@@ -115,6 +185,8 @@ func (pid *Pid) call(ct callType, data Term) (reply Term, err error) {
 	replyChan := pid.env.getReplyChan()
 	defer pid.env.putReplyChan(replyChan)
 
+	tok := seqTraceSend(from, pid, data)
+
 	switch ct {
 
 	case callTypeSys:
@@ -123,6 +195,7 @@ func (pid *Pid) call(ct callType, data Term) (reply Term, err error) {
 
 		r.Data = data
 		r.ReplyChan = replyChan
+		r.Token = tok
 		err = pid.sendSys(r)
 
 	case callTypeUsr:
@@ -131,12 +204,14 @@ func (pid *Pid) call(ct callType, data Term) (reply Term, err error) {
 
 		r.Data = data
 		r.ReplyChan = replyChan
+		r.Token = tok
 		err = pid.sendUsr(r)
 	}
 
 	if err != nil {
 		return nil, err
 	}
+	from.traceSend(pid, data, ct == callTypeSys)
 
 	// fmt.Printf("%s: before wait reply: %#v\n", pid, data)
 	processExit := false
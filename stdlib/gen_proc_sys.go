@@ -68,6 +68,18 @@ func (gps *GenProcSys) setPid(pid *Pid) {
 //
 func (gps *GenProcSys) SetTracer(t Tracer) {
 	gps.tracer = t
+	if gps.pid != nil {
+		gps.pid.setTrace(t, gps.pid.TraceFlags())
+	}
+}
+
+//
+// SetTraceFlags sets kinds of trace events emitted for the process
+//
+func (gps *GenProcSys) SetTraceFlags(flags TraceFlags) {
+	if gps.pid != nil {
+		gps.pid.setTrace(gps.tracer, flags)
+	}
 }
 
 //
@@ -82,6 +94,17 @@ func (gps *GenProcSys) Tracer() Tracer {
 //
 func (gps *GenProcSys) HandleSysMsg(msg Term) (err error) {
 
+	if r, ok := msg.(*SysReq); ok {
+		gps.pid.traceReceive(r.Data, true)
+		// token is set before span of the callback, so the span is a child
+		//  of the sender span
+		prev := seqTraceReceive(gps.pid, r.Token, r.Data)
+		defer seqTraceDone(gps.pid, prev)
+	} else {
+		gps.pid.traceReceive(msg, true)
+	}
+	defer seqTraceDone(gps.pid, seqTraceSpan(gps.pid))
+
 	ts := TraceCall(gps.Tracer(), gps.Self(), traceFuncHSM, msg)
 	defer TraceCallResult(gps.Tracer(), gps.Self(), ts, traceFuncHSM, msg, err)
 
@@ -126,6 +149,10 @@ func (gps *GenProcSys) handleSyncMsg(msg *SysReq) (err error) {
 		r.Links = gps.processLinks()
 		msg.ReplyChan <- true
 
+	case *TraceReq:
+		gps.doTraceReq(r)
+		msg.ReplyChan <- true
+
 	default:
 		err = gps.handleAsyncMsg(r)
 
@@ -260,6 +287,8 @@ func (gps *GenProcSys) Run(gp GenProc, opts *SpawnOpts, args ...Term) {
 	var err error
 	exitReason := ExitNormal
 
+	defer unbindCurrentPid(bindCurrentPid(gps.pid))
+
 	defer func() {
 		if r := recover(); r != nil {
 
@@ -275,13 +304,14 @@ func (gps *GenProcSys) Run(gp GenProc, opts *SpawnOpts, args ...Term) {
 			exitReason = err.Error()
 		}
 
-		TraceCall(
-			gp.Tracer(), gp.Self(), "gp: run.defer, exitReason", exitReason)
+		gps.pid.traceExit(exitReason)
 
 		gps.onStop(exitReason)
 		gps.flushMessages(gps.pid)
 	}()
 
+	gps.pid.traceSpawn(opts.Prefix, opts.Name)
+
 	//
 	// link processes
 	//
@@ -311,6 +341,7 @@ func (gps *GenProcSys) link(pid *Pid) bool {
 	}
 
 	gps.links = append(gps.links, pid)
+	gps.pid.traceLink(pid)
 
 	return true
 }
@@ -324,6 +355,7 @@ func (gps *GenProcSys) unlink(pid *Pid) bool {
 		if pid.Equal(linkedPid) {
 			gps.links[i] = gps.links[len(gps.links)-1]
 			gps.links = gps.links[:len(gps.links)-1]
+			gps.pid.traceUnlink(pid)
 			return true
 		}
 	}
@@ -390,3 +422,22 @@ func (gps *GenProcSys) flushMessages(pid *Pid) {
 
 	close(pid.exitChan)
 }
+
+//
+// Trace
+//
+func (pid *Pid) traceSpawn(prefix string, name Term) {
+	if t := pid.tracerFor(TraceFlagProcs); t != nil {
+		t.Event(&SpawnEvent{pid.traceEvent(), prefix, name})
+	}
+}
+
+//
+// traceExit emits *ExitEvent to the tracer of the process regardless of trace
+//  flags, as tracers set without flags were notified of exits before
+//
+func (pid *Pid) traceExit(reason string) {
+	if pt := pid.loadTrace(); pt != nil && pt.tracer != nil {
+		pt.tracer.Event(&ExitEvent{pid.traceEvent(), reason})
+	}
+}
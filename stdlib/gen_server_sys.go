@@ -194,12 +194,7 @@ func (gs *GenServerSys) GenProcLoop(args ...Term) (err error) {
 		if r := recover(); r != nil {
 			err = fmt.Errorf("%v", r)
 
-			trace := make([]byte, 4096)
-			n := runtime.Stack(trace, false)
-
-			fmt.Println(time.Now().Truncate(time.Microsecond), gs.Self(),
-				"crashed with reason:", r, n, "bytes stack:",
-				string(trace[:n]))
+			gs.crashReport(r)
 
 			TraceCall(gs.Tracer(), gs.Self(), "GenServerSysLoop crashed", err)
 		}
@@ -242,18 +237,30 @@ func (gs *GenServerSys) GenProcLoop(args ...Term) (err error) {
 
 			case *SyncReq:
 
-				if timeout, err = gs.doCall(m.Data, m.ReplyChan); err != nil {
+				pid.traceReceive(m.Data, false)
+				prev := seqTraceReceive(pid, m.Token, m.Data)
+
+				timeout, err = gs.doCall(m.Data, m.ReplyChan)
+				seqTraceDone(pid, prev)
+				if err != nil {
 					return
 				}
 
 			case *AsyncReq:
 
-				if timeout, err = gs.doCast(m.Data); err != nil {
+				pid.traceReceive(m.Data, false)
+				prev := seqTraceReceive(pid, m.Token, m.Data)
+
+				timeout, err = gs.doCast(m.Data)
+				seqTraceDone(pid, prev)
+				if err != nil {
 					return
 				}
 
 			default:
 
+				pid.traceReceive(m, false)
+
 				if timeout, err = gs.doInfo(m); err != nil {
 					return
 				}
@@ -276,16 +283,13 @@ func (gs *GenServerSys) doInit(
 	err = nil
 	timeout = nil
 
+	defer seqTraceDone(gs.Self(), seqTraceSpan(gs.Self()))
+
 	defer func() {
 		if r := recover(); r != nil {
 			err = fmt.Errorf("%v", r)
 
-			trace := make([]byte, 4096)
-			n := runtime.Stack(trace, false)
-
-			fmt.Println(time.Now().Truncate(time.Microsecond), gs.Self(),
-				"crashed with reason:", r, n, "bytes stack:",
-				string(trace[:n]))
+			gs.crashReport(r)
 
 			TraceCall(gs.Tracer(), gs.Self(), "Init crashed", err)
 		}
@@ -336,6 +340,8 @@ func (gs *GenServerSys) doCall(
 
 	inCall := false
 
+	defer seqTraceDone(gs.Self(), seqTraceSpan(gs.Self()))
+
 	defer func() {
 		if r := recover(); r != nil {
 			err = fmt.Errorf("%v", r)
@@ -343,12 +349,7 @@ func (gs *GenServerSys) doCall(
 				replyChan <- err
 			}
 
-			trace := make([]byte, 4096)
-			n := runtime.Stack(trace, false)
-
-			fmt.Println(time.Now().Truncate(time.Microsecond), gs.Self(),
-				"crashed with reason:", r, n, "bytes stack:",
-				string(trace[:n]))
+			gs.crashReport(r)
 
 			TraceCall(gs.Tracer(), gs.Self(), "HandleCall crashed", err)
 		}
@@ -433,16 +434,13 @@ func (gs *GenServerSys) doAsyncMsg(
 	err = nil
 	timeout = nil
 
+	defer seqTraceDone(gs.Self(), seqTraceSpan(gs.Self()))
+
 	defer func() {
 		if r := recover(); r != nil {
 			err = fmt.Errorf("%v", r)
 
-			trace := make([]byte, 4096)
-			n := runtime.Stack(trace, false)
-
-			fmt.Println(time.Now().Truncate(time.Microsecond), gs.Self(),
-				"crashed with reason:", r, n, "bytes stack:",
-				string(trace[:n]))
+			gs.crashReport(r)
 
 			TraceCall(gs.Tracer(), gs.Self(), tag+" crashed", err)
 		}
@@ -486,15 +484,12 @@ func (gs *GenServerSys) doAsyncMsg(
 
 func (gs *GenServerSys) doTerminate(reason string) {
 
+	defer seqTraceDone(gs.Self(), seqTraceSpan(gs.Self()))
+
 	defer func() {
 		if r := recover(); r != nil {
 
-			trace := make([]byte, 4096)
-			n := runtime.Stack(trace, false)
-
-			fmt.Println(time.Now().Truncate(time.Microsecond), gs.Self(),
-				"crashed with reason:", r, n, "bytes stack:",
-				string(trace[:n]))
+			gs.crashReport(r)
 
 			TraceCall(gs.Tracer(), gs.Self(), traceFuncTerminate+" crashed", r)
 		}
@@ -506,3 +501,33 @@ func (gs *GenServerSys) doTerminate(reason string) {
 
 	TraceCallResult(gs.Tracer(), gs.Self(), ts, traceFuncTerminate, reason, "")
 }
+
+//
+// crashReport prints the reason and the stack of recovered panic with recent
+//  events of the process attached to *CrashEvent by tracers
+//
+func (gs *GenServerSys) crashReport(r interface{}) {
+
+	trace := make([]byte, 4096)
+	n := runtime.Stack(trace, false)
+
+	evt := &CrashEvent{
+		TraceEvent: gs.Self().traceEvent(),
+		Reason:     fmt.Sprintf("%v", r),
+		Stack:      trace[:n],
+	}
+	if t := gs.Tracer(); t != nil {
+		t.Event(evt)
+	}
+
+	fmt.Println(evt.Time.Truncate(time.Microsecond), gs.Self(),
+		"crashed with reason:", r, n, "bytes stack:",
+		string(trace[:n]))
+
+	if len(evt.Recent) > 0 {
+		fmt.Println(gs.Self(), "last", len(evt.Recent), "events:")
+		for _, e := range evt.Recent {
+			fmt.Println(" ", FormatTraceEvent(e))
+		}
+	}
+}
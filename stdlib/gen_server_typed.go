@@ -0,0 +1,196 @@
+package stdlib
+
+//
+// Type-safe layer on top of GenServer and Pid messaging
+//
+
+import (
+	"errors"
+	"fmt"
+)
+
+//
+// GenServerOf is an interface for typed callbacks functions of the process
+//  with state S, call requests C, call replies R and cast requests K.
+//  Error returned by a callback stops the process with the error as reason
+//
+type GenServerOf[S, C, R, K any] interface {
+	Init(args ...Term) (S, error)
+	HandleCall(state S, req C) (R, S, error)
+	HandleCast(state S, req K) (S, error)
+	HandleInfo(state S, req Term) (S, error)
+	Terminate(state S, reason string)
+}
+
+//
+// PidOf is a process accepting call requests C with replies R and
+//  cast requests K
+//
+type PidOf[C, R, K any] struct {
+	pid *Pid
+}
+
+//
+// PidAs makes typed wrapper of the process
+//
+func PidAs[C, R, K any](pid *Pid) *PidOf[C, R, K] {
+	return &PidOf[C, R, K]{pid}
+}
+
+//
+// Pid returns underlying untyped process
+//
+func (p *PidOf[C, R, K]) Pid() *Pid {
+	return p.pid
+}
+
+//
+// Call sends sync message to the process and returns typed reply
+//
+func (p *PidOf[C, R, K]) Call(req C) (R, error) {
+	return CallOf[R](p.pid, req)
+}
+
+//
+// Cast sends async message to the process
+//
+func (p *PidOf[C, R, K]) Cast(req K) error {
+	return p.pid.Cast(req)
+}
+
+//
+// Stop stops the process
+//
+func (p *PidOf[C, R, K]) Stop() error {
+	return p.pid.Stop()
+}
+
+//
+// CallOf sends sync message to the process and returns reply of type R.
+//  Returns error if reply has other type
+//
+func CallOf[R any](pid *Pid, req Term) (R, error) {
+	var reply R
+
+	r, err := pid.Call(req)
+	if err != nil {
+		return reply, err
+	}
+
+	if r == nil {
+		return reply, nil
+	}
+
+	reply, ok := r.(R)
+	if !ok {
+		return reply, fmt.Errorf("Call bad reply type: %T", r)
+	}
+
+	return reply, nil
+}
+
+//
+// GenServerStartOf starts typed GenServer process in default environment
+//  with given options
+//
+func GenServerStartOf[S, C, R, K any](
+	gs GenServerOf[S, C, R, K],
+	opts *SpawnOpts, args ...Term) (*PidOf[C, R, K], error) {
+
+	return GenServerStartOfEnv(env, gs, opts, args...)
+}
+
+//
+// GenServerStartOfEnv starts typed GenServer process in specified environment
+//  with given options
+//
+func GenServerStartOfEnv[S, C, R, K any](
+	e *Env, gs GenServerOf[S, C, R, K],
+	opts *SpawnOpts, args ...Term) (*PidOf[C, R, K], error) {
+
+	if gs == nil {
+		return nil, errors.New("GenServer parameter is nil")
+	}
+
+	pid, err := e.GenServerStartOpts(
+		&genServerOf[S, C, R, K]{callback: gs}, opts, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return PidAs[C, R, K](pid), nil
+}
+
+// ---------------------------------------------------------------------------
+// Locals
+// ---------------------------------------------------------------------------
+
+//
+// genServerOf adapts typed callbacks to GenServer. Call requests of other
+//  types are replied with BadArgError, cast requests of other types are
+//  handled by HandleInfo
+//
+type genServerOf[S, C, R, K any] struct {
+	GenServerSys
+
+	callback GenServerOf[S, C, R, K]
+	state    S
+}
+
+func (gs *genServerOf[S, C, R, K]) Init(args ...Term) Term {
+
+	state, err := gs.callback.Init(args...)
+	if err != nil {
+		return err
+	}
+	gs.state = state
+
+	return gs.InitOk()
+}
+
+func (gs *genServerOf[S, C, R, K]) HandleCall(req Term, from From) Term {
+
+	r, ok := req.(C)
+	if !ok {
+		return gs.CallReply(BadArgError)
+	}
+
+	reply, state, err := gs.callback.HandleCall(gs.state, r)
+	if err != nil {
+		return err
+	}
+	gs.state = state
+
+	return gs.CallReply(reply)
+}
+
+func (gs *genServerOf[S, C, R, K]) HandleCast(req Term) Term {
+
+	r, ok := req.(K)
+	if !ok {
+		return gs.HandleInfo(req)
+	}
+
+	state, err := gs.callback.HandleCast(gs.state, r)
+	if err != nil {
+		return err
+	}
+	gs.state = state
+
+	return gs.NoReply()
+}
+
+func (gs *genServerOf[S, C, R, K]) HandleInfo(req Term) Term {
+
+	state, err := gs.callback.HandleInfo(gs.state, req)
+	if err != nil {
+		return err
+	}
+	gs.state = state
+
+	return gs.NoReply()
+}
+
+func (gs *genServerOf[S, C, R, K]) Terminate(reason string) {
+	gs.callback.Terminate(gs.state, reason)
+}
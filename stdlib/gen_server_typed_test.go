@@ -0,0 +1,96 @@
+package stdlib
+
+import (
+	"errors"
+	"testing"
+)
+
+type tsCounter struct {
+	terminated chan string
+}
+
+type tsCounterAdd struct {
+	n int
+}
+
+func (gs *tsCounter) Init(args ...Term) (int, error) {
+	if len(args) > 0 {
+		return 0, errors.New("bad args")
+	}
+	return 0, nil
+}
+
+func (gs *tsCounter) HandleCall(state int, req string) (int, int, error) {
+	switch req {
+	case "get":
+		return state, state, nil
+	case "reset":
+		return state, 0, nil
+	}
+	return 0, state, errors.New("unknown request " + req)
+}
+
+func (gs *tsCounter) HandleCast(state int, req tsCounterAdd) (int, error) {
+	return state + req.n, nil
+}
+
+func (gs *tsCounter) HandleInfo(state int, req Term) (int, error) {
+	if n, ok := req.(int); ok {
+		return state - n, nil
+	}
+	return state, nil
+}
+
+func (gs *tsCounter) Terminate(state int, reason string) {
+	gs.terminated <- reason
+}
+
+func TestGenServerTyped(t *testing.T) {
+
+	gs := &tsCounter{terminated: make(chan string, 1)}
+
+	if _, err := GenServerStartOf[int, string, int, tsCounterAdd](
+		gs, nil, "bad"); err == nil {
+		t.Fatal("expected Init error, actual no error")
+	}
+	<-gs.terminated
+
+	pid, err := GenServerStartOf[int, string, int, tsCounterAdd](gs, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pid.Cast(tsCounterAdd{5})
+	pid.Cast(tsCounterAdd{3})
+	// casts of other types are handled by HandleInfo
+	pid.Pid().Cast(2)
+
+	n, err := pid.Call("get")
+	if err != nil || n != 6 {
+		t.Fatalf("expected 6, actual %d, %v", n, err)
+	}
+
+	// bad request type
+	if _, err := pid.Pid().Call(1); !IsBadArgError(err) {
+		t.Fatalf("expected '%s' error, actual %v", BadArgError, err)
+	}
+
+	// untyped call with typed reply
+	if n, err = CallOf[int](pid.Pid(), "reset"); err != nil || n != 6 {
+		t.Fatalf("expected 6, actual %d, %v", n, err)
+	}
+	if _, err := CallOf[string](pid.Pid(), "get"); err == nil {
+		t.Fatal("expected bad reply type error, actual no error")
+	}
+
+	// callback error stops the process
+	if _, err = pid.Call("crash"); err == nil {
+		t.Fatal("expected error, actual no error")
+	}
+	if reason := <-gs.terminated; reason != "unknown request crash" {
+		t.Fatalf("expected reason 'unknown request crash', actual %s", reason)
+	}
+	if err := pid.Cast(tsCounterAdd{1}); !IsNoProcError(err) {
+		t.Fatalf("expected '%s' error, actual %v", NoProcError, err)
+	}
+}
@@ -1,7 +1,12 @@
 package stdlib
 
+import (
+	"time"
+)
+
 //
-// gts - Golang term store. Implementation is not thread-safe
+// gts - Golang term store. Implementation is not thread-safe, unless table
+// is created with GtsOpts.WithLock()
 //
 
 //
@@ -15,7 +20,11 @@ type GtsKeysComparator func(a, b interface{}) int
 type GtsForEach func(a, b interface{}) bool
 
 //
-// GtsIterator is the interface that difines functions to iterater over the table
+// GtsIterator is the interface that difines functions to iterater over the table.
+//
+// Keys of the set are not ordered: First, Last, Next and Prev of the set
+// return no objects, Cursor, Floor, Ceiling and Range of the set panic, use
+// ordered set for them.
 //
 type GtsIterator interface {
 	First() (Term, Term, bool)
@@ -23,6 +32,53 @@ type GtsIterator interface {
 	Next() (Term, Term, bool)
 	Prev() (Term, Term, bool)
 	ForEach(GtsForEach)
+
+	//
+	// Cursor returns new iterator independent of the table and other cursors
+	//
+	Cursor() GtsCursor
+	//
+	// Floor returns object with the greatest key less than or equal to key
+	//
+	Floor(key Term) (Term, Term, bool)
+	//
+	// Ceiling returns object with the least key greater than or equal to key
+	//
+	Ceiling(key Term) (Term, Term, bool)
+	//
+	// Range calls f for objects with keys from 'from' to 'to' inclusive in
+	// keys order until f returns false. The table may be modified in f
+	//
+	Range(from, to Term, f GtsForEach)
+}
+
+//
+// GtsCursor is the interface that defines functions of the cursor.
+//
+// Cursor is positioned at the key of the last returned object. When the
+// table is modified during traversal, Next returns object with the least key
+// greater than cursor key and Prev returns object with the greatest key less
+// than cursor key, so inserted objects are visited if they are ahead of the
+// cursor and deleted objects are never returned.
+//
+type GtsCursor interface {
+	First() (Term, Term, bool)
+	Last() (Term, Term, bool)
+	Next() (Term, Term, bool)
+	Prev() (Term, Term, bool)
+	//
+	// Seek positions cursor at key. Returns false if key is not in the table,
+	// Next and Prev continue from the key position anyway
+	//
+	Seek(key Term) (Term, Term, bool)
+	//
+	// Floor positions cursor at the greatest key less than or equal to key
+	//
+	Floor(key Term) (Term, Term, bool)
+	//
+	// Ceiling positions cursor at the least key greater than or equal to key
+	//
+	Ceiling(key Term) (Term, Term, bool)
 }
 
 //
@@ -30,13 +86,115 @@ type GtsIterator interface {
 //
 type Gts interface {
 	Insert(key Term, value Term)
+	//
+	// InsertTTL inserts object that expires after ttl. Tables created without
+	// GtsOpts.WithTTL() ignore ttl
+	//
+	InsertTTL(key Term, value Term, ttl time.Duration)
 	Delete(key Term)
 	Lookup(key Term) Term
 	Size() int
 	DeleteAllObjects()
 	Print()
+	//
+	// Close releases table resources
+	//
+	Close()
+	//
+	// Info returns table properties
+	//
+	Info() GtsInfo
 
 	GtsIterator
+	GtsSelector
+	GtsUpdater
+	GtsIndexer
+}
+
+//
+// GtsSelector is the interface that defines functions to query the table
+//  with match specs
+//
+type GtsSelector interface {
+	//
+	// Select returns objects matched ms. If limit > 0 returns at most limit
+	// objects and continuation to get the rest, otherwise returns all matched
+	// objects and nil continuation
+	//
+	Select(ms *GtsMatchSpec, limit int) ([]GtsObject, *GtsContinuation)
+	//
+	// SelectContinue returns next portion of objects of limited select.
+	// Returns nil continuation when there are no more objects
+	//
+	SelectContinue(c *GtsContinuation) ([]GtsObject, *GtsContinuation)
+	//
+	// SelectDelete deletes matched objects, returns count of deleted objects
+	//
+	SelectDelete(ms *GtsMatchSpec) int
+	//
+	// SelectCount returns count of matched objects
+	//
+	SelectCount(ms *GtsMatchSpec) int
+}
+
+//
+// GtsType is a type of the table
+//
+type GtsType int
+
+//
+// Table types
+//
+const (
+	GtsTypeSet GtsType = iota + 1
+	GtsTypeOrderedSet
+)
+
+//
+// GtsInfo holds table properties
+//
+type GtsInfo struct {
+	Type GtsType
+	// KeysComparatorID is an id of ordered set keys comparator registered with
+	// RegisterGtsKeysComparator, empty if comparator id is unknown
+	KeysComparatorID string
+}
+
+//
+// GtsUpdateFunc is a function to update value of the object.
+// Returns new value of the object
+//
+type GtsUpdateFunc func(value Term) Term
+
+//
+// GtsUpdater is the interface that defines atomic update functions
+//
+type GtsUpdater interface {
+	//
+	// UpdateCounter adds incr to the integer value of the object and returns
+	// new value. If the object does not exist it is inserted with value 0
+	// before update. If incr >= 0 and new value is greater than threshold, or
+	// incr < 0 and new value is less than threshold, the value is set to
+	// setValue. Use math.MaxInt64 or math.MinInt64 threshold for no threshold.
+	// Returns BadArgError if value is not an integer or new value overflows
+	// type of the value, the value is not changed then
+	//
+	UpdateCounter(key Term, incr, threshold, setValue int64) (int64, error)
+	//
+	// UpdateElement replaces value of the object with the value returned by
+	// f. Returns false if the object does not exist. f must not access the
+	// table
+	//
+	UpdateElement(key Term, f GtsUpdateFunc) bool
+	//
+	// InsertNew inserts object if the key does not exist.
+	// Returns false if the key exists
+	//
+	InsertNew(key Term, value Term) bool
+	//
+	// Take deletes the object and returns its value
+	//
+	Take(key Term) (Term, bool)
 }
 
 //
@@ -59,3 +217,44 @@ func NewOrderedSet() Gts {
 func NewOrderedSetWith(cmp GtsKeysComparator) Gts {
 	return newOrderedSetWith(cmp)
 }
+
+//
+// NewSetOpts makes new set with given options
+//
+func NewSetOpts(opts *GtsOpts) Gts {
+	if opts == nil {
+		opts = NewGtsOpts()
+	}
+	return newGtsOpts(newSet(), opts)
+}
+
+//
+// NewOrderedSetOpts makes new ordered set with given options. If keys
+//  comparator is not set int keys comparator is used
+//
+func NewOrderedSetOpts(opts *GtsOpts) Gts {
+	if opts == nil {
+		opts = NewGtsOpts()
+	}
+	return newGtsOpts(newOrderedSetOpts(opts), opts)
+}
+
+func newOrderedSetOpts(opts *GtsOpts) Gts {
+	if opts.cmp == nil {
+		return newOrderedSet()
+	}
+	tab := newOrderedSetWith(opts.cmp)
+	tab.(*gtsOs).cmpID = opts.cmpID
+	return tab
+}
+
+func newGtsOpts(tab Gts, opts *GtsOpts) Gts {
+	tab = newGtsIndexed(tab, opts.indexes)
+	if opts.ttl {
+		return newGtsTTL(tab, opts)
+	}
+	if opts.locked {
+		tab = newGtsLocked(tab, false)
+	}
+	return tab
+}
@@ -0,0 +1,19 @@
+package stdlib
+
+//
+// Codecs to store table keys and values
+//
+
+//
+// GtsCodec is the interface that defines functions to encode and decode
+//  table keys and values
+//
+type GtsCodec = Codec
+
+//
+// GtsGobCodec returns codec based on encoding/gob. Types other than gob basic
+//  types must be registered with RegisterTerm or gob.Register
+//
+func GtsGobCodec() GtsCodec {
+	return GobCodec()
+}
@@ -0,0 +1,720 @@
+package stdlib
+
+//
+// Disk-backed table. Objects are kept in memory table, every modification is
+// appended to the log file. On open the log is replayed. Log is compacted when
+// it has much more records than objects in the table
+//
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+//
+// GtsDisk is the interface of the disk-backed table
+//
+type GtsDisk interface {
+	Gts
+	//
+	// Sync commits log file to stable storage
+	//
+	Sync() error
+	//
+	// Compact rewrites log file with current table objects only
+	//
+	Compact() error
+	//
+	// Err returns first error of the log file write
+	//
+	Err() error
+}
+
+//
+// GtsSyncPolicy defines when log file is committed to stable storage
+//
+type GtsSyncPolicy int
+
+//
+// Sync policies
+//
+const (
+	// GtsSyncNone leaves commit to OS, written data survives process crash
+	GtsSyncNone GtsSyncPolicy = iota
+	// GtsSyncAlways commits log after every modification
+	GtsSyncAlways
+	// GtsSyncInterval commits log periodically,
+	//  see GtsOpts.WithSyncInterval
+	GtsSyncInterval
+)
+
+//
+// OpenDiskSet opens or creates disk-backed set stored in file path
+//
+func OpenDiskSet(path string, opts *GtsOpts) (GtsDisk, error) {
+	if opts == nil {
+		opts = NewGtsOpts()
+	}
+	return openGtsDisk(path, newGtsIndexed(newSet(), opts.indexes), opts)
+}
+
+//
+// OpenDiskOrderedSet opens or creates disk-backed ordered set stored in file
+//  path. If keys comparator is not set int keys comparator is used
+//
+func OpenDiskOrderedSet(path string, opts *GtsOpts) (GtsDisk, error) {
+	if opts == nil {
+		opts = NewGtsOpts()
+	}
+	return openGtsDisk(
+		path, newGtsIndexed(newOrderedSetOpts(opts), opts.indexes), opts)
+}
+
+// ---------------------------------------------------------------------------
+// Locals
+// ---------------------------------------------------------------------------
+
+const (
+	gtsLogMagic   = "GTSL"
+	gtsLogVersion = 1
+	gtsLogHdrLen  = 8
+	gtsRecHdrLen  = 8
+	gtsMaxRecord  = 64 << 20
+
+	gtsLogInsert byte = 1
+	gtsLogDelete byte = 2
+	gtsLogClear  byte = 3
+
+	gtsDefaultCompactThreshold = 1000
+	gtsDefaultSyncInterval     = time.Second
+)
+
+var (
+	errGtsBadHeader = errors.New("gts: bad file header")
+	errGtsBadRecord = errors.New("gts: bad record")
+	errGtsBigRecord = errors.New("gts: record is too large")
+)
+
+//
+// gtsDisk is guarded by gtsLocked, fmu guards file for interval sync
+//
+type gtsDisk struct {
+	mem   Gts
+	path  string
+	codec GtsCodec
+
+	syncPolicy       GtsSyncPolicy
+	compactThreshold int
+
+	fmu     sync.Mutex
+	f       *os.File
+	w       *bufio.Writer
+	dirty   bool
+	err     error
+	records int
+	stop    chan struct{}
+}
+
+type gtsLockedDisk struct {
+	*gtsLocked
+	disk *gtsDisk
+}
+
+func openGtsDisk(path string, mem Gts, opts *GtsOpts) (GtsDisk, error) {
+
+	gts := &gtsDisk{
+		mem:              mem,
+		path:             path,
+		codec:            opts.codec,
+		syncPolicy:       opts.syncPolicy,
+		compactThreshold: opts.compactThreshold,
+	}
+	if gts.codec == nil {
+		gts.codec = GtsGobCodec()
+	}
+	if gts.compactThreshold <= 0 {
+		gts.compactThreshold = gtsDefaultCompactThreshold
+	}
+
+	if err := gts.open(); err != nil {
+		return nil, err
+	}
+
+	if gts.syncPolicy == GtsSyncInterval {
+		interval := gtsDefaultSyncInterval
+		if opts.syncInterval > 0 {
+			interval = time.Duration(opts.syncInterval) * time.Millisecond
+		}
+		gts.stop = make(chan struct{})
+		go gts.syncLoop(interval)
+	}
+
+	return &gtsLockedDisk{newGtsLocked(gts, false).(*gtsLocked), gts}, nil
+}
+
+//
+// GtsDisk functions of the locked table
+//
+func (gts *gtsLockedDisk) Sync() error {
+	return gts.disk.Sync()
+}
+
+func (gts *gtsLockedDisk) Compact() error {
+	gts.mu.Lock()
+	defer gts.mu.Unlock()
+
+	return gts.disk.Compact()
+}
+
+func (gts *gtsLockedDisk) Err() error {
+	return gts.disk.Err()
+}
+
+//
+// File
+//
+func (gts *gtsDisk) open() error {
+
+	f, err := os.OpenFile(gts.path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	if fi.Size() == 0 {
+		if err = writeGtsLogHeader(f); err == nil {
+			err = f.Sync()
+		}
+	} else {
+		err = gts.replay(f)
+	}
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	gts.f = f
+	gts.w = bufio.NewWriter(f)
+
+	return nil
+}
+
+//
+// replay reads log records into memory table. Log is truncated at the first
+// incomplete or corrupted record, which is the result of interrupted write
+//
+func (gts *gtsDisk) replay(f *os.File) error {
+
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	r := bufio.NewReader(f)
+
+	if err = readGtsLogHeader(r); err != nil {
+		return err
+	}
+
+	offset := int64(gtsLogHdrLen)
+
+	for {
+		payload, n, err := readGtsRecord(r, fi.Size()-offset)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if err == io.ErrUnexpectedEOF || err == errGtsBadRecord {
+				break
+			}
+			return err
+		}
+
+		op, key, value, err := decodeGtsRecord(gts.codec, payload)
+		if err != nil {
+			return err
+		}
+
+		switch op {
+		case gtsLogInsert:
+			gts.mem.Insert(key, value)
+		case gtsLogDelete:
+			gts.mem.Delete(key)
+		case gtsLogClear:
+			gts.mem.DeleteAllObjects()
+		}
+
+		offset += int64(n)
+		gts.records++
+	}
+
+	if err = f.Truncate(offset); err != nil {
+		return err
+	}
+	_, err = f.Seek(offset, io.SeekStart)
+
+	return err
+}
+
+func (gts *gtsDisk) log(op byte, key, value Term) {
+
+	payload, err := encodeGtsRecord(gts.codec, op, key, value)
+	if err == nil {
+		gts.fmu.Lock()
+		err = writeGtsRecord(gts.w, payload)
+		if err == nil {
+			err = gts.w.Flush()
+		}
+		if err == nil {
+			switch gts.syncPolicy {
+			case GtsSyncAlways:
+				err = gts.f.Sync()
+			case GtsSyncInterval:
+				gts.dirty = true
+			}
+		}
+		gts.fmu.Unlock()
+	}
+
+	if err != nil {
+		gts.setErr(err)
+		return
+	}
+
+	gts.records++
+
+	garbage := gts.records - gts.mem.Size()
+	if garbage >= gts.compactThreshold && garbage > gts.mem.Size() {
+		gts.setErr(gts.Compact())
+	}
+}
+
+func (gts *gtsDisk) setErr(err error) {
+	gts.fmu.Lock()
+	if gts.err == nil {
+		gts.err = err
+	}
+	gts.fmu.Unlock()
+}
+
+func (gts *gtsDisk) Err() error {
+	gts.fmu.Lock()
+	defer gts.fmu.Unlock()
+
+	return gts.err
+}
+
+func (gts *gtsDisk) Sync() error {
+	gts.fmu.Lock()
+	defer gts.fmu.Unlock()
+
+	return gts.sync()
+}
+
+func (gts *gtsDisk) sync() error {
+	if gts.f == nil {
+		return nil
+	}
+	if err := gts.w.Flush(); err != nil {
+		return err
+	}
+	gts.dirty = false
+	return gts.f.Sync()
+}
+
+func (gts *gtsDisk) syncLoop(interval time.Duration) {
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			gts.fmu.Lock()
+			if gts.dirty {
+				if err := gts.sync(); err != nil && gts.err == nil {
+					gts.err = err
+				}
+			}
+			gts.fmu.Unlock()
+		case <-gts.stop:
+			return
+		}
+	}
+}
+
+//
+// Compact writes table objects to temporary file and replaces log with it
+//
+func (gts *gtsDisk) Compact() error {
+
+	gts.fmu.Lock()
+	defer gts.fmu.Unlock()
+
+	tmpPath := gts.path + ".compact"
+	tmp, err := os.OpenFile(
+		tmpPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(tmp)
+	err = writeGtsLogHeader(w)
+
+	objs, _ := gts.mem.Select(nil, 0)
+	for i := 0; err == nil && i < len(objs); i++ {
+		var payload []byte
+		payload, err = encodeGtsRecord(
+			gts.codec, gtsLogInsert, objs[i].Key, objs[i].Value)
+		if err == nil {
+			err = writeGtsRecord(w, payload)
+		}
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, gts.path)
+	}
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+
+	syncGtsDir(gts.path)
+
+	gts.f.Close()
+	gts.f = tmp
+	gts.w = bufio.NewWriter(tmp)
+	gts.dirty = false
+	gts.records = len(objs)
+
+	return nil
+}
+
+//
+// Record: op, key length, key, value
+//
+func encodeGtsRecord(
+	codec GtsCodec, op byte, key, value Term) ([]byte, error) {
+
+	var buf bytes.Buffer
+	buf.WriteByte(op)
+
+	if op == gtsLogClear {
+		return buf.Bytes(), nil
+	}
+
+	k, err := codec.Encode(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var lenBuf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(lenBuf[:], uint64(len(k)))
+	buf.Write(lenBuf[:n])
+	buf.Write(k)
+
+	if op == gtsLogInsert {
+		v, err := codec.Encode(value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(v)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func decodeGtsRecord(
+	codec GtsCodec, payload []byte) (op byte, key, value Term, err error) {
+
+	if len(payload) == 0 {
+		return 0, nil, nil, errGtsBadRecord
+	}
+
+	op = payload[0]
+	if op == gtsLogClear {
+		return
+	}
+
+	keyLen, n := binary.Uvarint(payload[1:])
+	if n <= 0 || uint64(len(payload)-1-n) < keyLen {
+		return 0, nil, nil, errGtsBadRecord
+	}
+	data := payload[1+n:]
+
+	if key, err = codec.Decode(data[:keyLen]); err != nil {
+		return
+	}
+	if op == gtsLogInsert {
+		value, err = codec.Decode(data[keyLen:])
+	}
+
+	return
+}
+
+//
+// Log file format: header, records of [length, crc32, payload]
+//
+func writeGtsLogHeader(w io.Writer) error {
+	hdr := make([]byte, gtsLogHdrLen)
+	copy(hdr, gtsLogMagic)
+	hdr[len(gtsLogMagic)] = gtsLogVersion
+	_, err := w.Write(hdr)
+	return err
+}
+
+func readGtsLogHeader(r io.Reader) error {
+	hdr := make([]byte, gtsLogHdrLen)
+	if _, err := io.ReadFull(r, hdr); err != nil {
+		return errGtsBadHeader
+	}
+	if string(hdr[:len(gtsLogMagic)]) != gtsLogMagic ||
+		hdr[len(gtsLogMagic)] != gtsLogVersion {
+		return errGtsBadHeader
+	}
+	return nil
+}
+
+func writeGtsRecord(w io.Writer, payload []byte) error {
+	if len(payload) > gtsMaxRecord {
+		return errGtsBigRecord
+	}
+	var hdr [gtsRecHdrLen]byte
+	binary.BigEndian.PutUint32(hdr[:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(hdr[4:], crc32.ChecksumIEEE(payload))
+	if _, err := w.Write(hdr[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(payload)
+	return err
+}
+
+//
+// readGtsRecord returns payload and size of the record, left is the number of
+//  bytes left in the file. Size of the torn record may be any, so it is
+//  checked before the payload is allocated
+//
+func readGtsRecord(r io.Reader, left int64) ([]byte, int, error) {
+	var hdr [gtsRecHdrLen]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return nil, 0, err
+	}
+
+	size := binary.BigEndian.Uint32(hdr[:4])
+	if size > gtsMaxRecord || int64(size) > left-gtsRecHdrLen {
+		return nil, 0, errGtsBadRecord
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, 0, err
+	}
+
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(hdr[4:]) {
+		return nil, 0, errGtsBadRecord
+	}
+
+	return payload, len(hdr) + len(payload), nil
+}
+
+func syncGtsDir(path string) {
+	if d, err := os.Open(filepath.Dir(path)); err == nil {
+		_ = d.Sync()
+		d.Close()
+	}
+}
+
+//
+// Gts
+//
+func (gts *gtsDisk) Insert(key Term, value Term) {
+	gts.mem.Insert(key, value)
+	gts.log(gtsLogInsert, key, value)
+}
+
+//
+// InsertTTL ignores ttl, disk-backed table does not support expiry
+//
+func (gts *gtsDisk) InsertTTL(key Term, value Term, ttl time.Duration) {
+	gts.Insert(key, value)
+}
+
+func (gts *gtsDisk) Delete(key Term) {
+	if _, ok := gts.lookup(key); !ok {
+		return
+	}
+	gts.mem.Delete(key)
+	gts.log(gtsLogDelete, key, nil)
+}
+
+func (gts *gtsDisk) Lookup(key Term) Term {
+	return gts.mem.Lookup(key)
+}
+
+func (gts *gtsDisk) Size() int {
+	return gts.mem.Size()
+}
+
+func (gts *gtsDisk) DeleteAllObjects() {
+	gts.mem.DeleteAllObjects()
+	gts.log(gtsLogClear, nil, nil)
+}
+
+func (gts *gtsDisk) Close() {
+	if gts.stop != nil {
+		close(gts.stop)
+		gts.stop = nil
+	}
+
+	gts.fmu.Lock()
+	if err := gts.sync(); err != nil && gts.err == nil {
+		gts.err = err
+	}
+	if gts.f != nil {
+		gts.f.Close()
+		gts.f = nil
+	}
+	gts.fmu.Unlock()
+
+	gts.mem.Close()
+}
+
+func (gts *gtsDisk) Info() GtsInfo {
+	return gts.mem.Info()
+}
+
+func (gts *gtsDisk) Print() {
+	gts.mem.Print()
+}
+
+//
+// Iterator
+//
+func (gts *gtsDisk) First() (Term, Term, bool) {
+	return gts.mem.First()
+}
+
+func (gts *gtsDisk) Last() (Term, Term, bool) {
+	return gts.mem.Last()
+}
+
+func (gts *gtsDisk) Next() (Term, Term, bool) {
+	return gts.mem.Next()
+}
+
+func (gts *gtsDisk) Prev() (Term, Term, bool) {
+	return gts.mem.Prev()
+}
+
+func (gts *gtsDisk) ForEach(f GtsForEach) {
+	objs, _ := gts.mem.Select(nil, 0)
+	for _, o := range objs {
+		if !f(o.Key, o.Value) {
+			gts.Delete(o.Key)
+		}
+	}
+}
+
+func (gts *gtsDisk) Cursor() GtsCursor {
+	return gts.mem.Cursor()
+}
+
+func (gts *gtsDisk) Floor(key Term) (Term, Term, bool) {
+	return gts.mem.Floor(key)
+}
+
+func (gts *gtsDisk) Ceiling(key Term) (Term, Term, bool) {
+	return gts.mem.Ceiling(key)
+}
+
+func (gts *gtsDisk) Range(from, to Term, f GtsForEach) {
+	gts.mem.Range(from, to, f)
+}
+
+//
+// Selector
+//
+func (gts *gtsDisk) Select(
+	ms *GtsMatchSpec, limit int) ([]GtsObject, *GtsContinuation) {
+
+	return gts.mem.Select(ms, limit)
+}
+
+func (gts *gtsDisk) SelectContinue(
+	c *GtsContinuation) ([]GtsObject, *GtsContinuation) {
+
+	return gts.mem.SelectContinue(c)
+}
+
+func (gts *gtsDisk) SelectDelete(ms *GtsMatchSpec) int {
+	return gtsSelectDelete(gts, ms)
+}
+
+func (gts *gtsDisk) SelectCount(ms *GtsMatchSpec) int {
+	return gts.mem.SelectCount(ms)
+}
+
+//
+// Indexer
+//
+func (gts *gtsDisk) LookupByIndex(
+	index string, ikey Term) ([]GtsObject, error) {
+
+	return gts.mem.LookupByIndex(index, ikey)
+}
+
+func (gts *gtsDisk) IndexRange(
+	index string, from, to Term, f GtsForEach) error {
+
+	return gts.mem.IndexRange(index, from, to, f)
+}
+
+func (gts *gtsDisk) scan(pos Term, f GtsForEach) Term {
+	return gts.mem.(gtsScanner).scan(pos, f)
+}
+
+//
+// Updater
+//
+func (gts *gtsDisk) UpdateCounter(
+	key Term, incr, threshold, setValue int64) (int64, error) {
+
+	return gtsUpdateCounter(gts, key, incr, threshold, setValue)
+}
+
+func (gts *gtsDisk) UpdateElement(key Term, f GtsUpdateFunc) bool {
+	return gtsUpdateElement(gts, key, f)
+}
+
+func (gts *gtsDisk) InsertNew(key Term, value Term) bool {
+	return gtsInsertNew(gts, key, value)
+}
+
+func (gts *gtsDisk) Take(key Term) (Term, bool) {
+	return gtsTake(gts, key)
+}
+
+func (gts *gtsDisk) lookup(key Term) (Term, bool) {
+	return gts.mem.(gtsStore).lookup(key)
+}
@@ -0,0 +1,222 @@
+package stdlib
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func gtsTestDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "gts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestGtsDiskReopen(t *testing.T) {
+
+	dir := gtsTestDir(t)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "tab.gts")
+
+	tab, err := OpenDiskOrderedSet(
+		path, NewGtsOpts().WithSyncPolicy(GtsSyncAlways))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 1; i <= 5; i++ {
+		tab.Insert(i, "v")
+	}
+	tab.Delete(2)
+	tab.Insert(3, "v3")
+	if _, err = tab.UpdateCounter(10, 7, math.MaxInt64, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := tab.Take(4); !ok {
+		t.Fatal("expected key 4 taken")
+	}
+	tab.Close()
+
+	if err = tab.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	tab, err = OpenDiskOrderedSet(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tab.Close()
+
+	if tab.Size() != 4 {
+		t.Fatalf("expected 4 objects, actual %d", tab.Size())
+	}
+	if tab.Lookup(2) != nil || tab.Lookup(4) != nil {
+		t.Fatal("expected keys 2 and 4 deleted")
+	}
+	if tab.Lookup(3) != "v3" || tab.Lookup(10) != int64(7) {
+		t.Fatalf("expected values v3 and 7, actual %v, %v",
+			tab.Lookup(3), tab.Lookup(10))
+	}
+	if k, _, _ := tab.First(); k != 1 {
+		t.Fatalf("expected first key 1, actual %v", k)
+	}
+}
+
+func TestGtsDiskTornTail(t *testing.T) {
+
+	dir := gtsTestDir(t)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "tab.gts")
+
+	tab, err := OpenDiskSet(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tab.Insert("a", 1)
+	tab.Insert("b", 2)
+	tab.Close()
+
+	// interrupted write of the last record
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Truncate(path, fi.Size()-3); err != nil {
+		t.Fatal(err)
+	}
+
+	tab, err = OpenDiskSet(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tab.Size() != 1 || tab.Lookup("a") != 1 {
+		t.Fatalf("expected only key 'a', actual size %d", tab.Size())
+	}
+
+	// log is writable after truncation
+	tab.Insert("c", 3)
+	tab.Close()
+
+	tab, err = OpenDiskSet(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tab.Close()
+
+	if tab.Size() != 2 || tab.Lookup("c") != 3 {
+		t.Fatalf("expected keys 'a' and 'c', actual size %d", tab.Size())
+	}
+}
+
+func TestGtsDiskBadRecordSize(t *testing.T) {
+
+	dir := gtsTestDir(t)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "tab.gts")
+
+	tab, err := OpenDiskSet(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tab.Insert("a", 1)
+	tab.Close()
+
+	// torn header of the record with huge size
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = f.Write([]byte{0xff, 0xff, 0xff, 0xf0, 0, 0, 0, 0, 1})
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tab, err = OpenDiskSet(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tab.Close()
+
+	if tab.Size() != 1 || tab.Lookup("a") != 1 {
+		t.Fatalf("expected only key 'a', actual size %d", tab.Size())
+	}
+
+	if _, _, err = readGtsRecord(
+		bytes.NewReader([]byte{0xff, 0xff, 0xff, 0xf0, 0, 0, 0, 0}),
+		gtsMaxRecord+gtsRecHdrLen); err != errGtsBadRecord {
+		t.Fatalf("expected %v, actual %v", errGtsBadRecord, err)
+	}
+}
+
+func TestGtsDiskBadHeader(t *testing.T) {
+
+	dir := gtsTestDir(t)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "tab.gts")
+	if err := ioutil.WriteFile(path, []byte("not a table"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := OpenDiskSet(path, nil); err == nil {
+		t.Fatal("expected bad header error")
+	}
+}
+
+func TestGtsDiskCompact(t *testing.T) {
+
+	dir := gtsTestDir(t)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "tab.gts")
+
+	tab, err := OpenDiskSet(path, NewGtsOpts().WithCompactThreshold(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 100; i++ {
+		tab.Insert("counter", i)
+	}
+	tab.Insert("other", true)
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sizeCompacted := fi.Size()
+
+	if err = tab.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	tab.Close()
+
+	fi, err = os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// log was compacted automatically, at most threshold records left
+	if sizeCompacted < fi.Size() || sizeCompacted > 12*fi.Size() {
+		t.Fatalf("expected compacted log, actual sizes %d and %d",
+			sizeCompacted, fi.Size())
+	}
+
+	tab, err = OpenDiskSet(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tab.Close()
+
+	if tab.Size() != 2 || tab.Lookup("counter") != 99 {
+		t.Fatalf("expected counter 99, actual %v", tab.Lookup("counter"))
+	}
+}
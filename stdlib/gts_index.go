@@ -0,0 +1,323 @@
+package stdlib
+
+//
+// Table with secondary indexes on values. Indexes are maintained on every
+// modification of the table. Indexed table is the innermost wrapper of the
+// table, so objects deleted by expiry or restored from disk are indexed too
+//
+
+import (
+	"time"
+)
+
+//
+// GtsIndexFunc extracts index key from value of the object.
+//  Returns false if the object is not indexed
+//
+type GtsIndexFunc func(value Term) (Term, bool)
+
+//
+// GtsIndexer is the interface that defines functions to query the table by
+//  secondary indexes declared with GtsOpts.WithIndex and
+//  GtsOpts.WithOrderedIndex
+//
+type GtsIndexer interface {
+	//
+	// LookupByIndex returns objects with index key ikey.
+	// Returns BadArgError if the index is not declared
+	//
+	LookupByIndex(index string, ikey Term) ([]GtsObject, error)
+	//
+	// IndexRange calls f for objects with index keys from 'from' to 'to'
+	// inclusive in index keys order until f returns false.
+	// Returns BadArgError if the index is not declared or is not ordered
+	//
+	IndexRange(index string, from, to Term, f GtsForEach) error
+}
+
+type gtsIndexSpec struct {
+	name    string
+	extract GtsIndexFunc
+	cmp     GtsKeysComparator
+}
+
+type gtsIndex struct {
+	gtsIndexSpec
+	// index key -> set of primary keys
+	keys Gts
+}
+
+type gtsIndexed struct {
+	tab     Gts
+	indexes map[string]*gtsIndex
+}
+
+func newGtsIndexed(tab Gts, specs []gtsIndexSpec) Gts {
+	if len(specs) == 0 {
+		return tab
+	}
+
+	gts := &gtsIndexed{
+		tab:     tab,
+		indexes: make(map[string]*gtsIndex, len(specs)),
+	}
+
+	for _, spec := range specs {
+		idx := &gtsIndex{gtsIndexSpec: spec}
+		if spec.cmp == nil {
+			idx.keys = NewSet()
+		} else {
+			idx.keys = NewOrderedSetWith(spec.cmp)
+		}
+		gts.indexes[spec.name] = idx
+	}
+
+	// index objects of the table
+	objs, _ := tab.Select(nil, 0)
+	for _, o := range objs {
+		gts.index(o.Key, o.Value)
+	}
+
+	return gts
+}
+
+//
+// Index maintenance
+//
+func (gts *gtsIndexed) index(key, value Term) {
+	for _, idx := range gts.indexes {
+		ikey, ok := idx.extract(value)
+		if !ok {
+			continue
+		}
+		pkeys, _ := idx.keys.Lookup(ikey).(map[Term]struct{})
+		if pkeys == nil {
+			pkeys = make(map[Term]struct{})
+			idx.keys.Insert(ikey, pkeys)
+		}
+		pkeys[key] = struct{}{}
+	}
+}
+
+func (gts *gtsIndexed) unindex(key Term) {
+
+	value, ok := gts.lookup(key)
+	if !ok {
+		return
+	}
+
+	for _, idx := range gts.indexes {
+		ikey, ok := idx.extract(value)
+		if !ok {
+			continue
+		}
+		pkeys, _ := idx.keys.Lookup(ikey).(map[Term]struct{})
+		delete(pkeys, key)
+		if len(pkeys) == 0 {
+			idx.keys.Delete(ikey)
+		}
+	}
+}
+
+func (gts *gtsIndexed) objects(pkeys map[Term]struct{}) []GtsObject {
+	objs := make([]GtsObject, 0, len(pkeys))
+	for k := range pkeys {
+		if v, ok := gts.lookup(k); ok {
+			objs = append(objs, GtsObject{k, v})
+		}
+	}
+	return objs
+}
+
+//
+// Indexer
+//
+func (gts *gtsIndexed) LookupByIndex(
+	index string, ikey Term) ([]GtsObject, error) {
+
+	idx, ok := gts.indexes[index]
+	if !ok {
+		return nil, BadArgError
+	}
+
+	pkeys, _ := idx.keys.Lookup(ikey).(map[Term]struct{})
+
+	return gts.objects(pkeys), nil
+}
+
+func (gts *gtsIndexed) IndexRange(
+	index string, from, to Term, f GtsForEach) error {
+
+	idx, ok := gts.indexes[index]
+	if !ok || idx.cmp == nil {
+		return BadArgError
+	}
+
+	idx.keys.Range(from, to, func(ikey, pkeys interface{}) bool {
+		for _, o := range gts.objects(pkeys.(map[Term]struct{})) {
+			if !f(o.Key, o.Value) {
+				return false
+			}
+		}
+		return true
+	})
+
+	return nil
+}
+
+//
+// Gts
+//
+func (gts *gtsIndexed) Insert(key Term, value Term) {
+	gts.unindex(key)
+	gts.tab.Insert(key, value)
+	gts.index(key, value)
+}
+
+func (gts *gtsIndexed) InsertTTL(key Term, value Term, ttl time.Duration) {
+	gts.unindex(key)
+	gts.tab.InsertTTL(key, value, ttl)
+	gts.index(key, value)
+}
+
+func (gts *gtsIndexed) Delete(key Term) {
+	gts.unindex(key)
+	gts.tab.Delete(key)
+}
+
+func (gts *gtsIndexed) Lookup(key Term) Term {
+	return gts.tab.Lookup(key)
+}
+
+func (gts *gtsIndexed) Size() int {
+	return gts.tab.Size()
+}
+
+func (gts *gtsIndexed) DeleteAllObjects() {
+	gts.tab.DeleteAllObjects()
+	for _, idx := range gts.indexes {
+		idx.keys.DeleteAllObjects()
+	}
+}
+
+func (gts *gtsIndexed) Close() {
+	gts.tab.Close()
+}
+
+func (gts *gtsIndexed) Info() GtsInfo {
+	return gts.tab.Info()
+}
+
+func (gts *gtsIndexed) Print() {
+	gts.tab.Print()
+}
+
+//
+// Iterator
+//
+func (gts *gtsIndexed) First() (Term, Term, bool) {
+	return gts.tab.First()
+}
+
+func (gts *gtsIndexed) Last() (Term, Term, bool) {
+	return gts.tab.Last()
+}
+
+func (gts *gtsIndexed) Next() (Term, Term, bool) {
+	return gts.tab.Next()
+}
+
+func (gts *gtsIndexed) Prev() (Term, Term, bool) {
+	return gts.tab.Prev()
+}
+
+func (gts *gtsIndexed) ForEach(f GtsForEach) {
+	objs, _ := gts.tab.Select(nil, 0)
+	for _, o := range objs {
+		if !f(o.Key, o.Value) {
+			gts.Delete(o.Key)
+		}
+	}
+}
+
+func (gts *gtsIndexed) Cursor() GtsCursor {
+	return gts.tab.Cursor()
+}
+
+func (gts *gtsIndexed) Floor(key Term) (Term, Term, bool) {
+	return gts.tab.Floor(key)
+}
+
+func (gts *gtsIndexed) Ceiling(key Term) (Term, Term, bool) {
+	return gts.tab.Ceiling(key)
+}
+
+func (gts *gtsIndexed) Range(from, to Term, f GtsForEach) {
+	gts.tab.Range(from, to, f)
+}
+
+//
+// Selector
+//
+func (gts *gtsIndexed) Select(
+	ms *GtsMatchSpec, limit int) ([]GtsObject, *GtsContinuation) {
+
+	return gts.tab.Select(ms, limit)
+}
+
+func (gts *gtsIndexed) SelectContinue(
+	c *GtsContinuation) ([]GtsObject, *GtsContinuation) {
+
+	return gts.tab.SelectContinue(c)
+}
+
+func (gts *gtsIndexed) SelectDelete(ms *GtsMatchSpec) int {
+	return gtsSelectDelete(gts, ms)
+}
+
+func (gts *gtsIndexed) SelectCount(ms *GtsMatchSpec) int {
+	return gts.tab.SelectCount(ms)
+}
+
+func (gts *gtsIndexed) scan(pos Term, f GtsForEach) Term {
+	return gts.tab.(gtsScanner).scan(pos, f)
+}
+
+//
+// Updater
+//
+func (gts *gtsIndexed) UpdateCounter(
+	key Term, incr, threshold, setValue int64) (int64, error) {
+
+	return gtsUpdateCounter(gts, key, incr, threshold, setValue)
+}
+
+//
+// UpdateElement unindexes the object before f call, f may modify the value
+//
+func (gts *gtsIndexed) UpdateElement(key Term, f GtsUpdateFunc) bool {
+
+	value, ok := gts.lookup(key)
+	if !ok {
+		return false
+	}
+
+	gts.unindex(key)
+	value = f(value)
+	gts.tab.Insert(key, value)
+	gts.index(key, value)
+
+	return true
+}
+
+func (gts *gtsIndexed) InsertNew(key Term, value Term) bool {
+	return gtsInsertNew(gts, key, value)
+}
+
+func (gts *gtsIndexed) Take(key Term) (Term, bool) {
+	return gtsTake(gts, key)
+}
+
+func (gts *gtsIndexed) lookup(key Term) (Term, bool) {
+	return gts.tab.(gtsStore).lookup(key)
+}
@@ -0,0 +1,166 @@
+package stdlib
+
+import (
+	"encoding/gob"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/emirpasic/gods/utils"
+)
+
+type gtsTestUser struct {
+	Name string
+	City string
+	Age  int
+}
+
+func gtsTestUserCity(v Term) (Term, bool) {
+	return v.(*gtsTestUser).City, true
+}
+
+func gtsTestUserAge(v Term) (Term, bool) {
+	u := v.(*gtsTestUser)
+	return u.Age, u.Age > 0
+}
+
+func gtsTestIndexOpts() *GtsOpts {
+	return NewGtsOpts().
+		WithIndex("city", gtsTestUserCity).
+		WithOrderedIndex("age", gtsTestUserAge, utils.IntComparator)
+}
+
+func gtsTestSortedNames(objs []GtsObject) string {
+	names := make([]string, len(objs))
+	for i, o := range objs {
+		names[i] = o.Value.(*gtsTestUser).Name
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+func TestGtsIndex(t *testing.T) {
+
+	tabs := map[string]Gts{
+		"set":         NewSetOpts(gtsTestIndexOpts()),
+		"ordered_set": NewOrderedSetOpts(gtsTestIndexOpts().WithLock()),
+	}
+
+	for name, tab := range tabs {
+		t.Run(name, func(t *testing.T) {
+
+			tab.Insert(1, &gtsTestUser{"ann", "paris", 30})
+			tab.Insert(2, &gtsTestUser{"bob", "rome", 25})
+			tab.Insert(3, &gtsTestUser{"cid", "paris", 40})
+			tab.Insert(4, &gtsTestUser{"dan", "oslo", 0})
+
+			objs, err := tab.LookupByIndex("city", "paris")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if names := gtsTestSortedNames(objs); names != "ann,cid" {
+				t.Fatalf("expected ann,cid, actual %s", names)
+			}
+
+			// reinsert moves object to other index key
+			tab.Insert(1, &gtsTestUser{"ann", "rome", 31})
+			objs, _ = tab.LookupByIndex("city", "rome")
+			if names := gtsTestSortedNames(objs); names != "ann,bob" {
+				t.Fatalf("expected ann,bob, actual %s", names)
+			}
+
+			tab.Delete(3)
+			objs, _ = tab.LookupByIndex("city", "paris")
+			if len(objs) != 0 {
+				t.Fatalf("expected no objects, actual %v", objs)
+			}
+
+			tab.UpdateElement(2, func(v Term) Term {
+				u := v.(*gtsTestUser)
+				u.Age = 50
+				return u
+			})
+
+			var names []string
+			err = tab.IndexRange("age", 0, 100, func(k, v interface{}) bool {
+				names = append(names, v.(*gtsTestUser).Name)
+				return true
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(names, ",") != "ann,bob" {
+				t.Fatalf("expected ann,bob in age order, actual %v", names)
+			}
+
+			if _, err = tab.LookupByIndex("unknown", 1); !IsBadArgError(err) {
+				t.Fatalf("expected '%s' error, actual '%v'", BadArgError, err)
+			}
+			err = tab.IndexRange("city", "a", "z", nil)
+			if !IsBadArgError(err) {
+				t.Fatalf("expected '%s' error, actual '%v'", BadArgError, err)
+			}
+		})
+	}
+}
+
+func TestGtsIndexNoIndexes(t *testing.T) {
+
+	tab := NewSet()
+	if _, err := tab.LookupByIndex("city", "paris"); !IsBadArgError(err) {
+		t.Fatalf("expected '%s' error, actual '%v'", BadArgError, err)
+	}
+}
+
+func TestGtsIndexExpiry(t *testing.T) {
+
+	tab := NewSetOpts(
+		gtsTestIndexOpts().WithTTL(20 * time.Millisecond))
+	defer tab.Close()
+
+	tab.Insert(1, &gtsTestUser{"ann", "paris", 30})
+	tab.InsertTTL(2, &gtsTestUser{"bob", "paris", 25}, 0)
+
+	time.Sleep(30 * time.Millisecond)
+
+	objs, _ := tab.LookupByIndex("city", "paris")
+	if names := gtsTestSortedNames(objs); names != "bob" {
+		t.Fatalf("expected bob, actual %s", names)
+	}
+}
+
+func TestGtsIndexDisk(t *testing.T) {
+
+	dir := gtsTestDir(t)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "tab.gts")
+
+	gob.Register(&gtsTestUser{})
+
+	tab, err := OpenDiskSet(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tab.Insert(1, &gtsTestUser{"ann", "paris", 30})
+	tab.Insert(2, &gtsTestUser{"bob", "rome", 25})
+	tab.Close()
+	if err = tab.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	// indexes are built on reopen
+	tab, err = OpenDiskSet(path, gtsTestIndexOpts())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tab.Close()
+
+	objs, _ := tab.LookupByIndex("city", "rome")
+	if names := gtsTestSortedNames(objs); names != "bob" {
+		t.Fatalf("expected bob, actual %s", names)
+	}
+}
@@ -0,0 +1,362 @@
+package stdlib
+
+//
+// Table guarded by mutex for concurrent use
+//
+
+import (
+	"sync"
+	"time"
+)
+
+type gtsLocked struct {
+	mu  sync.RWMutex
+	tab Gts
+	// exclusive is set if table modifies itself on reads
+	exclusive bool
+	// after is called once the table is unlocked, for the work of the table
+	//  that must not be done with the lock held
+	after func()
+}
+
+func newGtsLocked(tab Gts, exclusive bool) Gts {
+	return &gtsLocked{tab: tab, exclusive: exclusive}
+}
+
+func (gts *gtsLocked) rlock() {
+	if gts.exclusive {
+		gts.mu.Lock()
+	} else {
+		gts.mu.RLock()
+	}
+}
+
+func (gts *gtsLocked) runlock() {
+	if gts.exclusive {
+		gts.unlock()
+	} else {
+		gts.mu.RUnlock()
+	}
+}
+
+func (gts *gtsLocked) unlock() {
+	gts.mu.Unlock()
+	if gts.after != nil {
+		gts.after()
+	}
+}
+
+//
+// Gts
+//
+func (gts *gtsLocked) Insert(key Term, value Term) {
+	gts.mu.Lock()
+	gts.tab.Insert(key, value)
+	gts.unlock()
+}
+
+func (gts *gtsLocked) InsertTTL(key Term, value Term, ttl time.Duration) {
+	gts.mu.Lock()
+	gts.tab.InsertTTL(key, value, ttl)
+	gts.unlock()
+}
+
+func (gts *gtsLocked) Delete(key Term) {
+	gts.mu.Lock()
+	gts.tab.Delete(key)
+	gts.unlock()
+}
+
+func (gts *gtsLocked) Lookup(key Term) Term {
+	gts.rlock()
+	defer gts.runlock()
+
+	return gts.tab.Lookup(key)
+}
+
+func (gts *gtsLocked) Size() int {
+	gts.rlock()
+	defer gts.runlock()
+
+	return gts.tab.Size()
+}
+
+func (gts *gtsLocked) DeleteAllObjects() {
+	gts.mu.Lock()
+	gts.tab.DeleteAllObjects()
+	gts.unlock()
+}
+
+func (gts *gtsLocked) Close() {
+	gts.mu.Lock()
+	gts.tab.Close()
+	gts.unlock()
+}
+
+func (gts *gtsLocked) Info() GtsInfo {
+	return gts.tab.Info()
+}
+
+func (gts *gtsLocked) Print() {
+	gts.rlock()
+	defer gts.runlock()
+
+	gts.tab.Print()
+}
+
+//
+// Iterator. Table iterator functions change iterator state and require
+// write lock
+//
+func (gts *gtsLocked) First() (Term, Term, bool) {
+	gts.mu.Lock()
+	defer gts.unlock()
+
+	return gts.tab.First()
+}
+
+func (gts *gtsLocked) Last() (Term, Term, bool) {
+	gts.mu.Lock()
+	defer gts.unlock()
+
+	return gts.tab.Last()
+}
+
+func (gts *gtsLocked) Next() (Term, Term, bool) {
+	gts.mu.Lock()
+	defer gts.unlock()
+
+	return gts.tab.Next()
+}
+
+func (gts *gtsLocked) Prev() (Term, Term, bool) {
+	gts.mu.Lock()
+	defer gts.unlock()
+
+	return gts.tab.Prev()
+}
+
+//
+// ForEach calls f without lock held, so f may access the table
+//
+func (gts *gtsLocked) ForEach(f GtsForEach) {
+
+	objs, _ := gts.Select(nil, 0)
+
+	for _, o := range objs {
+		if !f(o.Key, o.Value) {
+			gts.Delete(o.Key)
+		}
+	}
+}
+
+func (gts *gtsLocked) Cursor() GtsCursor {
+	gts.rlock()
+	defer gts.runlock()
+
+	return &gtsLockedCursor{gts, gts.tab.Cursor()}
+}
+
+func (gts *gtsLocked) Floor(key Term) (Term, Term, bool) {
+	gts.rlock()
+	defer gts.runlock()
+
+	return gts.tab.Floor(key)
+}
+
+func (gts *gtsLocked) Ceiling(key Term) (Term, Term, bool) {
+	gts.rlock()
+	defer gts.runlock()
+
+	return gts.tab.Ceiling(key)
+}
+
+//
+// Range calls f without lock held, so f may access the table
+//
+func (gts *gtsLocked) Range(from, to Term, f GtsForEach) {
+
+	var objs []GtsObject
+
+	// unlocked by defer, Range of the set panics
+	func() {
+		gts.rlock()
+		defer gts.runlock()
+
+		gts.tab.Range(from, to, func(k, v interface{}) bool {
+			objs = append(objs, GtsObject{k, v})
+			return true
+		})
+	}()
+
+	for _, o := range objs {
+		if !f(o.Key, o.Value) {
+			return
+		}
+	}
+}
+
+//
+// Selector
+//
+func (gts *gtsLocked) Select(
+	ms *GtsMatchSpec, limit int) ([]GtsObject, *GtsContinuation) {
+
+	gts.rlock()
+	defer gts.runlock()
+
+	return gts.tab.Select(ms, limit)
+}
+
+func (gts *gtsLocked) SelectContinue(
+	c *GtsContinuation) ([]GtsObject, *GtsContinuation) {
+
+	gts.rlock()
+	defer gts.runlock()
+
+	return gts.tab.SelectContinue(c)
+}
+
+func (gts *gtsLocked) SelectDelete(ms *GtsMatchSpec) int {
+	gts.mu.Lock()
+	defer gts.unlock()
+
+	return gts.tab.SelectDelete(ms)
+}
+
+func (gts *gtsLocked) SelectCount(ms *GtsMatchSpec) int {
+	gts.rlock()
+	defer gts.runlock()
+
+	return gts.tab.SelectCount(ms)
+}
+
+//
+// Updater
+//
+func (gts *gtsLocked) UpdateCounter(
+	key Term, incr, threshold, setValue int64) (int64, error) {
+
+	gts.mu.Lock()
+	defer gts.unlock()
+
+	return gts.tab.UpdateCounter(key, incr, threshold, setValue)
+}
+
+func (gts *gtsLocked) UpdateElement(key Term, f GtsUpdateFunc) bool {
+	gts.mu.Lock()
+	defer gts.unlock()
+
+	return gts.tab.UpdateElement(key, f)
+}
+
+func (gts *gtsLocked) InsertNew(key Term, value Term) bool {
+	gts.mu.Lock()
+	defer gts.unlock()
+
+	return gts.tab.InsertNew(key, value)
+}
+
+func (gts *gtsLocked) Take(key Term) (Term, bool) {
+	gts.mu.Lock()
+	defer gts.unlock()
+
+	return gts.tab.Take(key)
+}
+
+//
+// Indexer
+//
+func (gts *gtsLocked) LookupByIndex(
+	index string, ikey Term) ([]GtsObject, error) {
+
+	gts.rlock()
+	defer gts.runlock()
+
+	return gts.tab.LookupByIndex(index, ikey)
+}
+
+//
+// IndexRange calls f without lock held, so f may access the table
+//
+func (gts *gtsLocked) IndexRange(
+	index string, from, to Term, f GtsForEach) error {
+
+	var objs []GtsObject
+
+	gts.rlock()
+	err := gts.tab.IndexRange(index, from, to, func(k, v interface{}) bool {
+		objs = append(objs, GtsObject{k, v})
+		return true
+	})
+	gts.runlock()
+
+	if err != nil {
+		return err
+	}
+
+	for _, o := range objs {
+		if !f(o.Key, o.Value) {
+			break
+		}
+	}
+
+	return nil
+}
+
+//
+// Cursor
+//
+type gtsLockedCursor struct {
+	gts *gtsLocked
+	c   GtsCursor
+}
+
+func (c *gtsLockedCursor) First() (Term, Term, bool) {
+	c.gts.rlock()
+	defer c.gts.runlock()
+
+	return c.c.First()
+}
+
+func (c *gtsLockedCursor) Last() (Term, Term, bool) {
+	c.gts.rlock()
+	defer c.gts.runlock()
+
+	return c.c.Last()
+}
+
+func (c *gtsLockedCursor) Next() (Term, Term, bool) {
+	c.gts.rlock()
+	defer c.gts.runlock()
+
+	return c.c.Next()
+}
+
+func (c *gtsLockedCursor) Prev() (Term, Term, bool) {
+	c.gts.rlock()
+	defer c.gts.runlock()
+
+	return c.c.Prev()
+}
+
+func (c *gtsLockedCursor) Seek(key Term) (Term, Term, bool) {
+	c.gts.rlock()
+	defer c.gts.runlock()
+
+	return c.c.Seek(key)
+}
+
+func (c *gtsLockedCursor) Floor(key Term) (Term, Term, bool) {
+	c.gts.rlock()
+	defer c.gts.runlock()
+
+	return c.c.Floor(key)
+}
+
+func (c *gtsLockedCursor) Ceiling(key Term) (Term, Term, bool) {
+	c.gts.rlock()
+	defer c.gts.runlock()
+
+	return c.c.Ceiling(key)
+}
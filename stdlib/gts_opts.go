@@ -0,0 +1,177 @@
+package stdlib
+
+//
+// GtsOpts for table creation
+//
+
+import (
+	"time"
+)
+
+//
+// NewGtsOpts makes options object and returns object to manipulate
+//
+func NewGtsOpts() *GtsOpts {
+	return new(GtsOpts)
+}
+
+//
+// GtsOpts is the structure to hold values of the table options
+//
+type GtsOpts struct {
+	cmp    GtsKeysComparator
+	cmpID  string
+	locked bool
+	//
+	ttl           bool
+	defaultTTL    time.Duration
+	sweepInterval uint32
+	expiryNotify  *Pid
+	//
+	codec            GtsCodec
+	syncPolicy       GtsSyncPolicy
+	syncInterval     uint32
+	compactThreshold int
+	//
+	indexes []gtsIndexSpec
+}
+
+//
+// WithKeysComparator sets keys compare function for ordered set
+//
+func (op *GtsOpts) WithKeysComparator(cmp GtsKeysComparator) *GtsOpts {
+
+	op.cmp = cmp
+
+	return op
+}
+
+//
+// WithKeysComparatorID sets keys comparator for ordered set by id of the
+//  comparator registered with RegisterGtsKeysComparator. The id is stored in
+//  the table snapshot to restore it with the same comparator
+//
+func (op *GtsOpts) WithKeysComparatorID(id string) *GtsOpts {
+
+	op.cmpID = id
+	op.cmp = gtsKeysComparator(id)
+
+	return op
+}
+
+//
+// WithLock makes table safe for concurrent use. All table functions,
+//  including atomic updates, are guarded by the table mutex
+//
+func (op *GtsOpts) WithLock() *GtsOpts {
+
+	op.locked = true
+
+	return op
+}
+
+//
+// WithTTL makes table with expiring objects. Objects inserted with Insert
+//  expire after defaultTTL, zero defaultTTL means objects inserted with
+//  Insert never expire. Expired objects are deleted on access to the table.
+//  Table with TTL is safe for concurrent use
+//
+func (op *GtsOpts) WithTTL(defaultTTL time.Duration) *GtsOpts {
+
+	op.ttl = true
+	op.defaultTTL = defaultTTL
+
+	return op
+}
+
+//
+// WithSweepInterval sets interval to delete expired objects in background.
+//  Sweeping is driven by the timer server, see TimerServerStart. Sweeper runs
+//  while the table has expiring objects and is not closed
+//
+func (op *GtsOpts) WithSweepInterval(timeMs uint32) *GtsOpts {
+
+	op.sweepInterval = timeMs
+
+	return op
+}
+
+//
+// WithExpiryNotify sets pid to send *GtsExpired message to when object
+//  expires
+//
+func (op *GtsOpts) WithExpiryNotify(pid *Pid) *GtsOpts {
+
+	op.expiryNotify = pid
+
+	return op
+}
+
+//
+// WithCodec sets codec of keys and values for disk-backed table.
+//  Default is GtsGobCodec(), see also BinaryCodec()
+//
+func (op *GtsOpts) WithCodec(codec GtsCodec) *GtsOpts {
+
+	op.codec = codec
+
+	return op
+}
+
+//
+// WithSyncPolicy sets when disk-backed table commits log file to stable
+//  storage. Default is GtsSyncNone
+//
+func (op *GtsOpts) WithSyncPolicy(policy GtsSyncPolicy) *GtsOpts {
+
+	op.syncPolicy = policy
+
+	return op
+}
+
+//
+// WithSyncInterval sets GtsSyncInterval policy with given interval
+//
+func (op *GtsOpts) WithSyncInterval(timeMs uint32) *GtsOpts {
+
+	op.syncPolicy = GtsSyncInterval
+	op.syncInterval = timeMs
+
+	return op
+}
+
+//
+// WithCompactThreshold sets count of obsolete records in the log of
+//  disk-backed table to start compaction. Log is compacted when obsolete
+//  records count is above threshold and above count of objects in the table
+//
+func (op *GtsOpts) WithCompactThreshold(records int) *GtsOpts {
+
+	op.compactThreshold = records
+
+	return op
+}
+
+//
+// WithIndex declares secondary index with given name on values of the table.
+//  Objects are queried by index with LookupByIndex
+//
+func (op *GtsOpts) WithIndex(name string, extract GtsIndexFunc) *GtsOpts {
+
+	op.indexes = append(op.indexes, gtsIndexSpec{name, extract, nil})
+
+	return op
+}
+
+//
+// WithOrderedIndex declares ordered secondary index with given name on values
+//  of the table. Objects are queried by index with LookupByIndex and
+//  IndexRange
+//
+func (op *GtsOpts) WithOrderedIndex(
+	name string, extract GtsIndexFunc, cmp GtsKeysComparator) *GtsOpts {
+
+	op.indexes = append(op.indexes, gtsIndexSpec{name, extract, cmp})
+
+	return op
+}
@@ -6,8 +6,8 @@ package stdlib
 
 import (
 	"fmt"
+	"time"
 
-	"github.com/emirpasic/gods/containers"
 	avl "github.com/emirpasic/gods/trees/avltree"
 	"github.com/emirpasic/gods/utils"
 )
@@ -16,19 +16,24 @@ import (
 // Ordered Set
 //
 type gtsOs struct {
-	tree *avl.Tree
-	it   containers.ReverseIteratorWithKey
+	tree  *avl.Tree
+	cmpID string
+	// version is incremented on every modification to reposition cursors
+	version uint64
+	// cursor for the table iterator functions
+	cur *gtsOsCursor
 }
 
 func newOrderedSet() Gts {
-	t := new(gtsOs)
-	t.tree = avl.NewWithIntComparator()
+	t := newOrderedSetWith(utils.IntComparator)
+	t.(*gtsOs).cmpID = GtsIntComparatorID
 	return t
 }
 
 func newOrderedSetWith(cmp GtsKeysComparator) Gts {
 	t := new(gtsOs)
 	t.tree = avl.NewWith(utils.Comparator(cmp))
+	t.cur = newGtsOsCursor(t)
 	return t
 }
 
@@ -37,12 +42,16 @@ func newOrderedSetWith(cmp GtsKeysComparator) Gts {
 //
 func (gts *gtsOs) Insert(key Term, value Term) {
 	gts.tree.Put(key, value)
-	gts.it = nil
+	gts.version++
+}
+
+func (gts *gtsOs) InsertTTL(key Term, value Term, ttl time.Duration) {
+	gts.Insert(key, value)
 }
 
 func (gts *gtsOs) Delete(key Term) {
 	gts.tree.Remove(key)
-	gts.it = nil
+	gts.version++
 }
 
 func (gts *gtsOs) Lookup(key Term) Term {
@@ -59,7 +68,14 @@ func (gts *gtsOs) Size() int {
 
 func (gts *gtsOs) DeleteAllObjects() {
 	gts.tree.Clear()
-	gts.it = nil
+	gts.version++
+}
+
+func (gts *gtsOs) Close() {
+}
+
+func (gts *gtsOs) Info() GtsInfo {
+	return GtsInfo{Type: GtsTypeOrderedSet, KeysComparatorID: gts.cmpID}
 }
 
 func (gts *gtsOs) Print() {
@@ -70,51 +86,270 @@ func (gts *gtsOs) Print() {
 // Iterator
 //
 func (gts *gtsOs) First() (Term, Term, bool) {
-	if gts.it == nil {
-		gts.it = gts.tree.Iterator()
+	return gts.cur.First()
+}
+
+func (gts *gtsOs) Last() (Term, Term, bool) {
+	return gts.cur.Last()
+}
+
+func (gts *gtsOs) Next() (Term, Term, bool) {
+	return gts.cur.Next()
+}
+
+func (gts *gtsOs) Prev() (Term, Term, bool) {
+	return gts.cur.Prev()
+}
+
+func (gts *gtsOs) Cursor() GtsCursor {
+	return newGtsOsCursor(gts)
+}
+
+func (gts *gtsOs) Floor(key Term) (Term, Term, bool) {
+	return nodeObject(gts.tree.Floor(key))
+}
+
+func (gts *gtsOs) Ceiling(key Term) (Term, Term, bool) {
+	return nodeObject(gts.tree.Ceiling(key))
+}
+
+func (gts *gtsOs) Range(from, to Term, f GtsForEach) {
+	c := newGtsOsCursor(gts)
+	for k, v, ok := c.Ceiling(from); ok; k, v, ok = c.Next() {
+		if gts.tree.Comparator(k, to) > 0 || !f(k, v) {
+			return
+		}
 	}
-	if gts.it.First() {
-		return gts.it.Key(), gts.it.Value(), true
+}
+
+//
+// ForEach deletes objects for which f returns false
+//
+func (gts *gtsOs) ForEach(f GtsForEach) {
+	c := newGtsOsCursor(gts)
+	for k, v, ok := c.First(); ok; k, v, ok = c.Next() {
+		if !f(k, v) {
+			gts.Delete(k)
+		}
 	}
-	return nil, nil, false
 }
 
-func (gts *gtsOs) Last() (Term, Term, bool) {
-	if gts.it == nil {
-		gts.it = gts.tree.Iterator()
+//
+// Selector
+//
+func (gts *gtsOs) Select(
+	ms *GtsMatchSpec, limit int) ([]GtsObject, *GtsContinuation) {
+
+	return gtsSelect(gts, ms, limit, nil)
+}
+
+func (gts *gtsOs) SelectContinue(
+	c *GtsContinuation) ([]GtsObject, *GtsContinuation) {
+
+	return gtsSelectContinue(c)
+}
+
+func (gts *gtsOs) SelectDelete(ms *GtsMatchSpec) int {
+	return gtsSelectDelete(gts, ms)
+}
+
+func (gts *gtsOs) SelectCount(ms *GtsMatchSpec) int {
+	return gtsSelectCount(gts, ms)
+}
+
+//
+// Scan position is the key to resume scan from. If the key was deleted
+// scan resumes from the next greater key
+//
+type gtsOsPos struct {
+	key Term
+}
+
+func (gts *gtsOs) scan(pos Term, f GtsForEach) Term {
+
+	var node *avl.Node
+
+	if pos == nil {
+		node = gts.tree.Left()
+	} else {
+		node, _ = gts.tree.Ceiling(pos.(*gtsOsPos).key)
 	}
-	if gts.it.Last() {
-		return gts.it.Key(), gts.it.Value(), true
+
+	for ; node != nil; node = node.Next() {
+		if !f(node.Key, node.Value) {
+			return &gtsOsPos{node.Key}
+		}
 	}
-	return nil, nil, false
+
+	return nil
 }
 
-func (gts *gtsOs) Next() (Term, Term, bool) {
-	if gts.it == nil {
-		gts.it = gts.tree.Iterator()
+//
+// Cursor
+//
+type gtsOsCursorState int
+
+const (
+	gtsCursorBegin gtsOsCursorState = iota
+	gtsCursorAt
+	gtsCursorEnd
+)
+
+type gtsOsCursor struct {
+	tab   *gtsOs
+	state gtsOsCursorState
+	key   Term
+	// node is valid while version equals to the table version
+	node    *avl.Node
+	version uint64
+}
+
+func newGtsOsCursor(tab *gtsOs) *gtsOsCursor {
+	return &gtsOsCursor{tab: tab}
+}
+
+func (c *gtsOsCursor) First() (Term, Term, bool) {
+	return c.moveTo(c.tab.tree.Left(), gtsCursorEnd)
+}
+
+func (c *gtsOsCursor) Last() (Term, Term, bool) {
+	return c.moveTo(c.tab.tree.Right(), gtsCursorBegin)
+}
+
+func (c *gtsOsCursor) Next() (Term, Term, bool) {
+	switch c.state {
+	case gtsCursorBegin:
+		return c.First()
+	case gtsCursorEnd:
+		return nil, nil, false
 	}
-	if gts.it.Next() {
-		return gts.it.Key(), gts.it.Value(), true
+
+	var node *avl.Node
+	if c.valid() {
+		node = c.node.Next()
+	} else {
+		node, _ = c.tab.tree.Ceiling(c.key)
+		if node != nil && c.tab.tree.Comparator(node.Key, c.key) == 0 {
+			node = node.Next()
+		}
 	}
-	return nil, nil, false
+
+	return c.moveTo(node, gtsCursorEnd)
 }
 
-func (gts *gtsOs) Prev() (Term, Term, bool) {
-	if gts.it == nil {
-		gts.it = gts.tree.Iterator()
+func (c *gtsOsCursor) Prev() (Term, Term, bool) {
+	switch c.state {
+	case gtsCursorBegin:
+		return nil, nil, false
+	case gtsCursorEnd:
+		return c.Last()
 	}
-	if gts.it.Prev() {
-		return gts.it.Key(), gts.it.Value(), true
+
+	var node *avl.Node
+	if c.valid() {
+		node = c.node.Prev()
+	} else {
+		node, _ = c.tab.tree.Floor(c.key)
+		if node != nil && c.tab.tree.Comparator(node.Key, c.key) == 0 {
+			node = node.Prev()
+		}
+	}
+
+	return c.moveTo(node, gtsCursorBegin)
+}
+
+func (c *gtsOsCursor) Seek(key Term) (Term, Term, bool) {
+	node, _ := c.tab.tree.Ceiling(key)
+	if node != nil && c.tab.tree.Comparator(node.Key, key) == 0 {
+		return c.moveTo(node, gtsCursorEnd)
 	}
+
+	// position at absent key, next move repositions cursor by the key
+	c.state = gtsCursorAt
+	c.key = key
+	c.node = nil
+
 	return nil, nil, false
 }
 
-func (gts *gtsOs) ForEach(f GtsForEach) {
-	gts.it = nil
-	for k, v, ok := gts.First(); ok; k, v, ok = gts.Next() {
-		if !f(k, v) {
-			gts.Delete(k)
-		}
+func (c *gtsOsCursor) Floor(key Term) (Term, Term, bool) {
+	node, _ := c.tab.tree.Floor(key)
+	return c.moveTo(node, gtsCursorBegin)
+}
+
+func (c *gtsOsCursor) Ceiling(key Term) (Term, Term, bool) {
+	node, _ := c.tab.tree.Ceiling(key)
+	return c.moveTo(node, gtsCursorEnd)
+}
+
+func (c *gtsOsCursor) valid() bool {
+	return c.node != nil && c.version == c.tab.version
+}
+
+//
+// moveTo positions cursor at node, or to the given state if node is nil
+//
+func (c *gtsOsCursor) moveTo(
+	node *avl.Node, notFound gtsOsCursorState) (Term, Term, bool) {
+
+	if node == nil {
+		c.state = notFound
+		c.key = nil
+		c.node = nil
+		return nil, nil, false
 	}
 
+	c.state = gtsCursorAt
+	c.key = node.Key
+	c.node = node
+	c.version = c.tab.version
+
+	return node.Key, node.Value, true
+}
+
+func nodeObject(node *avl.Node, found bool) (Term, Term, bool) {
+	if !found {
+		return nil, nil, false
+	}
+	return node.Key, node.Value, true
+}
+
+//
+// Indexer. Table without indexes
+//
+func (gts *gtsOs) LookupByIndex(
+	index string, ikey Term) ([]GtsObject, error) {
+
+	return nil, BadArgError
+}
+
+func (gts *gtsOs) IndexRange(
+	index string, from, to Term, f GtsForEach) error {
+
+	return BadArgError
+}
+
+//
+// Updater
+//
+func (gts *gtsOs) UpdateCounter(
+	key Term, incr, threshold, setValue int64) (int64, error) {
+
+	return gtsUpdateCounter(gts, key, incr, threshold, setValue)
+}
+
+func (gts *gtsOs) UpdateElement(key Term, f GtsUpdateFunc) bool {
+	return gtsUpdateElement(gts, key, f)
+}
+
+func (gts *gtsOs) InsertNew(key Term, value Term) bool {
+	return gtsInsertNew(gts, key, value)
+}
+
+func (gts *gtsOs) Take(key Term) (Term, bool) {
+	return gtsTake(gts, key)
+}
+
+func (gts *gtsOs) lookup(key Term) (Term, bool) {
+	return gts.tree.Get(key)
 }
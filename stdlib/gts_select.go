@@ -0,0 +1,267 @@
+package stdlib
+
+//
+// Select and match queries over Gts tables
+//
+
+import (
+	"reflect"
+)
+
+//
+// GtsMatch is a pattern to match a single term (key or value)
+//
+type GtsMatch func(t Term) bool
+
+//
+// GtsGuard is a predicate on key and value of the table object
+//
+type GtsGuard func(key, value Term) bool
+
+//
+// GtsObject is a key-value pair returned by select functions
+//
+type GtsObject struct {
+	Key   Term
+	Value Term
+}
+
+//
+// GtsMatchSpec describes which objects select functions return.
+// Nil Key or Value pattern matches any term.
+//
+type GtsMatchSpec struct {
+	Key    GtsMatch
+	Value  GtsMatch
+	Guards []GtsGuard
+}
+
+//
+// GtsContinuation is used to get next portion of objects from
+//  limited select
+//
+type GtsContinuation struct {
+	tab   gtsScanner
+	ms    *GtsMatchSpec
+	limit int
+	pos   Term
+}
+
+//
+// gtsScanner is implemented by all table types.
+// scan calls f for objects starting at pos (nil - from the beginning).
+// When f returns false scan stops and returns position of the object passed
+// to f, to resume scan from it. Returns nil if all objects were scanned.
+// scan must not modify the table.
+//
+type gtsScanner interface {
+	scan(pos Term, f GtsForEach) Term
+	Delete(key Term)
+}
+
+//
+// NewGtsMatchSpec makes match spec that matches all objects
+//
+func NewGtsMatchSpec() *GtsMatchSpec {
+	return new(GtsMatchSpec)
+}
+
+//
+// WithKey sets key pattern
+//
+func (ms *GtsMatchSpec) WithKey(m GtsMatch) *GtsMatchSpec {
+
+	ms.Key = m
+
+	return ms
+}
+
+//
+// WithValue sets value pattern
+//
+func (ms *GtsMatchSpec) WithValue(m GtsMatch) *GtsMatchSpec {
+
+	ms.Value = m
+
+	return ms
+}
+
+//
+// WithGuard adds guard predicate. All guards must be true to match object
+//
+func (ms *GtsMatchSpec) WithGuard(g GtsGuard) *GtsMatchSpec {
+
+	ms.Guards = append(ms.Guards, g)
+
+	return ms
+}
+
+//
+// Match checks if object matches spec. Nil spec matches all objects
+//
+func (ms *GtsMatchSpec) Match(key, value Term) bool {
+	if ms == nil {
+		return true
+	}
+	if ms.Key != nil && !ms.Key(key) {
+		return false
+	}
+	if ms.Value != nil && !ms.Value(value) {
+		return false
+	}
+	for _, g := range ms.Guards {
+		if !g(key, value) {
+			return false
+		}
+	}
+	return true
+}
+
+//
+// Patterns
+//
+
+//
+// GtsMatchAny matches any term
+//
+func GtsMatchAny() GtsMatch {
+	return func(t Term) bool {
+		return true
+	}
+}
+
+//
+// GtsMatchEq matches terms equal to v
+//
+func GtsMatchEq(v Term) GtsMatch {
+	return func(t Term) bool {
+		return reflect.DeepEqual(t, v)
+	}
+}
+
+//
+// GtsMatchType matches terms of the same type as sample
+//
+func GtsMatchType(sample Term) GtsMatch {
+	typ := reflect.TypeOf(sample)
+	return func(t Term) bool {
+		return reflect.TypeOf(t) == typ
+	}
+}
+
+//
+// GtsMatchCmp matches terms for which cmp(term, v) result satisfies f,
+//  e.g. GtsMatchCmp(v, cmp, func(r int) bool { return r > 0 }) matches
+//  terms greater than v
+//
+func GtsMatchCmp(v Term, cmp GtsKeysComparator, f func(r int) bool) GtsMatch {
+	return func(t Term) bool {
+		return f(cmp(t, v))
+	}
+}
+
+//
+// GtsMatchAnd matches if all patterns match
+//
+func GtsMatchAnd(ms ...GtsMatch) GtsMatch {
+	return func(t Term) bool {
+		for _, m := range ms {
+			if !m(t) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+//
+// GtsMatchOr matches if any of patterns matches
+//
+func GtsMatchOr(ms ...GtsMatch) GtsMatch {
+	return func(t Term) bool {
+		for _, m := range ms {
+			if m(t) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+//
+// GtsMatchNot negates pattern
+//
+func GtsMatchNot(m GtsMatch) GtsMatch {
+	return func(t Term) bool {
+		return !m(t)
+	}
+}
+
+//
+// Select
+//
+func gtsSelect(
+	tab gtsScanner,
+	ms *GtsMatchSpec,
+	limit int,
+	pos Term) ([]GtsObject, *GtsContinuation) {
+
+	var objs []GtsObject
+
+	pos = tab.scan(pos, func(k, v interface{}) bool {
+		if !ms.Match(k, v) {
+			return true
+		}
+		if limit > 0 && len(objs) == limit {
+			// one more object matched, resume from it
+			return false
+		}
+		objs = append(objs, GtsObject{k, v})
+		return true
+	})
+
+	if pos == nil {
+		return objs, nil
+	}
+
+	return objs, &GtsContinuation{tab, ms, limit, pos}
+}
+
+func gtsSelectContinue(c *GtsContinuation) ([]GtsObject, *GtsContinuation) {
+	if c == nil {
+		return nil, nil
+	}
+	return gtsSelect(c.tab, c.ms, c.limit, c.pos)
+}
+
+func gtsSelectDelete(tab gtsScanner, ms *GtsMatchSpec) int {
+
+	var keys []Term
+
+	tab.scan(nil, func(k, v interface{}) bool {
+		if ms.Match(k, v) {
+			keys = append(keys, k)
+		}
+		return true
+	})
+
+	for _, k := range keys {
+		tab.Delete(k)
+	}
+
+	return len(keys)
+}
+
+func gtsSelectCount(tab gtsScanner, ms *GtsMatchSpec) int {
+
+	n := 0
+
+	tab.scan(nil, func(k, v interface{}) bool {
+		if ms.Match(k, v) {
+			n++
+		}
+		return true
+	})
+
+	return n
+}
@@ -0,0 +1,179 @@
+package stdlib
+
+import (
+	"sort"
+	"testing"
+)
+
+func gtsSelectTestTabs() map[string]Gts {
+	tabs := map[string]Gts{
+		"set":         NewSet(),
+		"ordered_set": NewOrderedSet(),
+	}
+
+	for _, tab := range tabs {
+		for i := 1; i <= 10; i++ {
+			v := "odd"
+			if i%2 == 0 {
+				v = "even"
+			}
+			tab.Insert(i, v)
+		}
+	}
+
+	return tabs
+}
+
+func gtsObjectKeys(objs []GtsObject) []int {
+	keys := make([]int, len(objs))
+	for i, o := range objs {
+		keys[i] = o.Key.(int)
+	}
+	sort.Ints(keys)
+	return keys
+}
+
+func TestGtsSelect(t *testing.T) {
+
+	for name, tab := range gtsSelectTestTabs() {
+		t.Run(name, func(t *testing.T) {
+
+			objs, c := tab.Select(nil, 0)
+			if len(objs) != 10 || c != nil {
+				t.Fatalf("expected 10 objects and no continuation, "+
+					"actual %d, %v", len(objs), c)
+			}
+
+			ms := NewGtsMatchSpec().
+				WithValue(GtsMatchEq("even")).
+				WithGuard(func(k, v Term) bool { return k.(int) > 4 })
+
+			objs, _ = tab.Select(ms, 0)
+			keys := gtsObjectKeys(objs)
+			expected := []int{6, 8, 10}
+			if len(keys) != len(expected) {
+				t.Fatalf("expected keys %v, actual %v", expected, keys)
+			}
+			for i := range keys {
+				if keys[i] != expected[i] {
+					t.Fatalf("expected keys %v, actual %v", expected, keys)
+				}
+			}
+
+			ms = NewGtsMatchSpec().
+				WithKey(GtsMatchOr(GtsMatchEq(1), GtsMatchEq(2))).
+				WithValue(GtsMatchNot(GtsMatchEq("even")))
+			if n := tab.SelectCount(ms); n != 1 {
+				t.Fatalf("expected count 1, actual %d", n)
+			}
+
+			ms = NewGtsMatchSpec().WithKey(GtsMatchType(""))
+			if n := tab.SelectCount(ms); n != 0 {
+				t.Fatalf("expected count 0, actual %d", n)
+			}
+
+			// ForEach is not used, table is not modified
+			if tab.Size() != 10 {
+				t.Fatalf("expected tab size 10, actual %d", tab.Size())
+			}
+		})
+	}
+}
+
+func TestGtsSelectContinue(t *testing.T) {
+
+	for name, tab := range gtsSelectTestTabs() {
+		t.Run(name, func(t *testing.T) {
+
+			ms := NewGtsMatchSpec().WithValue(GtsMatchEq("odd"))
+
+			var all []GtsObject
+			objs, c := tab.Select(ms, 2)
+			all = append(all, objs...)
+			pages := 1
+			for c != nil {
+				if len(objs) != 2 {
+					t.Fatalf("expected page of 2 objects, actual %d", len(objs))
+				}
+				objs, c = tab.SelectContinue(c)
+				all = append(all, objs...)
+				pages++
+			}
+
+			if pages != 3 {
+				t.Fatalf("expected 3 pages, actual %d", pages)
+			}
+
+			keys := gtsObjectKeys(all)
+			expected := []int{1, 3, 5, 7, 9}
+			if len(keys) != len(expected) {
+				t.Fatalf("expected keys %v, actual %v", expected, keys)
+			}
+			for i := range keys {
+				if keys[i] != expected[i] {
+					t.Fatalf("expected keys %v, actual %v", expected, keys)
+				}
+			}
+		})
+	}
+}
+
+func TestGtsSelectContinueAfterDelete(t *testing.T) {
+
+	tab := NewOrderedSet()
+	for i := 1; i <= 6; i++ {
+		tab.Insert(i, i)
+	}
+
+	objs, c := tab.Select(nil, 3)
+	if len(objs) != 3 || c == nil {
+		t.Fatalf("expected 3 objects and continuation, actual %d, %v",
+			len(objs), c)
+	}
+
+	// next page starts from deleted key 4
+	tab.Delete(4)
+
+	objs, c = tab.SelectContinue(c)
+	keys := gtsObjectKeys(objs)
+	if len(keys) != 2 || keys[0] != 5 || keys[1] != 6 || c != nil {
+		t.Fatalf("expected keys [5 6] and no continuation, actual %v, %v",
+			keys, c)
+	}
+}
+
+func TestGtsSelectDelete(t *testing.T) {
+
+	for name, tab := range gtsSelectTestTabs() {
+		t.Run(name, func(t *testing.T) {
+
+			ms := NewGtsMatchSpec().WithValue(GtsMatchEq("even"))
+			if n := tab.SelectDelete(ms); n != 5 {
+				t.Fatalf("expected 5 deleted objects, actual %d", n)
+			}
+			if tab.Size() != 5 {
+				t.Fatalf("expected tab size 5, actual %d", tab.Size())
+			}
+			if n := tab.SelectCount(ms); n != 0 {
+				t.Fatalf("expected count 0, actual %d", n)
+			}
+		})
+	}
+}
+
+func TestGtsMatchCmp(t *testing.T) {
+
+	tab := NewOrderedSetWith(cmpTimer)
+	tab.Insert(&timer{10, "a"}, 1)
+	tab.Insert(&timer{20, "b"}, 2)
+	tab.Insert(&timer{30, "c"}, 3)
+
+	gt := func(r int) bool { return r > 0 }
+	ms := NewGtsMatchSpec().
+		WithKey(GtsMatchCmp(&timer{15, ""}, cmpTimer, gt))
+
+	objs, _ := tab.Select(ms, 0)
+	if len(objs) != 2 || objs[0].Value != 2 || objs[1].Value != 3 {
+		t.Fatalf("expected values [2 3], actual %v", objs)
+	}
+}
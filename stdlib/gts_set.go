@@ -2,11 +2,13 @@ package stdlib
 
 //
 // Implements Set
-// Iterator returns nil values always
+// Iterator returns nil values always, ordered access panics as keys are not
+// ordered
 //
 
 import (
 	"fmt"
+	"time"
 )
 
 //
@@ -26,6 +28,10 @@ func (gts *gtsS) Insert(key Term, value Term) {
 	gts.set[key] = value
 }
 
+func (gts *gtsS) InsertTTL(key Term, value Term, ttl time.Duration) {
+	gts.Insert(key, value)
+}
+
 func (gts *gtsS) Delete(key Term) {
 	delete(gts.set, key)
 }
@@ -46,6 +52,13 @@ func (gts *gtsS) DeleteAllObjects() {
 	gts.set = make(map[Term]Term)
 }
 
+func (gts *gtsS) Close() {
+}
+
+func (gts *gtsS) Info() GtsInfo {
+	return GtsInfo{Type: GtsTypeSet}
+}
+
 func (gts *gtsS) Print() {
 	fmt.Println(gts.set)
 }
@@ -69,6 +82,33 @@ func (gts *gtsS) Prev() (Term, Term, bool) {
 	return nil, nil, false
 }
 
+func (gts *gtsS) Cursor() GtsCursor {
+	panic(gtsNotOrdered("Cursor"))
+}
+
+func (gts *gtsS) Floor(key Term) (Term, Term, bool) {
+	panic(gtsNotOrdered("Floor"))
+}
+
+func (gts *gtsS) Ceiling(key Term) (Term, Term, bool) {
+	panic(gtsNotOrdered("Ceiling"))
+}
+
+func (gts *gtsS) Range(from, to Term, f GtsForEach) {
+	panic(gtsNotOrdered("Range"))
+}
+
+//
+// gtsNotOrdered returns panic value of ordered access to the set
+//
+func gtsNotOrdered(op string) string {
+	return "gts: " + op + " of set, keys of set are not ordered, " +
+		"use ordered set"
+}
+
+//
+// ForEach deletes objects for which f returns false
+//
 func (gts *gtsS) ForEach(f GtsForEach) {
 	for k, v := range gts.set {
 		if !f(k, v) {
@@ -76,3 +116,97 @@ func (gts *gtsS) ForEach(f GtsForEach) {
 		}
 	}
 }
+
+//
+// Selector
+//
+func (gts *gtsS) Select(
+	ms *GtsMatchSpec, limit int) ([]GtsObject, *GtsContinuation) {
+
+	return gtsSelect(gts, ms, limit, nil)
+}
+
+func (gts *gtsS) SelectContinue(
+	c *GtsContinuation) ([]GtsObject, *GtsContinuation) {
+
+	return gtsSelectContinue(c)
+}
+
+func (gts *gtsS) SelectDelete(ms *GtsMatchSpec) int {
+	return gtsSelectDelete(gts, ms)
+}
+
+func (gts *gtsS) SelectCount(ms *GtsMatchSpec) int {
+	return gtsSelectCount(gts, ms)
+}
+
+//
+// Set has no order, scan position is a snapshot of keys not scanned yet.
+// Keys deleted after the snapshot was taken are skipped
+//
+func (gts *gtsS) scan(pos Term, f GtsForEach) Term {
+
+	var keys []Term
+
+	if pos == nil {
+		keys = make([]Term, 0, len(gts.set))
+		for k := range gts.set {
+			keys = append(keys, k)
+		}
+	} else {
+		keys = pos.([]Term)
+	}
+
+	for i, k := range keys {
+		v, ok := gts.set[k]
+		if !ok {
+			continue
+		}
+		if !f(k, v) {
+			return keys[i:]
+		}
+	}
+
+	return nil
+}
+
+//
+// Indexer. Table without indexes
+//
+func (gts *gtsS) LookupByIndex(
+	index string, ikey Term) ([]GtsObject, error) {
+
+	return nil, BadArgError
+}
+
+func (gts *gtsS) IndexRange(
+	index string, from, to Term, f GtsForEach) error {
+
+	return BadArgError
+}
+
+//
+// Updater
+//
+func (gts *gtsS) UpdateCounter(
+	key Term, incr, threshold, setValue int64) (int64, error) {
+
+	return gtsUpdateCounter(gts, key, incr, threshold, setValue)
+}
+
+func (gts *gtsS) UpdateElement(key Term, f GtsUpdateFunc) bool {
+	return gtsUpdateElement(gts, key, f)
+}
+
+func (gts *gtsS) InsertNew(key Term, value Term) bool {
+	return gtsInsertNew(gts, key, value)
+}
+
+func (gts *gtsS) Take(key Term) (Term, bool) {
+	return gtsTake(gts, key)
+}
+
+func (gts *gtsS) lookup(key Term) (Term, bool) {
+	value, found := gts.set[key]
+	return value, found
+}
@@ -0,0 +1,290 @@
+package stdlib
+
+//
+// Snapshot of the table to file and restore from file.
+//
+// File format: header (magic, version, table type, keys comparator id,
+// objects count), records of objects, crc32 of all preceding bytes
+//
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/emirpasic/gods/utils"
+)
+
+//
+// GtsIntComparatorID is an id of the int keys comparator of ordered set
+//
+const GtsIntComparatorID = "int"
+
+//
+// GtsStringComparatorID is an id of the string keys comparator of ordered set
+//
+const GtsStringComparatorID = "string"
+
+var (
+	gtsCmpMu  sync.RWMutex
+	gtsCmpReg = map[string]GtsKeysComparator{
+		GtsIntComparatorID:    GtsKeysComparator(utils.IntComparator),
+		GtsStringComparatorID: GtsKeysComparator(utils.StringComparator),
+	}
+)
+
+//
+// RegisterGtsKeysComparator registers keys comparator with id to make
+//  ordered sets with GtsOpts.WithKeysComparatorID and restore them
+//  from snapshots
+//
+func RegisterGtsKeysComparator(id string, cmp GtsKeysComparator) {
+	gtsCmpMu.Lock()
+	gtsCmpReg[id] = cmp
+	gtsCmpMu.Unlock()
+}
+
+func gtsKeysComparator(id string) GtsKeysComparator {
+	gtsCmpMu.RLock()
+	defer gtsCmpMu.RUnlock()
+
+	return gtsCmpReg[id]
+}
+
+//
+// GtsTab2File dumps table to file. Options may set codec of keys and values
+//  and keys comparator id of ordered set, if the table was not made with
+//  comparator id. File is replaced atomically
+//
+func GtsTab2File(tab Gts, path string, opts *GtsOpts) error {
+
+	if opts == nil {
+		opts = NewGtsOpts()
+	}
+	codec := opts.codec
+	if codec == nil {
+		codec = GtsGobCodec()
+	}
+
+	info := tab.Info()
+	if opts.cmpID != "" {
+		info.KeysComparatorID = opts.cmpID
+	}
+	if info.Type == GtsTypeOrderedSet && info.KeysComparatorID == "" {
+		return errors.New("gts: keys comparator id of ordered set is unknown")
+	}
+
+	objs, _ := tab.Select(nil, 0)
+
+	tmpPath := path + ".tmp"
+	f, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	err = writeGtsSnapshot(f, info, objs, codec)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, path)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	syncGtsDir(path)
+
+	return nil
+}
+
+//
+// GtsFile2Tab makes new table from file. Options may set codec of keys and
+//  values and other table options, table type and keys comparator are taken
+//  from file
+//
+func GtsFile2Tab(path string, opts *GtsOpts) (Gts, error) {
+
+	if opts == nil {
+		opts = NewGtsOpts()
+	}
+	codec := opts.codec
+	if codec == nil {
+		codec = GtsGobCodec()
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, objs, err := readGtsSnapshot(bufio.NewReader(f), codec)
+	if err != nil {
+		return nil, err
+	}
+
+	tabOpts := *opts
+	var tab Gts
+
+	switch info.Type {
+	case GtsTypeSet:
+		tab = NewSetOpts(&tabOpts)
+
+	case GtsTypeOrderedSet:
+		cmp := gtsKeysComparator(info.KeysComparatorID)
+		if cmp == nil {
+			return nil, fmt.Errorf(
+				"gts: keys comparator '%s' is not registered",
+				info.KeysComparatorID)
+		}
+		tabOpts.cmp = cmp
+		tabOpts.cmpID = info.KeysComparatorID
+		tab = NewOrderedSetOpts(&tabOpts)
+
+	default:
+		return nil, errGtsBadHeader
+	}
+
+	for _, o := range objs {
+		tab.Insert(o.Key, o.Value)
+	}
+
+	return tab, nil
+}
+
+// ---------------------------------------------------------------------------
+// Locals
+// ---------------------------------------------------------------------------
+
+const (
+	gtsSnapshotMagic   = "GTSD"
+	gtsSnapshotVersion = 1
+)
+
+func writeGtsSnapshot(
+	f io.Writer, info GtsInfo, objs []GtsObject, codec GtsCodec) error {
+
+	crc := crc32.NewIEEE()
+	bw := bufio.NewWriter(f)
+	w := io.MultiWriter(bw, crc)
+
+	hdr := make([]byte, 0, 32)
+	hdr = append(hdr, gtsSnapshotMagic...)
+	hdr = append(hdr, gtsSnapshotVersion, byte(info.Type))
+	hdr = appendUint16(hdr, uint16(len(info.KeysComparatorID)))
+	hdr = append(hdr, info.KeysComparatorID...)
+	hdr = appendUint64(hdr, uint64(len(objs)))
+
+	if _, err := w.Write(hdr); err != nil {
+		return err
+	}
+
+	for _, o := range objs {
+		payload, err := encodeGtsRecord(codec, gtsLogInsert, o.Key, o.Value)
+		if err != nil {
+			return err
+		}
+		if err = writeGtsRecord(w, payload); err != nil {
+			return err
+		}
+	}
+
+	if _, err := bw.Write(appendUint32(nil, crc.Sum32())); err != nil {
+		return err
+	}
+
+	return bw.Flush()
+}
+
+func readGtsSnapshot(
+	r io.Reader, codec GtsCodec) (info GtsInfo, objs []GtsObject, err error) {
+
+	crc := crc32.NewIEEE()
+	tr := io.TeeReader(r, crc)
+
+	var hdr [8]byte
+	if _, err = io.ReadFull(tr, hdr[:len(gtsSnapshotMagic)+4]); err != nil {
+		return info, nil, errGtsBadHeader
+	}
+	if string(hdr[:len(gtsSnapshotMagic)]) != gtsSnapshotMagic ||
+		hdr[4] != gtsSnapshotVersion {
+		return info, nil, errGtsBadHeader
+	}
+	info.Type = GtsType(hdr[5])
+
+	cmpID := make([]byte, binary.BigEndian.Uint16(hdr[6:8]))
+	if _, err = io.ReadFull(tr, cmpID); err != nil {
+		return info, nil, errGtsBadHeader
+	}
+	info.KeysComparatorID = string(cmpID)
+
+	if _, err = io.ReadFull(tr, hdr[:]); err != nil {
+		return info, nil, errGtsBadHeader
+	}
+	count := binary.BigEndian.Uint64(hdr[:])
+
+	for i := uint64(0); i < count; i++ {
+		payload, _, err := readGtsRecord(tr, gtsMaxRecord+gtsRecHdrLen)
+		if err != nil {
+			return info, nil, checkGtsSnapshotErr(err)
+		}
+		_, key, value, err := decodeGtsRecord(codec, payload)
+		if err != nil {
+			return info, nil, err
+		}
+		objs = append(objs, GtsObject{key, value})
+	}
+
+	if err = checkGtsSnapshotSum(r, crc); err != nil {
+		return info, nil, err
+	}
+
+	return info, objs, nil
+}
+
+func checkGtsSnapshotSum(r io.Reader, crc hash.Hash32) error {
+	var sum [4]byte
+	if _, err := io.ReadFull(r, sum[:]); err != nil {
+		return checkGtsSnapshotErr(err)
+	}
+	if binary.BigEndian.Uint32(sum[:]) != crc.Sum32() {
+		return errors.New("gts: snapshot checksum mismatch")
+	}
+	return nil
+}
+
+func checkGtsSnapshotErr(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF || err == errGtsBadRecord {
+		return errors.New("gts: snapshot is truncated or corrupted")
+	}
+	return err
+}
+
+func appendUint16(b []byte, v uint16) []byte {
+	var buf [2]byte
+	binary.BigEndian.PutUint16(buf[:], v)
+	return append(b, buf[:]...)
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], v)
+	return append(b, buf[:]...)
+}
+
+func appendUint64(b []byte, v uint64) []byte {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], v)
+	return append(b, buf[:]...)
+}
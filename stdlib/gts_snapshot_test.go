@@ -0,0 +1,118 @@
+package stdlib
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func cmpIntDesc(a, b interface{}) int {
+	return b.(int) - a.(int)
+}
+
+func TestGtsSnapshot(t *testing.T) {
+
+	dir := gtsTestDir(t)
+	defer os.RemoveAll(dir)
+
+	RegisterGtsKeysComparator("int_desc", cmpIntDesc)
+
+	tabs := map[string]Gts{
+		"set":         NewSet(),
+		"ordered_set": NewOrderedSet(),
+		"ordered_set_desc": NewOrderedSetOpts(
+			NewGtsOpts().WithKeysComparatorID("int_desc")),
+	}
+
+	for name, tab := range tabs {
+		t.Run(name, func(t *testing.T) {
+
+			for i := 1; i <= 10; i++ {
+				tab.Insert(i, "v")
+			}
+
+			path := filepath.Join(dir, name)
+			if err := GtsTab2File(tab, path, nil); err != nil {
+				t.Fatal(err)
+			}
+
+			tab2, err := GtsFile2Tab(path, NewGtsOpts().WithLock())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tab2.Info() != tab.Info() {
+				t.Fatalf("expected info %v, actual %v", tab.Info(), tab2.Info())
+			}
+			if tab2.Size() != 10 || tab2.Lookup(5) != "v" {
+				t.Fatalf("expected 10 objects, actual %d", tab2.Size())
+			}
+
+			k1, _, _ := tab.First()
+			k2, _, _ := tab2.First()
+			if k1 != k2 {
+				t.Fatalf("expected first key %v, actual %v", k1, k2)
+			}
+		})
+	}
+}
+
+func TestGtsSnapshotComparatorID(t *testing.T) {
+
+	dir := gtsTestDir(t)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "tab")
+
+	tab := NewOrderedSetWith(cmpIntDesc)
+	tab.Insert(1, 1)
+
+	if err := GtsTab2File(tab, path, nil); err == nil {
+		t.Fatal("expected unknown comparator id error")
+	}
+
+	opts := NewGtsOpts().WithKeysComparatorID("int_desc_unknown")
+	if err := GtsTab2File(tab, path, opts); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GtsFile2Tab(path, nil); err == nil {
+		t.Fatal("expected not registered comparator error")
+	}
+}
+
+func TestGtsSnapshotCorrupted(t *testing.T) {
+
+	dir := gtsTestDir(t)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "tab")
+
+	tab := NewSet()
+	tab.Insert("key", "value")
+	if err := GtsTab2File(tab, path, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// truncated
+	if err = ioutil.WriteFile(path, data[:len(data)-1], 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = GtsFile2Tab(path, nil); err == nil {
+		t.Fatal("expected truncated snapshot error")
+	}
+
+	// checksum
+	data[len(data)-1]++
+	if err = ioutil.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = GtsFile2Tab(path, nil); err == nil {
+		t.Fatal("expected checksum error")
+	}
+}
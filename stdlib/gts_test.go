@@ -250,6 +250,155 @@ func TestGtsSet(t *testing.T) {
 	tab.Print()
 }
 
+func TestGtsSetNotOrdered(t *testing.T) {
+
+	tab := NewSetOpts(NewGtsOpts().WithLock())
+	tab.Insert(1, "one")
+
+	for name, f := range map[string]func(){
+		"Cursor":  func() { tab.Cursor() },
+		"Floor":   func() { tab.Floor(1) },
+		"Ceiling": func() { tab.Ceiling(1) },
+		"Range": func() {
+			tab.Range(1, 2, func(k, v interface{}) bool { return true })
+		},
+	} {
+		func() {
+			defer func() {
+				if r := recover(); r != gtsNotOrdered(name) {
+					t.Fatalf("%s: expected panic, actual %v", name, r)
+				}
+			}()
+			f()
+		}()
+	}
+
+	// table is unlocked after panic
+	if v := tab.Lookup(1); v != "one" {
+		t.Fatalf("expected value 'one', actual '%v'", v)
+	}
+}
+
+func TestGtsOsCursors(t *testing.T) {
+
+	tab := NewOrderedSet()
+	for i := 1; i <= 5; i++ {
+		tab.Insert(i*10, i)
+	}
+
+	c1 := tab.Cursor()
+	c2 := tab.Cursor()
+
+	k1, _, _ := c1.First()
+	k2, _, _ := c2.Last()
+	k1, _, _ = c1.Next()
+	k2, _, _ = c2.Prev()
+	if k1 != 20 || k2 != 40 {
+		t.Fatalf("expected cursors at 20 and 40, actual %v and %v", k1, k2)
+	}
+
+	// modifications do not reset cursors
+	tab.Insert(25, 0)
+	tab.Delete(30)
+
+	if k, _, _ := c1.Next(); k != 25 {
+		t.Fatalf("expected next key 25, actual %v", k)
+	}
+	if k, _, _ := c2.Prev(); k != 25 {
+		t.Fatalf("expected prev key 25, actual %v", k)
+	}
+
+	// delete current key of the cursor
+	tab.Delete(25)
+	if k, _, _ := c1.Next(); k != 40 {
+		t.Fatalf("expected next key 40, actual %v", k)
+	}
+	if k, _, _ := c2.Prev(); k != 20 {
+		t.Fatalf("expected prev key 20, actual %v", k)
+	}
+}
+
+func TestGtsOsSeek(t *testing.T) {
+
+	tab := NewOrderedSet()
+	for i := 1; i <= 5; i++ {
+		tab.Insert(i*10, i)
+	}
+
+	c := tab.Cursor()
+
+	if k, v, ok := c.Seek(30); !ok || k != 30 || v != 3 {
+		t.Fatalf("expected 30, 3, true, actual %v, %v, %v", k, v, ok)
+	}
+	if _, _, ok := c.Seek(35); ok {
+		t.Fatal("expected key 35 not found")
+	}
+	if k, _, _ := c.Next(); k != 40 {
+		t.Fatalf("expected next key 40, actual %v", k)
+	}
+	if _, _, ok := c.Seek(35); ok {
+		t.Fatal("expected key 35 not found")
+	}
+	if k, _, _ := c.Prev(); k != 30 {
+		t.Fatalf("expected prev key 30, actual %v", k)
+	}
+
+	if k, _, _ := c.Floor(35); k != 30 {
+		t.Fatalf("expected floor key 30, actual %v", k)
+	}
+	if k, _, _ := c.Ceiling(35); k != 40 {
+		t.Fatalf("expected ceiling key 40, actual %v", k)
+	}
+
+	if _, _, ok := c.Ceiling(51); ok {
+		t.Fatal("expected no ceiling key for 51")
+	}
+	if k, _, _ := c.Prev(); k != 50 {
+		t.Fatalf("expected prev key 50 after end, actual %v", k)
+	}
+
+	if _, _, ok := c.Floor(5); ok {
+		t.Fatal("expected no floor key for 5")
+	}
+	if k, _, _ := c.Next(); k != 10 {
+		t.Fatalf("expected next key 10 after begin, actual %v", k)
+	}
+
+	if k, _, ok := tab.Floor(49); !ok || k != 40 {
+		t.Fatalf("expected floor key 40, actual %v", k)
+	}
+	if k, _, ok := tab.Ceiling(11); !ok || k != 20 {
+		t.Fatalf("expected ceiling key 20, actual %v", k)
+	}
+}
+
+func TestGtsOsRange(t *testing.T) {
+
+	tab := NewOrderedSet()
+	for i := 1; i <= 10; i++ {
+		tab.Insert(i, i)
+	}
+
+	var keys []Term
+	tab.Range(3, 7, func(k, v interface{}) bool {
+		keys = append(keys, k)
+		if k == 4 {
+			tab.Delete(5)
+		}
+		return true
+	})
+
+	expected := []Term{3, 4, 6, 7}
+	if len(keys) != len(expected) {
+		t.Fatalf("expected keys %v, actual %v", expected, keys)
+	}
+	for i := range keys {
+		if keys[i] != expected[i] {
+			t.Fatalf("expected keys %v, actual %v", expected, keys)
+		}
+	}
+}
+
 func BenchmarkGtsOs(b *testing.B) {
 
 	tab := NewOrderedSet()
@@ -0,0 +1,518 @@
+package stdlib
+
+//
+// Table with expiring objects. Not thread-safe, always used guarded by
+// gtsLocked. Expired objects are deleted before any access to the table and
+// periodically by sweeper process. Sweeper is started by the table when it
+// gets expiring objects and exits when they are gone or the table is closed,
+// so it does not keep the table longer than ttl of its objects. Sweeper start
+// and expiry notifications are left pending under the table lock and done
+// after the table is unlocked
+//
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+//
+// GtsExpired is a message sent to pid set by GtsOpts.WithExpiryNotify
+//  when object expires
+//
+type GtsExpired struct {
+	Key   Term
+	Value Term
+}
+
+type gtsTTL struct {
+	tab        Gts
+	defaultTTL time.Duration
+	notify     *Pid
+
+	locked        *gtsLocked
+	sweepInterval uint32
+
+	// sweeper and work pending until the table is unlocked, guarded by pmu
+	pmu          sync.Mutex
+	sweeper      *Pid
+	sweepNeeded  bool
+	sweepStarts  bool
+	sweeperExits *Pid
+	closed       bool
+	expired      []*GtsExpired
+
+	// expiry time by key and keys by expiry time
+	expires map[Term]*gtsExpiry
+	byTime  Gts
+	seq     uint64
+}
+
+type gtsExpiry struct {
+	when time.Time
+	seq  uint64
+}
+
+func cmpGtsExpiry(a1, b1 interface{}) int {
+
+	a := a1.(*gtsExpiry)
+	b := b1.(*gtsExpiry)
+
+	switch {
+	case a.when.After(b.when):
+		return 1
+	case a.when.Before(b.when):
+		return -1
+	case a.seq > b.seq:
+		return 1
+	case a.seq < b.seq:
+		return -1
+	default:
+		return 0
+	}
+}
+
+//
+// newGtsTTL returns table guarded by gtsLocked
+//
+func newGtsTTL(tab Gts, opts *GtsOpts) Gts {
+
+	gts := &gtsTTL{
+		tab:           tab,
+		defaultTTL:    opts.defaultTTL,
+		notify:        opts.expiryNotify,
+		sweepInterval: opts.sweepInterval,
+		expires:       make(map[Term]*gtsExpiry),
+		byTime:        NewOrderedSetWith(cmpGtsExpiry),
+	}
+	// expired objects are deleted on reads, reads need exclusive lock
+	gts.locked = newGtsLocked(gts, true).(*gtsLocked)
+	gts.locked.after = gts.flush
+
+	return gts.locked
+}
+
+//
+// Expiry
+//
+func (gts *gtsTTL) setTTL(key Term, ttl time.Duration) {
+
+	gts.clearTTL(key)
+
+	if ttl <= 0 {
+		return
+	}
+
+	gts.seq++
+	e := &gtsExpiry{time.Now().Add(ttl), gts.seq}
+	gts.expires[key] = e
+	gts.byTime.Insert(e, key)
+
+	if gts.sweepInterval > 0 {
+		gts.pmu.Lock()
+		gts.sweepNeeded = gts.sweeper == nil
+		gts.pmu.Unlock()
+	}
+}
+
+func (gts *gtsTTL) clearTTL(key Term) {
+	if e, ok := gts.expires[key]; ok {
+		gts.byTime.Delete(e)
+		delete(gts.expires, key)
+	}
+}
+
+//
+// expire deletes objects expired by now
+//
+func (gts *gtsTTL) expire(now time.Time) {
+
+	for k, v, ok := gts.byTime.First(); ok; k, v, ok = gts.byTime.First() {
+
+		if k.(*gtsExpiry).when.After(now) {
+			return
+		}
+
+		gts.byTime.Delete(k)
+		delete(gts.expires, v)
+
+		if value, found := gts.tab.Take(v); found && gts.notify != nil {
+			gts.pmu.Lock()
+			gts.expired = append(gts.expired, &GtsExpired{v, value})
+			gts.pmu.Unlock()
+		}
+	}
+}
+
+func (gts *gtsTTL) expireNow() {
+	gts.expire(time.Now())
+}
+
+//
+// Gts
+//
+func (gts *gtsTTL) Insert(key Term, value Term) {
+	gts.InsertTTL(key, value, gts.defaultTTL)
+}
+
+func (gts *gtsTTL) InsertTTL(key Term, value Term, ttl time.Duration) {
+	gts.expireNow()
+	gts.tab.Insert(key, value)
+	gts.setTTL(key, ttl)
+}
+
+func (gts *gtsTTL) Delete(key Term) {
+	gts.tab.Delete(key)
+	gts.clearTTL(key)
+}
+
+func (gts *gtsTTL) Lookup(key Term) Term {
+	gts.expireNow()
+	return gts.tab.Lookup(key)
+}
+
+func (gts *gtsTTL) Size() int {
+	gts.expireNow()
+	return gts.tab.Size()
+}
+
+func (gts *gtsTTL) DeleteAllObjects() {
+	gts.tab.DeleteAllObjects()
+	gts.expires = make(map[Term]*gtsExpiry)
+	gts.byTime.DeleteAllObjects()
+}
+
+func (gts *gtsTTL) Close() {
+	gts.pmu.Lock()
+	gts.closed = true
+	gts.sweeperExits, gts.sweeper = gts.sweeper, nil
+	gts.pmu.Unlock()
+
+	gts.tab.Close()
+}
+
+func (gts *gtsTTL) Info() GtsInfo {
+	return gts.tab.Info()
+}
+
+func (gts *gtsTTL) Print() {
+	gts.expireNow()
+	gts.tab.Print()
+}
+
+//
+// Iterator
+//
+func (gts *gtsTTL) First() (Term, Term, bool) {
+	gts.expireNow()
+	return gts.tab.First()
+}
+
+func (gts *gtsTTL) Last() (Term, Term, bool) {
+	gts.expireNow()
+	return gts.tab.Last()
+}
+
+func (gts *gtsTTL) Next() (Term, Term, bool) {
+	gts.expireNow()
+	return gts.tab.Next()
+}
+
+func (gts *gtsTTL) Prev() (Term, Term, bool) {
+	gts.expireNow()
+	return gts.tab.Prev()
+}
+
+func (gts *gtsTTL) ForEach(f GtsForEach) {
+	gts.expireNow()
+
+	objs, _ := gts.tab.Select(nil, 0)
+	for _, o := range objs {
+		if !f(o.Key, o.Value) {
+			gts.Delete(o.Key)
+		}
+	}
+}
+
+func (gts *gtsTTL) Cursor() GtsCursor {
+	return &gtsTTLCursor{gts, gts.tab.Cursor()}
+}
+
+func (gts *gtsTTL) Floor(key Term) (Term, Term, bool) {
+	gts.expireNow()
+	return gts.tab.Floor(key)
+}
+
+func (gts *gtsTTL) Ceiling(key Term) (Term, Term, bool) {
+	gts.expireNow()
+	return gts.tab.Ceiling(key)
+}
+
+func (gts *gtsTTL) Range(from, to Term, f GtsForEach) {
+	gts.expireNow()
+	gts.tab.Range(from, to, f)
+}
+
+//
+// Selector
+//
+func (gts *gtsTTL) Select(
+	ms *GtsMatchSpec, limit int) ([]GtsObject, *GtsContinuation) {
+
+	gts.expireNow()
+	return gts.tab.Select(ms, limit)
+}
+
+func (gts *gtsTTL) SelectContinue(
+	c *GtsContinuation) ([]GtsObject, *GtsContinuation) {
+
+	gts.expireNow()
+	return gts.tab.SelectContinue(c)
+}
+
+func (gts *gtsTTL) SelectDelete(ms *GtsMatchSpec) int {
+	gts.expireNow()
+
+	objs, _ := gts.tab.Select(ms, 0)
+	for _, o := range objs {
+		gts.Delete(o.Key)
+	}
+
+	return len(objs)
+}
+
+func (gts *gtsTTL) SelectCount(ms *GtsMatchSpec) int {
+	gts.expireNow()
+	return gts.tab.SelectCount(ms)
+}
+
+//
+// Updater. Updated objects keep expiry time, new objects expire after
+// default ttl
+//
+func (gts *gtsTTL) UpdateCounter(
+	key Term, incr, threshold, setValue int64) (int64, error) {
+
+	gts.expireNow()
+
+	_, exists := gts.tab.(gtsStore).lookup(key)
+
+	n, err := gts.tab.UpdateCounter(key, incr, threshold, setValue)
+	if err == nil && !exists {
+		gts.setTTL(key, gts.defaultTTL)
+	}
+
+	return n, err
+}
+
+func (gts *gtsTTL) UpdateElement(key Term, f GtsUpdateFunc) bool {
+	gts.expireNow()
+	return gts.tab.UpdateElement(key, f)
+}
+
+func (gts *gtsTTL) InsertNew(key Term, value Term) bool {
+	gts.expireNow()
+
+	if !gts.tab.InsertNew(key, value) {
+		return false
+	}
+	gts.setTTL(key, gts.defaultTTL)
+
+	return true
+}
+
+func (gts *gtsTTL) Take(key Term) (Term, bool) {
+	gts.expireNow()
+
+	gts.clearTTL(key)
+	return gts.tab.Take(key)
+}
+
+//
+// Indexer
+//
+func (gts *gtsTTL) LookupByIndex(
+	index string, ikey Term) ([]GtsObject, error) {
+
+	gts.expireNow()
+	return gts.tab.LookupByIndex(index, ikey)
+}
+
+func (gts *gtsTTL) IndexRange(
+	index string, from, to Term, f GtsForEach) error {
+
+	gts.expireNow()
+	return gts.tab.IndexRange(index, from, to, f)
+}
+
+//
+// Cursor
+//
+type gtsTTLCursor struct {
+	gts *gtsTTL
+	c   GtsCursor
+}
+
+func (c *gtsTTLCursor) First() (Term, Term, bool) {
+	c.gts.expireNow()
+	return c.c.First()
+}
+
+func (c *gtsTTLCursor) Last() (Term, Term, bool) {
+	c.gts.expireNow()
+	return c.c.Last()
+}
+
+func (c *gtsTTLCursor) Next() (Term, Term, bool) {
+	c.gts.expireNow()
+	return c.c.Next()
+}
+
+func (c *gtsTTLCursor) Prev() (Term, Term, bool) {
+	c.gts.expireNow()
+	return c.c.Prev()
+}
+
+func (c *gtsTTLCursor) Seek(key Term) (Term, Term, bool) {
+	c.gts.expireNow()
+	return c.c.Seek(key)
+}
+
+func (c *gtsTTLCursor) Floor(key Term) (Term, Term, bool) {
+	c.gts.expireNow()
+	return c.c.Floor(key)
+}
+
+func (c *gtsTTLCursor) Ceiling(key Term) (Term, Term, bool) {
+	c.gts.expireNow()
+	return c.c.Ceiling(key)
+}
+
+// ---------------------------------------------------------------------------
+// Sweeper GenServer - deletes expired objects of the table periodically
+// ---------------------------------------------------------------------------
+
+type gtsSweeper struct {
+	GenServerSys
+
+	tab  *gtsTTL
+	tref Term
+}
+
+type gtsSweepMsg struct{}
+
+//
+// flush does the work left pending under the table lock: sends expiry
+//  notifications, stops sweeper of closed table and starts sweeper if
+//  expiring objects are added. Called after the table is unlocked
+//
+func (gts *gtsTTL) flush() {
+
+	gts.pmu.Lock()
+	expired, exits := gts.expired, gts.sweeperExits
+	gts.expired, gts.sweeperExits = nil, nil
+	start := gts.sweepNeeded && gts.sweeper == nil && !gts.sweepStarts &&
+		!gts.closed
+	gts.sweepNeeded = false
+	if start {
+		gts.sweepStarts = true
+	}
+	gts.pmu.Unlock()
+
+	for _, e := range expired {
+		_ = gts.notify.Send(e)
+	}
+	if exits != nil {
+		_ = exits.Exit(ExitNormal)
+	}
+	if start {
+		gts.startSweeper()
+	}
+}
+
+//
+// startSweeper starts sweeper, the sweeper sets itself as the sweeper of the
+//  table in Init. If it fails to start expired objects are deleted on access
+//  only, start is retried with the next expiring object
+//
+func (gts *gtsTTL) startSweeper() {
+
+	if err := TimerServerStart(); err == nil {
+		_, _ = GenServerStart(&gtsSweeper{tab: gts}, gts.sweepInterval)
+	}
+
+	gts.pmu.Lock()
+	gts.sweepStarts = false
+	gts.pmu.Unlock()
+}
+
+//
+// sweep deletes expired objects. Returns false if the sweeper is not the
+//  sweeper of the table or there are no expiring objects left
+//
+func (gts *gtsTTL) sweep(sweeper *Pid) bool {
+
+	gts.locked.mu.Lock()
+	defer gts.locked.unlock()
+
+	gts.pmu.Lock()
+	current := gts.sweeper == sweeper
+	gts.pmu.Unlock()
+
+	if !current {
+		return false
+	}
+
+	gts.expireNow()
+
+	if gts.byTime.Size() > 0 {
+		return true
+	}
+
+	gts.pmu.Lock()
+	gts.sweeper = nil
+	gts.pmu.Unlock()
+
+	return false
+}
+
+func (gs *gtsSweeper) Init(args ...Term) Term {
+
+	gs.tab.pmu.Lock()
+	closed := gs.tab.closed
+	if !closed {
+		gs.tab.sweeper = gs.Self()
+	}
+	gs.tab.pmu.Unlock()
+
+	if closed {
+		return errors.New("table closed")
+	}
+
+	tref, err := TimerSendInterval(args[0].(uint32), gs.Self(), gtsSweepMsg{})
+	if err != nil {
+		return err
+	}
+	gs.tref = tref
+
+	return gs.InitOk()
+}
+
+func (gs *gtsSweeper) HandleInfo(req Term) Term {
+
+	switch req.(type) {
+	case gtsSweepMsg:
+		if !gs.tab.sweep(gs.Self()) {
+			return gs.Stop(ExitNormal)
+		}
+	}
+
+	return gs.NoReply()
+}
+
+func (gs *gtsSweeper) Terminate(reason string) {
+	if err := TimerCancel(gs.tref); err != nil && reason != ExitNormal {
+		fmt.Println(gs.Self(), "gts_sweeper: terminated:", reason, err)
+	}
+}
@@ -0,0 +1,141 @@
+package stdlib
+
+import (
+	"testing"
+	"time"
+)
+
+//
+// testForwardFunc forwards usr messages of the process to channel passed
+//  as first argument
+//
+func testForwardFunc(gp GenProc, args ...Term) error {
+
+	out := args[0].(chan Term)
+
+	pid := gp.Self()
+	sys := pid.GetSysChannel()
+	usr := pid.GetUsrChannel()
+
+	for {
+		select {
+		case m := <-sys:
+			if err := gp.HandleSysMsg(m); err != nil {
+				return err
+			}
+		case m := <-usr:
+			out <- m
+		}
+	}
+}
+
+func TestGtsTTLLookup(t *testing.T) {
+
+	tabs := map[string]Gts{
+		"set": NewSetOpts(
+			NewGtsOpts().WithTTL(30 * time.Millisecond)),
+		"ordered_set": NewOrderedSetOpts(
+			NewGtsOpts().WithTTL(30 * time.Millisecond)),
+	}
+
+	for name, tab := range tabs {
+		t.Run(name, func(t *testing.T) {
+			defer tab.Close()
+
+			tab.Insert(1, "default")
+			tab.InsertTTL(2, "long", time.Hour)
+			tab.InsertTTL(3, "forever", 0)
+			if !tab.InsertNew(4, "new") {
+				t.Fatal("expected key 4 inserted")
+			}
+
+			if tab.Size() != 4 || tab.Lookup(1) != "default" {
+				t.Fatalf("expected 4 objects, actual %d", tab.Size())
+			}
+
+			time.Sleep(50 * time.Millisecond)
+
+			if v := tab.Lookup(1); v != nil {
+				t.Fatalf("expected key 1 expired, actual %v", v)
+			}
+			if tab.Size() != 2 {
+				t.Fatalf("expected 2 objects, actual %d", tab.Size())
+			}
+			if tab.Lookup(2) != "long" || tab.Lookup(3) != "forever" {
+				t.Fatal("expected keys 2 and 3 not expired")
+			}
+		})
+	}
+}
+
+func TestGtsTTLUpdateKeepsExpiry(t *testing.T) {
+
+	tab := NewSetOpts(NewGtsOpts().WithTTL(30 * time.Millisecond))
+	defer tab.Close()
+
+	if _, err := tab.UpdateCounter("c", 1, 100, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	if n, _ := tab.UpdateCounter("c", 1, 100, 0); n != 2 {
+		t.Fatalf("expected counter 2, actual %d", n)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	if v := tab.Lookup("c"); v != nil {
+		t.Fatalf("expected counter expired, actual %v", v)
+	}
+}
+
+func TestGtsTTLSweepNotify(t *testing.T) {
+
+	out := make(chan Term, 8)
+	pid, err := Spawn(testForwardFunc, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pid.Stop()
+
+	tab := NewOrderedSetOpts(
+		NewGtsOpts().
+			WithTTL(10 * time.Millisecond).
+			WithSweepInterval(20).
+			WithExpiryNotify(pid))
+	defer tab.Close()
+
+	tab.Insert(1, "one")
+
+	// no access to the table, object is deleted by sweeper
+	select {
+	case m := <-out:
+		e, ok := m.(*GtsExpired)
+		if !ok || e.Key != 1 || e.Value != "one" {
+			t.Fatalf("expected GtsExpired{1, one}, actual %#v", m)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected expiry notification")
+	}
+
+	// sweeper exits when there are no expiring objects
+	ttl := tab.(*gtsLocked).tab.(*gtsTTL)
+	sweeper := func() *Pid {
+		ttl.pmu.Lock()
+		defer ttl.pmu.Unlock()
+		return ttl.sweeper
+	}
+	for i := 0; i < 100 && sweeper() != nil; i++ {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if pid := sweeper(); pid != nil {
+		t.Fatalf("expected sweeper %s stopped", pid)
+	}
+
+	// and starts again with new expiring object
+	tab.Insert(2, "two")
+	if sweeper() == nil {
+		t.Fatal("expected sweeper started")
+	}
+}
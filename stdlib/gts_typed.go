@@ -0,0 +1,180 @@
+package stdlib
+
+//
+// Type-safe wrapper of Gts
+//
+
+//
+// GtsOf is a table with keys of type K and values of type V.
+// Objects of other types inserted into underlying table are ignored
+//
+type GtsOf[K, V any] struct {
+	tab Gts
+}
+
+//
+// NewSetOf makes new typed set with given options
+//
+func NewSetOf[K comparable, V any](opts *GtsOpts) *GtsOf[K, V] {
+	return GtsAs[K, V](NewSetOpts(opts))
+}
+
+//
+// NewOrderedSetOf makes new typed ordered set with keys compare function
+//  and given options. If cmp is nil, comparator of options is used
+//
+func NewOrderedSetOf[K, V any](
+	cmp func(a, b K) int, opts *GtsOpts) *GtsOf[K, V] {
+
+	if opts == nil {
+		opts = NewGtsOpts()
+	}
+	o := *opts
+	if cmp != nil {
+		o.cmp = GtsKeysComparatorOf(cmp)
+		o.cmpID = ""
+	}
+
+	return GtsAs[K, V](NewOrderedSetOpts(&o))
+}
+
+//
+// GtsAs makes typed wrapper of the table
+//
+func GtsAs[K, V any](tab Gts) *GtsOf[K, V] {
+	return &GtsOf[K, V]{tab}
+}
+
+//
+// GtsKeysComparatorOf makes keys comparator from typed compare function
+//
+func GtsKeysComparatorOf[K any](cmp func(a, b K) int) GtsKeysComparator {
+	return func(a, b interface{}) int {
+		return cmp(a.(K), b.(K))
+	}
+}
+
+//
+// Gts returns underlying untyped table
+//
+func (t *GtsOf[K, V]) Gts() Gts {
+	return t.tab
+}
+
+//
+// Insert inserts object
+//
+func (t *GtsOf[K, V]) Insert(key K, value V) {
+	t.tab.Insert(key, value)
+}
+
+//
+// InsertNew inserts object if the key does not exist
+//
+func (t *GtsOf[K, V]) InsertNew(key K, value V) bool {
+	return t.tab.InsertNew(key, value)
+}
+
+//
+// Lookup returns value of the object
+//
+func (t *GtsOf[K, V]) Lookup(key K) (V, bool) {
+	v, ok := t.tab.Lookup(key).(V)
+	return v, ok
+}
+
+//
+// Delete deletes object
+//
+func (t *GtsOf[K, V]) Delete(key K) {
+	t.tab.Delete(key)
+}
+
+//
+// Take deletes object and returns its value
+//
+func (t *GtsOf[K, V]) Take(key K) (V, bool) {
+	v, found := t.tab.Take(key)
+	value, ok := v.(V)
+	return value, found && ok
+}
+
+//
+// UpdateElement replaces value of the object with the value returned by f
+//
+func (t *GtsOf[K, V]) UpdateElement(key K, f func(value V) V) bool {
+	return t.tab.UpdateElement(key, func(v Term) Term {
+		value, ok := v.(V)
+		if !ok {
+			return v
+		}
+		return f(value)
+	})
+}
+
+//
+// Size returns count of objects in the table
+//
+func (t *GtsOf[K, V]) Size() int {
+	return t.tab.Size()
+}
+
+//
+// Close releases table resources
+//
+func (t *GtsOf[K, V]) Close() {
+	t.tab.Close()
+}
+
+//
+// ForEach calls f for all objects until f returns false. Unlike
+//  Gts.ForEach does not delete objects
+//
+func (t *GtsOf[K, V]) ForEach(f func(key K, value V) bool) {
+	objs, _ := t.tab.Select(nil, 0)
+	for _, o := range objs {
+		k, kOk := o.Key.(K)
+		v, vOk := o.Value.(V)
+		if kOk && vOk && !f(k, v) {
+			return
+		}
+	}
+}
+
+//
+// Range calls f for objects with keys from 'from' to 'to' inclusive in keys
+//  order until f returns false
+//
+func (t *GtsOf[K, V]) Range(from, to K, f func(key K, value V) bool) {
+	t.tab.Range(from, to, func(k, v interface{}) bool {
+		key, kOk := k.(K)
+		value, vOk := v.(V)
+		if !kOk || !vOk {
+			return true
+		}
+		return f(key, value)
+	})
+}
+
+//
+// Select returns objects for which f returns true, at most limit objects
+//  if limit > 0
+//
+func (t *GtsOf[K, V]) Select(f func(key K, value V) bool, limit int) []V {
+
+	ms := NewGtsMatchSpec().
+		WithGuard(func(k, v Term) bool {
+			key, kOk := k.(K)
+			value, vOk := v.(V)
+			return kOk && vOk && f(key, value)
+		})
+
+	objs, _ := t.tab.Select(ms, limit)
+
+	values := make([]V, len(objs))
+	for i, o := range objs {
+		values[i] = o.Value.(V)
+	}
+
+	return values
+}
@@ -0,0 +1,79 @@
+package stdlib
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGtsTyped(t *testing.T) {
+
+	tab := NewSetOf[string, int](nil)
+	defer tab.Close()
+
+	tab.Insert("a", 1)
+	tab.Insert("b", 2)
+	if !tab.InsertNew("c", 3) || tab.InsertNew("c", 30) {
+		t.Fatal("InsertNew must insert only new keys")
+	}
+
+	if v, ok := tab.Lookup("b"); !ok || v != 2 {
+		t.Fatalf("expected 2, actual %d, %v", v, ok)
+	}
+	if _, ok := tab.Lookup("z"); ok {
+		t.Fatal("expected not found")
+	}
+
+	// objects of other types are ignored
+	tab.Gts().Insert("bad", "value")
+	if _, ok := tab.Lookup("bad"); ok {
+		t.Fatal("expected not found value of other type")
+	}
+	if vs := tab.Select(func(k string, v int) bool { return true }, 0); len(vs) != 3 {
+		t.Fatalf("expected 3 values, actual %v", vs)
+	}
+	tab.Delete("bad")
+
+	if !tab.UpdateElement("a", func(v int) int { return v + 10 }) {
+		t.Fatal("expected update")
+	}
+	if v, _ := tab.Lookup("a"); v != 11 {
+		t.Fatalf("expected 11, actual %d", v)
+	}
+
+	if v, ok := tab.Take("c"); !ok || v != 3 || tab.Size() != 2 {
+		t.Fatalf("expected take 3, actual %d, %v, size %d", v, ok, tab.Size())
+	}
+
+	sum := 0
+	tab.ForEach(func(k string, v int) bool {
+		sum += v
+		return true
+	})
+	if sum != 13 {
+		t.Fatalf("expected sum 13, actual %d", sum)
+	}
+}
+
+func TestGtsTypedOrdered(t *testing.T) {
+
+	tab := NewOrderedSetOf[string, int](strings.Compare, nil)
+	defer tab.Close()
+
+	for i, k := range []string{"d", "a", "c", "b", "e"} {
+		tab.Insert(k, i)
+	}
+
+	var keys []string
+	tab.Range("b", "d", func(k string, v int) bool {
+		keys = append(keys, k)
+		return true
+	})
+	if strings.Join(keys, "") != "bcd" {
+		t.Fatalf("expected keys bcd, actual %v", keys)
+	}
+
+	vs := tab.Select(func(k string, v int) bool { return v%2 == 0 }, 2)
+	if len(vs) != 2 || vs[0] != 2 || vs[1] != 0 {
+		t.Fatalf("expected values [2 0], actual %v", vs)
+	}
+}
@@ -0,0 +1,136 @@
+package stdlib
+
+//
+// Update operations common for all table types
+//
+
+import "math"
+
+//
+// gtsStore is implemented by all table types
+//
+type gtsStore interface {
+	lookup(key Term) (Term, bool)
+	Insert(key Term, value Term)
+	Delete(key Term)
+}
+
+func gtsUpdateCounter(
+	tab gtsStore, key Term, incr, threshold, setValue int64) (int64, error) {
+
+	var value Term = int64(0)
+	if v, ok := tab.lookup(key); ok {
+		value = v
+	}
+
+	counter, ok := termToInt64(value)
+	if !ok {
+		return 0, BadArgError
+	}
+
+	if (incr > 0 && counter > math.MaxInt64-incr) ||
+		(incr < 0 && counter < math.MinInt64-incr) {
+		return 0, BadArgError
+	}
+
+	counter += incr
+	if (incr >= 0 && counter > threshold) || (incr < 0 && counter < threshold) {
+		counter = setValue
+	}
+
+	term, ok := int64ToTerm(counter, value)
+	if !ok {
+		return 0, BadArgError
+	}
+
+	tab.Insert(key, term)
+
+	return counter, nil
+}
+
+func gtsUpdateElement(tab gtsStore, key Term, f GtsUpdateFunc) bool {
+
+	value, ok := tab.lookup(key)
+	if !ok {
+		return false
+	}
+
+	tab.Insert(key, f(value))
+
+	return true
+}
+
+func gtsInsertNew(tab gtsStore, key Term, value Term) bool {
+
+	if _, ok := tab.lookup(key); ok {
+		return false
+	}
+
+	tab.Insert(key, value)
+
+	return true
+}
+
+func gtsTake(tab gtsStore, key Term) (Term, bool) {
+
+	value, ok := tab.lookup(key)
+	if ok {
+		tab.Delete(key)
+	}
+
+	return value, ok
+}
+
+//
+// Counter value keeps type of the original value. Values out of int64 range
+//  and out of range of the type are not counters
+//
+func termToInt64(t Term) (int64, bool) {
+	switch t := t.(type) {
+	case int:
+		return int64(t), true
+	case int8:
+		return int64(t), true
+	case int16:
+		return int64(t), true
+	case int32:
+		return int64(t), true
+	case int64:
+		return t, true
+	case uint:
+		return int64(t), uint64(t) <= math.MaxInt64
+	case uint8:
+		return int64(t), true
+	case uint16:
+		return int64(t), true
+	case uint32:
+		return int64(t), true
+	case uint64:
+		return int64(t), t <= math.MaxInt64
+	}
+	return 0, false
+}
+
+func int64ToTerm(v int64, sample Term) (Term, bool) {
+	switch sample.(type) {
+	case int:
+		return int(v), int64(int(v)) == v
+	case int8:
+		return int8(v), int64(int8(v)) == v
+	case int16:
+		return int16(v), int64(int16(v)) == v
+	case int32:
+		return int32(v), int64(int32(v)) == v
+	case uint:
+		return uint(v), v >= 0 && int64(uint(v)) == v
+	case uint8:
+		return uint8(v), int64(uint8(v)) == v
+	case uint16:
+		return uint16(v), int64(uint16(v)) == v
+	case uint32:
+		return uint32(v), int64(uint32(v)) == v
+	case uint64:
+		return uint64(v), v >= 0
+	}
+	return v, true
+}
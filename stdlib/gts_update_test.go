@@ -0,0 +1,182 @@
+package stdlib
+
+import (
+	"math"
+	"sync"
+	"testing"
+)
+
+type gtsTestRecord struct {
+	name  string
+	count int
+}
+
+func TestGtsUpdateCounter(t *testing.T) {
+
+	tabs := map[string]Gts{
+		"set":         NewSet(),
+		"ordered_set": NewOrderedSet(),
+		"locked":      NewSetOpts(NewGtsOpts().WithLock()),
+	}
+
+	for name, tab := range tabs {
+		t.Run(name, func(t *testing.T) {
+
+			// default insert
+			n, err := tab.UpdateCounter(1, 5, math.MaxInt64, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != 5 || tab.Lookup(1) != int64(5) {
+				t.Fatalf("expected counter 5, actual %d, %v", n, tab.Lookup(1))
+			}
+
+			// keeps type of the value
+			tab.Insert(2, 10)
+			if n, _ = tab.UpdateCounter(2, -3, math.MinInt64, 0); n != 7 {
+				t.Fatalf("expected counter 7, actual %d", n)
+			}
+			if tab.Lookup(2) != 7 {
+				t.Fatalf("expected int value 7, actual %#v", tab.Lookup(2))
+			}
+
+			// threshold
+			if n, _ = tab.UpdateCounter(2, 5, 10, 1); n != 1 {
+				t.Fatalf("expected counter 1, actual %d", n)
+			}
+			if n, _ = tab.UpdateCounter(2, -5, 0, 100); n != 100 {
+				t.Fatalf("expected counter 100, actual %d", n)
+			}
+
+			tab.Insert(3, "value")
+			if _, err = tab.UpdateCounter(3, 1, math.MaxInt64, 0); !IsBadArgError(err) {
+				t.Fatalf("expected '%s' error, actual '%v'", BadArgError, err)
+			}
+
+			// overflow of the type of the value
+			overflows := []struct {
+				value Term
+				incr  int64
+			}{
+				{int8(math.MaxInt8), 1},
+				{uint8(0), -1},
+				{uint16(math.MaxUint16), 1},
+				{int64(math.MaxInt64), 1},
+				{int64(math.MinInt64), -1},
+				{uint64(math.MaxUint64), 0},
+			}
+			for _, o := range overflows {
+				tab.Insert(4, o.value)
+				threshold := int64(math.MaxInt64)
+				if o.incr < 0 {
+					threshold = math.MinInt64
+				}
+				_, err = tab.UpdateCounter(4, o.incr, threshold, 0)
+				if !IsBadArgError(err) {
+					t.Fatalf("%#v %+d: expected '%s' error, actual '%v'",
+						o.value, o.incr, BadArgError, err)
+				}
+				if tab.Lookup(4) != o.value {
+					t.Fatalf("expected value %#v, actual %#v",
+						o.value, tab.Lookup(4))
+				}
+			}
+
+			// value after threshold fits the type
+			tab.Insert(4, int8(math.MaxInt8))
+			if n, err = tab.UpdateCounter(4, 1, math.MaxInt8, 0); err != nil || n != 0 {
+				t.Fatalf("expected counter 0, actual %d, %v", n, err)
+			}
+			if tab.Lookup(4) != int8(0) {
+				t.Fatalf("expected int8 value 0, actual %#v", tab.Lookup(4))
+			}
+		})
+	}
+}
+
+func TestGtsUpdateElement(t *testing.T) {
+
+	tab := NewOrderedSet()
+	tab.Insert(1, &gtsTestRecord{"one", 0})
+
+	inc := func(v Term) Term {
+		r := *v.(*gtsTestRecord)
+		r.count++
+		return &r
+	}
+
+	if !tab.UpdateElement(1, inc) {
+		t.Fatal("expected element updated")
+	}
+	if r := tab.Lookup(1).(*gtsTestRecord); r.count != 1 || r.name != "one" {
+		t.Fatalf("expected record {one 1}, actual %v", r)
+	}
+	if tab.UpdateElement(2, inc) {
+		t.Fatal("expected element 2 does not exist")
+	}
+	if tab.Size() != 1 {
+		t.Fatalf("expected tab size 1, actual %d", tab.Size())
+	}
+}
+
+func TestGtsInsertNewTake(t *testing.T) {
+
+	tab := NewSet()
+
+	if !tab.InsertNew(1, "a") {
+		t.Fatal("expected key 1 inserted")
+	}
+	if tab.InsertNew(1, "b") {
+		t.Fatal("expected key 1 exists")
+	}
+	if tab.Lookup(1) != "a" {
+		t.Fatalf("expected value 'a', actual %v", tab.Lookup(1))
+	}
+
+	v, ok := tab.Take(1)
+	if !ok || v != "a" {
+		t.Fatalf("expected value 'a', actual %v, %v", v, ok)
+	}
+	if _, ok = tab.Take(1); ok {
+		t.Fatal("expected key 1 deleted")
+	}
+}
+
+func TestGtsLockedConcurrentUpdates(t *testing.T) {
+
+	tab := NewOrderedSetOpts(NewGtsOpts().WithLock())
+
+	const (
+		workers = 8
+		incs    = 1000
+	)
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		created int
+	)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < incs; j++ {
+				_, _ = tab.UpdateCounter(1, 1, math.MaxInt64, 0)
+				if tab.InsertNew(j+2, j) {
+					mu.Lock()
+					created++
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if v := tab.Lookup(1); v != int64(workers*incs) {
+		t.Fatalf("expected counter %d, actual %v", workers*incs, v)
+	}
+	if created != incs {
+		t.Fatalf("expected %d inserted keys, actual %d", incs, created)
+	}
+}
@@ -0,0 +1,376 @@
+package stdlib
+
+//
+// Key registry. Local, decentralized registry of processes like Registry of
+// Elixir. Each registration maps the key to the process and the value. Keys
+// are unique or duplicate. Registrations are removed when the process exits.
+//
+// Registry has its own lock, so lookups do not touch registry of the Env.
+// Registry spawns process in the Env to monitor registered processes
+//
+
+import (
+	"sync"
+)
+
+//
+// RegistryKeys is a kind of keys of the registry
+//
+type RegistryKeys int
+
+//
+// Kinds of registry keys
+//
+const (
+	// RegistryUnique keys are registered by one process
+	RegistryUnique RegistryKeys = iota
+	// RegistryDuplicate keys are registered by many processes, process can
+	//  register the same key many times
+	RegistryDuplicate
+)
+
+//
+// RegistryEntry is a registration of the process
+//
+type RegistryEntry struct {
+	Pid   *Pid
+	Value Term
+}
+
+//
+// KeyRegistry is a registry of processes by keys with values
+//
+type KeyRegistry struct {
+	mu   sync.RWMutex
+	keys RegistryKeys
+	env  *Env
+	// process monitoring registered processes
+	pid *Pid
+
+	entries map[Term][]*RegistryEntry
+	owners  map[*Pid]*keyRegistryOwner
+	byRef   map[Ref]*Pid
+}
+
+//
+// NewKeyRegistry makes registry in default environment
+//
+func NewKeyRegistry(keys RegistryKeys) (*KeyRegistry, error) {
+	return env.NewKeyRegistry(keys)
+}
+
+//
+// NewKeyRegistry makes registry in specified environment
+//
+func (e *Env) NewKeyRegistry(keys RegistryKeys) (*KeyRegistry, error) {
+
+	kr := &KeyRegistry{
+		keys:    keys,
+		env:     e,
+		entries: make(map[Term][]*RegistryEntry),
+		owners:  make(map[*Pid]*keyRegistryOwner),
+		byRef:   make(map[Ref]*Pid),
+	}
+
+	pid, err := e.GenServerStart(&keyRegistryGs{kr: kr})
+	if err != nil {
+		return nil, err
+	}
+	kr.pid = pid
+
+	return kr, nil
+}
+
+//
+// Register registers process under the key with value. Returns
+//  AlreadyRegError if unique key is registered
+//
+func (kr *KeyRegistry) Register(pid *Pid, key, value Term) error {
+
+	if err := pid.Alive(); err != nil {
+		return err
+	}
+	if pid.env != kr.env {
+		return BadArgError
+	}
+
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+
+	if kr.pid == nil {
+		return NoProcError
+	}
+
+	if kr.keys == RegistryUnique {
+		if _, ok := kr.entries[key]; ok {
+			return AlreadyRegError
+		}
+	}
+
+	kr.entries[key] = append(kr.entries[key], &RegistryEntry{pid, value})
+
+	owner, ok := kr.owners[pid]
+	if !ok {
+		owner = &keyRegistryOwner{
+			ref:  kr.env.MakeRef(),
+			keys: make(map[Term]int),
+		}
+		pid.monitorMe(kr.pid, owner.ref)
+		kr.pid.monitorByMe(pid, owner.ref)
+
+		kr.owners[pid] = owner
+		kr.byRef[owner.ref] = pid
+	}
+	owner.keys[key]++
+
+	return nil
+}
+
+//
+// Unregister removes all registrations of the process under the key
+//
+func (kr *KeyRegistry) Unregister(pid *Pid, key Term) error {
+
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+
+	owner, ok := kr.owners[pid]
+	if !ok || owner.keys[key] == 0 {
+		return NotRegError
+	}
+
+	kr.removeEntries(pid, key)
+
+	delete(owner.keys, key)
+	if len(owner.keys) == 0 {
+		kr.demonitor(pid, owner)
+	}
+
+	return nil
+}
+
+//
+// Lookup returns registrations under the key
+//
+func (kr *KeyRegistry) Lookup(key Term) []RegistryEntry {
+	return kr.Match(key, nil)
+}
+
+//
+// Match returns registrations under the key with values accepted by f.
+//  Nil f accepts all values
+//
+func (kr *KeyRegistry) Match(
+	key Term, f func(value Term) bool) []RegistryEntry {
+
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+
+	var entries []RegistryEntry
+	for _, entry := range kr.entries[key] {
+		// process could exit before monitor was set
+		if entry.Pid.Alive() != nil {
+			continue
+		}
+		if f == nil || f(entry.Value) {
+			entries = append(entries, *entry)
+		}
+	}
+
+	return entries
+}
+
+//
+// UpdateValue updates value of unique key registered by the process.
+//  Returns new value
+//
+func (kr *KeyRegistry) UpdateValue(
+	pid *Pid, key Term, f func(value Term) Term) (Term, error) {
+
+	if kr.keys != RegistryUnique {
+		return nil, BadArgError
+	}
+
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+
+	entries := kr.entries[key]
+	if len(entries) == 0 || entries[0].Pid != pid {
+		return nil, NotRegError
+	}
+
+	entries[0].Value = f(entries[0].Value)
+
+	return entries[0].Value, nil
+}
+
+//
+// Keys returns keys registered by the process
+//
+func (kr *KeyRegistry) Keys(pid *Pid) []Term {
+
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+
+	owner, ok := kr.owners[pid]
+	if !ok {
+		return nil
+	}
+
+	keys := make([]Term, 0, len(owner.keys))
+	for key := range owner.keys {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+//
+// Count returns count of registrations
+//
+func (kr *KeyRegistry) Count() int {
+
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+
+	n := 0
+	for _, entries := range kr.entries {
+		n += len(entries)
+	}
+
+	return n
+}
+
+//
+// Close removes all registrations and stops the registry process
+//
+func (kr *KeyRegistry) Close() error {
+
+	kr.mu.Lock()
+
+	pid := kr.pid
+	if pid == nil {
+		kr.mu.Unlock()
+		return nil
+	}
+
+	for owner, o := range kr.owners {
+		kr.demonitor(owner, o)
+	}
+	kr.entries = make(map[Term][]*RegistryEntry)
+	kr.pid = nil
+
+	kr.mu.Unlock()
+
+	return pid.Stop()
+}
+
+//
+// RegisterName implements Registry for unique keys registry
+//
+func (kr *KeyRegistry) RegisterName(name Term, pid *Pid) error {
+	if kr.keys != RegistryUnique {
+		return BadArgError
+	}
+	return kr.Register(pid, name, nil)
+}
+
+//
+// UnregisterName implements Registry for unique keys registry
+//
+func (kr *KeyRegistry) UnregisterName(name Term) error {
+
+	entries := kr.Lookup(name)
+	if len(entries) == 0 {
+		return NotRegError
+	}
+
+	return kr.Unregister(entries[0].Pid, name)
+}
+
+//
+// WhereisName implements Registry for unique keys registry
+//
+func (kr *KeyRegistry) WhereisName(name Term) (*Pid, error) {
+
+	entries := kr.Lookup(name)
+	if len(entries) == 0 {
+		return nil, NotRegError
+	}
+
+	return entries[0].Pid, nil
+}
+
+// ---------------------------------------------------------------------------
+// Locals
+// ---------------------------------------------------------------------------
+
+type keyRegistryOwner struct {
+	ref Ref
+	// key -> count of registrations
+	keys map[Term]int
+}
+
+//
+// keyRegistryGs is a process of the registry. Processes are monitored by it
+//
+type keyRegistryGs struct {
+	GenServerSys
+
+	kr *KeyRegistry
+}
+
+func (gs *keyRegistryGs) Init(args ...Term) Term {
+
+	gs.Self().RegisterMonitorDownFunc(gs.kr.down)
+
+	return gs.InitOk()
+}
+
+//
+// down removes registrations of exited process, it is called in goroutine of
+//  the exited process
+//
+func (kr *KeyRegistry) down(ref Ref, reason string) {
+
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+
+	pid, ok := kr.byRef[ref]
+	if !ok {
+		return
+	}
+
+	for key := range kr.owners[pid].keys {
+		kr.removeEntries(pid, key)
+	}
+
+	delete(kr.owners, pid)
+	delete(kr.byRef, ref)
+}
+
+func (kr *KeyRegistry) removeEntries(pid *Pid, key Term) {
+
+	entries := kr.entries[key][:0]
+	for _, entry := range kr.entries[key] {
+		if entry.Pid != pid {
+			entries = append(entries, entry)
+		}
+	}
+
+	if len(entries) == 0 {
+		delete(kr.entries, key)
+	} else {
+		kr.entries[key] = entries
+	}
+}
+
+func (kr *KeyRegistry) demonitor(pid *Pid, owner *keyRegistryOwner) {
+
+	if kr.pid.demonitorByMe(false, owner.ref, "") != nil {
+		pid.demonitorMe(owner.ref)
+	}
+
+	delete(kr.owners, pid)
+	delete(kr.byRef, owner.ref)
+}
@@ -0,0 +1,144 @@
+package stdlib
+
+import (
+	"testing"
+	"time"
+)
+
+func TestKeyRegistryUnique(t *testing.T) {
+
+	e := NewEnv()
+
+	kr, err := e.NewKeyRegistry(RegistryUnique)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer kr.Close()
+
+	a, err := e.GenServerStart(new(ts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Stop()
+	b, err := e.GenServerStart(new(ts))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = kr.Register(a, "key", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err = kr.Register(b, "key", 2); !IsAlreadyRegError(err) {
+		t.Fatalf("expected AlreadyRegError, actual %v", err)
+	}
+	if err = kr.Register(b, "other", 2); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := kr.Lookup("key")
+	if len(entries) != 1 || entries[0].Pid != a || entries[0].Value != 1 {
+		t.Fatalf("unexpected entries %v", entries)
+	}
+
+	inc := func(v Term) Term { return v.(int) + 1 }
+	if _, err = kr.UpdateValue(b, "key", inc); !IsNotRegError(err) {
+		t.Fatalf("expected NotRegError, actual %v", err)
+	}
+	v, err := kr.UpdateValue(a, "key", inc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != 2 || kr.Lookup("key")[0].Value != 2 {
+		t.Fatalf("expected value 2, actual %v", v)
+	}
+
+	if err = kr.Unregister(a, "key"); err != nil {
+		t.Fatal(err)
+	}
+	if err = kr.Unregister(a, "key"); !IsNotRegError(err) {
+		t.Fatalf("expected NotRegError, actual %v", err)
+	}
+	if n := kr.Count(); n != 1 {
+		t.Fatalf("expected 1 registration, actual %d", n)
+	}
+
+	// cleanup on exit
+	if err = b.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100 && kr.Count() != 0; i++ {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if n := kr.Count(); n != 0 {
+		t.Fatalf("expected no registrations, actual %d", n)
+	}
+	if keys := kr.Keys(b); len(keys) != 0 {
+		t.Fatalf("expected no keys, actual %v", keys)
+	}
+}
+
+func TestKeyRegistryDuplicate(t *testing.T) {
+
+	e := NewEnv()
+
+	kr, err := e.NewKeyRegistry(RegistryDuplicate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pids := make([]*Pid, 3)
+	for i := range pids {
+		if pids[i], err = e.GenServerStart(new(ts)); err != nil {
+			t.Fatal(err)
+		}
+		defer pids[i].Stop()
+
+		if err = kr.Register(pids[i], "topic", i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = kr.Register(pids[0], "topic", 10); err != nil {
+		t.Fatal(err)
+	}
+
+	if entries := kr.Lookup("topic"); len(entries) != 4 {
+		t.Fatalf("expected 4 entries, actual %v", entries)
+	}
+
+	entries := kr.Match("topic", func(v Term) bool { return v.(int) >= 2 })
+	if len(entries) != 2 || entries[0].Pid != pids[2] ||
+		entries[1].Pid != pids[0] {
+		t.Fatalf("unexpected entries %v", entries)
+	}
+
+	if _, err = kr.UpdateValue(pids[0], "topic", nil); !IsBadArgError(err) {
+		t.Fatalf("expected BadArgError, actual %v", err)
+	}
+
+	if err = kr.Unregister(pids[0], "topic"); err != nil {
+		t.Fatal(err)
+	}
+	if n := kr.Count(); n != 2 {
+		t.Fatalf("expected 2 registrations, actual %d", n)
+	}
+
+	other := NewEnv()
+	pid, err := other.GenServerStart(new(ts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pid.Stop()
+	if err = kr.Register(pid, "topic", 0); !IsBadArgError(err) {
+		t.Fatalf("expected BadArgError, actual %v", err)
+	}
+
+	if err = kr.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n := kr.Count(); n != 0 {
+		t.Fatalf("expected no registrations, actual %d", n)
+	}
+	if err = kr.Register(pids[1], "topic", 0); !IsNoProcError(err) {
+		t.Fatalf("expected NoProcError, actual %v", err)
+	}
+}
@@ -44,3 +44,18 @@ func (pid *Pid) ProcessLinks() ([]*Pid, error) {
 	_, err := pid.CallSys(r)
 	return r.Links, err
 }
+
+//
+// Trace
+//
+func (pid *Pid) traceLink(to *Pid) {
+	if t := pid.tracerFor(TraceFlagLinks); t != nil {
+		t.Event(&LinkEvent{pid.traceEvent(), to})
+	}
+}
+
+func (pid *Pid) traceUnlink(to *Pid) {
+	if t := pid.tracerFor(TraceFlagLinks); t != nil {
+		t.Event(&UnlinkEvent{pid.traceEvent(), to})
+	}
+}
@@ -0,0 +1,267 @@
+package stdlib
+
+//
+// Names of processes. Name is resolved to the process when the message is
+// sent, so callers address processes by name without Whereis. Processes are
+// named by local name, by prefix and name, or by name in pluggable registry.
+// *Pid is a Name too
+//
+
+//
+// Registry is the interface of pluggable registry of process names
+//
+type Registry interface {
+	// RegisterName associates the name with process
+	RegisterName(name Term, pid *Pid) error
+	// UnregisterName removes the name
+	UnregisterName(name Term) error
+	// WhereisName returns process registered with the name
+	WhereisName(name Term) (*Pid, error)
+}
+
+//
+// Name is a name of the process
+//
+type Name interface {
+	whereis(e *Env) (*Pid, error)
+}
+
+//
+// LocalName makes name registered with Register
+//
+func LocalName(name Term) Name {
+	return &localName{name}
+}
+
+//
+// PrefixName makes name registered with RegisterPrefix
+//
+func PrefixName(prefix string, name Term) Name {
+	return &prefixName{prefix, name}
+}
+
+//
+// ViaName makes name registered in the registry
+//
+func ViaName(r Registry, name Term) Name {
+	return &viaName{r, name}
+}
+
+//
+// RemoteName makes name registered with Register on other node
+//
+func RemoteName(node string, name Term) Name {
+	return &remoteName{node, "", name}
+}
+
+//
+// RemotePrefixName makes name registered with RegisterPrefix on other node
+//
+func RemotePrefixName(node, prefix string, name Term) Name {
+	return &remoteName{node, prefix, name}
+}
+
+//
+// ErlName makes name registered on Erlang node connected with
+//  Node.ErlConnect
+//
+func ErlName(node, name string) Name {
+	return &erlName{node, Atom(name)}
+}
+
+//
+// WhereisName resolves the name in default environment
+//
+func WhereisName(name Name) (*Pid, error) {
+	return env.WhereisName(name)
+}
+
+//
+// WhereisName resolves the name in specified environment
+//
+func (e *Env) WhereisName(name Name) (*Pid, error) {
+	if name == nil {
+		return nil, NameEmptyError
+	}
+	return name.whereis(e)
+}
+
+//
+// SendName sends raw message to the named process in default environment
+//
+func SendName(to Name, msg Term) error {
+	return env.SendName(to, msg)
+}
+
+//
+// SendName sends raw message to the named process in specified environment
+//
+func (e *Env) SendName(to Name, msg Term) error {
+	pid, err := e.WhereisName(to)
+	if err != nil {
+		return err
+	}
+	return pid.Send(msg)
+}
+
+//
+// CastName sends async message to the named process in default environment
+//
+func CastName(to Name, msg Term) error {
+	return env.CastName(to, msg)
+}
+
+//
+// CastName sends async message to the named process in specified environment
+//
+func (e *Env) CastName(to Name, msg Term) error {
+	pid, err := e.WhereisName(to)
+	if err != nil {
+		return err
+	}
+	return pid.Cast(msg)
+}
+
+//
+// CallName sends sync message to the named process in default environment
+//
+func CallName(to Name, req Term) (Term, error) {
+	return env.CallName(to, req)
+}
+
+//
+// CallName sends sync message to the named process in specified environment
+//
+func (e *Env) CallName(to Name, req Term) (Term, error) {
+	pid, err := e.WhereisName(to)
+	if err != nil {
+		return nil, err
+	}
+	return pid.Call(req)
+}
+
+//
+// StopReasonName stops the named process in default environment
+//
+func StopReasonName(to Name, reason string) error {
+	return env.StopReasonName(to, reason)
+}
+
+//
+// StopReasonName stops the named process in specified environment
+//
+func (e *Env) StopReasonName(to Name, reason string) error {
+	pid, err := e.WhereisName(to)
+	if err != nil {
+		return err
+	}
+	return pid.StopReason(reason)
+}
+
+//
+// MonitorName sets monitor for the named process
+//
+func (gps *GenProcSys) MonitorName(name Name) (Ref, error) {
+	pid, err := gps.pid.env.WhereisName(name)
+	if err != nil {
+		return Ref{}, err
+	}
+	return gps.MonitorProcessPid(pid), nil
+}
+
+//
+// WithRegName sets name the process is registered with at spawn. Only local,
+//  prefix and via names can be registered, spawn with other names returns
+//  BadArgError
+//
+func (op *SpawnOpts) WithRegName(name Name) *SpawnOpts {
+
+	op.regNameErr = nil
+
+	switch name := name.(type) {
+	case *localName:
+		op.Prefix, op.Name, op.via = "", name.name, nil
+	case *prefixName:
+		op.Prefix, op.Name, op.via = name.prefix, name.name, nil
+	case *viaName:
+		op.Prefix, op.Name, op.via = "", nil, name
+	default:
+		op.regNameErr = BadArgError
+	}
+
+	return op
+}
+
+// ---------------------------------------------------------------------------
+// Locals
+// ---------------------------------------------------------------------------
+
+type localName struct {
+	name Term
+}
+
+type prefixName struct {
+	prefix string
+	name   Term
+}
+
+type viaName struct {
+	r    Registry
+	name Term
+}
+
+type remoteName struct {
+	node   string
+	prefix string
+	name   Term
+}
+
+type erlName struct {
+	node string
+	name Atom
+}
+
+func (pid *Pid) whereis(e *Env) (*Pid, error) {
+	if pid == nil {
+		return nil, NilPidError
+	}
+	return pid, nil
+}
+
+func (n *localName) whereis(e *Env) (*Pid, error) {
+	return e.whereis(n.name)
+}
+
+func (n *prefixName) whereis(e *Env) (*Pid, error) {
+	return e.whereisPrefix(n.prefix, n.name)
+}
+
+func (n *viaName) whereis(e *Env) (*Pid, error) {
+	return n.r.WhereisName(n.name)
+}
+
+func (n *remoteName) whereis(e *Env) (*Pid, error) {
+	node := e.Node()
+	if node == nil {
+		return nil, NoConnectionError
+	}
+	c := node.conn(n.node)
+	if c == nil {
+		return nil, NoConnectionError
+	}
+	return c.whereis(n.prefix, n.name)
+}
+
+func (n *erlName) whereis(e *Env) (*Pid, error) {
+	node := e.Node()
+	if node == nil || node.erlConn(n.node) == nil {
+		return nil, NoConnectionError
+	}
+	return &Pid{
+		remote: &remotePid{node: n.node, via: node, erl: &erlProc{name: n.name}},
+	}, nil
+}
+
+func (n *viaName) register(pid *Pid) error {
+	return n.r.RegisterName(n.name, pid)
+}
@@ -0,0 +1,155 @@
+package stdlib
+
+import (
+	"testing"
+)
+
+func TestName(t *testing.T) {
+
+	e := NewEnv()
+
+	kr, err := e.NewKeyRegistry(RegistryUnique)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer kr.Close()
+
+	names := []Name{
+		LocalName("srv"),
+		PrefixName("pfx", "srv"),
+		ViaName(kr, "srv"),
+	}
+
+	for _, name := range names {
+
+		if _, err = e.CallName(name, "ping"); !IsNotRegError(err) {
+			t.Fatalf("%#v: expected NotRegError, actual %v", name, err)
+		}
+
+		pid, err := e.GenServerStartOpts(
+			new(ts), NewSpawnOpts().WithRegName(name))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if found, err := e.WhereisName(name); err != nil || found != pid {
+			t.Fatalf("%#v: expected %s, actual %s, %v", name, pid, found, err)
+		}
+		if _, err = e.GenServerStartOpts(
+			new(ts), NewSpawnOpts().WithRegName(name)); !IsAlreadyRegError(err) {
+			t.Fatalf("%#v: expected AlreadyRegError, actual %v", name, err)
+		}
+
+		reply, err := e.CallName(name, "ping")
+		if err != nil || reply != "pong" {
+			t.Fatalf("%#v: expected pong, actual %v, %v", name, reply, err)
+		}
+		if err = e.CastName(name, "ping"); err != nil {
+			t.Fatal(err)
+		}
+		if err = e.SendName(name, "ping"); err != nil {
+			t.Fatal(err)
+		}
+
+		// monitor by name
+		out := make(chan Term, 1)
+		monitor, err := e.Spawn(testMonitorNameFunc, name, out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err, _ := (<-out).(error); err != nil {
+			t.Fatal(err)
+		}
+
+		if err = e.StopReasonName(name, ExitNormal); err != nil {
+			t.Fatal(err)
+		}
+		if reason := <-out; reason != ExitNormal {
+			t.Fatalf("%#v: expected down with %s, actual %v",
+				name, ExitNormal, reason)
+		}
+		_ = monitor.Stop()
+	}
+
+	// pid is a name too
+	pid, err := e.GenServerStart(new(ts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply, err := e.CallName(pid, "ping"); err != nil || reply != "pong" {
+		t.Fatalf("expected pong, actual %v, %v", reply, err)
+	}
+	if err = StopReasonName(pid, ExitNormal); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = e.WhereisName(nil); !IsNameEmptyError(err) {
+		t.Fatalf("expected NameEmptyError, actual %v", err)
+	}
+}
+
+func TestNameRegErrors(t *testing.T) {
+
+	e := NewEnv()
+
+	pid, err := e.GenServerStart(new(ts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pid.Stop()
+
+	// names which can not be registered
+	for _, name := range []Name{
+		pid,
+		RemoteName("other@host", "srv"),
+		ErlName("erl@host", "srv"),
+		nil,
+	} {
+		_, err = e.GenServerStartOpts(new(ts), NewSpawnOpts().WithRegName(name))
+		if !IsBadArgError(err) {
+			t.Fatalf("%#v: expected BadArgError, actual %v", name, err)
+		}
+	}
+
+	// failed via registration releases the pid and its local name
+	kr, err := e.NewKeyRegistry(RegistryUnique)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer kr.Close()
+
+	if err = kr.RegisterName("srv", pid); err != nil {
+		t.Fatal(err)
+	}
+	opts := NewSpawnOpts().WithRegName(ViaName(kr, "srv")).WithName("local")
+	if _, err = e.GenServerStartOpts(new(ts), opts); err == nil {
+		t.Fatal("expected error of via registration")
+	}
+	if _, err = e.Whereis("local"); !IsNotRegError(err) {
+		t.Fatalf("expected NotRegError, actual %v", err)
+	}
+}
+
+func testMonitorNameFunc(gp GenProc, args ...Term) error {
+
+	name := args[0].(Name)
+	out := args[1].(chan Term)
+
+	gp.Self().RegisterMonitorDownFunc(func(ref Ref, reason string) {
+		out <- reason
+	})
+
+	_, err := gp.(*GenProcSys).MonitorName(name)
+	out <- err
+	if err != nil {
+		return err
+	}
+
+	for m := range gp.Self().GetSysChannel() {
+		if err := gp.HandleSysMsg(m); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
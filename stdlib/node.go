@@ -0,0 +1,504 @@
+package stdlib
+
+//
+// Nodes. Environment started as a node listens TCP address and connects to
+// other nodes. Pids of processes of other nodes are used as local pids: Send,
+// Cast, Call, links and monitors are forwarded over the connection to the
+// node. When the connection is lost, linked processes get exit and monitors
+// get down with reason NoConnection.
+//
+// Nodes are authenticated by the challenge/response handshake with the cookie
+// shared by nodes, see NodeOpts.WithCookie. Connection is used only after
+// both nodes proved they know the cookie.
+//
+// Messages are encoded with the codec of the node, GobCodec by default. Types
+// other than basic types and stdlib types must be registered with
+// RegisterTerm on both nodes
+//
+
+import (
+	"net"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const nodeHandshakeTimeout = 5 * time.Second
+
+//
+// Node is the environment connected to other nodes
+//
+type Node struct {
+	// count of dropped messages, first to be aligned for atomic access
+	dropped uint64
+
+	name string
+	env  *Env
+	ln   net.Listener
+	// codec of messages
+	codec Codec
+	// cookie shared by connected nodes
+	cookie string
+	// process monitoring pids sent to other nodes
+	pid *Pid
+
+	mu       sync.RWMutex
+	conns    map[string]*nodeConn
+	erlConns map[string]*erlConn
+	stopped  bool
+
+	// id -> *Pid of local processes known to other nodes
+	exported sync.Map
+	// monitor ref -> id of exported processes
+	exportedRefs sync.Map
+}
+
+//
+// local nodes and names of all known nodes
+//
+var nodes = struct {
+	mu     sync.RWMutex
+	byName map[string]*Node
+	byEnv  map[uint32]*Node
+	names  []string
+	index  map[string]uint32
+}{
+	byName: make(map[string]*Node),
+	byEnv:  make(map[uint32]*Node),
+	index:  make(map[string]uint32),
+}
+
+//
+// StartNode starts default environment as the node with name, listening
+//  TCP address addr
+//
+func StartNode(name, addr string) (*Node, error) {
+	return env.StartNode(name, addr)
+}
+
+//
+// StartNode starts specified environment as the node with name, listening
+//  TCP address addr. Port 0 of addr selects free port, see Node.Addr.
+//  Name must be unique among connected nodes
+//
+func (e *Env) StartNode(name, addr string) (*Node, error) {
+	return e.StartNodeOpts(name, addr, nil)
+}
+
+//
+// StartNodeOpts starts default environment as the node with options
+//
+func StartNodeOpts(name, addr string, opts *NodeOpts) (*Node, error) {
+	return env.StartNodeOpts(name, addr, opts)
+}
+
+//
+// StartNodeOpts starts specified environment as the node with options.
+//  Connected nodes must use the same codec
+//
+func (e *Env) StartNodeOpts(
+	name, addr string, opts *NodeOpts) (*Node, error) {
+
+	if opts == nil {
+		opts = NewNodeOpts()
+	}
+	codec := opts.codec
+	if codec == nil {
+		codec = GobCodec()
+	}
+
+	if name == "" {
+		return nil, NameEmptyError
+	}
+
+	nodes.mu.Lock()
+	defer nodes.mu.Unlock()
+
+	if _, ok := nodes.byName[name]; ok {
+		return nil, AlreadyRegError
+	}
+	if _, ok := nodes.byEnv[e.uid]; ok {
+		return nil, AlreadyRegError
+	}
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	n := &Node{
+		name:     name,
+		env:      e,
+		ln:       ln,
+		codec:    codec,
+		cookie:   opts.cookie,
+		conns:    make(map[string]*nodeConn),
+		erlConns: make(map[string]*erlConn),
+	}
+
+	if n.pid, err = e.GenServerStart(&nodeGs{n: n}); err != nil {
+		_ = ln.Close()
+		return nil, err
+	}
+
+	nodes.byName[name] = n
+	nodes.byEnv[e.uid] = n
+	nodeIndexLocked(name)
+	e.node.Store(n)
+
+	go n.acceptLoop()
+
+	return n, nil
+}
+
+//
+// Node returns node of specified environment, nil if it is not started
+//
+func (e *Env) Node() *Node {
+	n, _ := e.node.Load().(*Node)
+	return n
+}
+
+//
+// Name returns name of the node
+//
+func (n *Node) Name() string {
+	return n.name
+}
+
+//
+// NewNodeOpts makes options object and returns object to manipulate
+//
+func NewNodeOpts() *NodeOpts {
+	return new(NodeOpts)
+}
+
+//
+// NodeOpts is the structure to hold values of the node options
+//
+type NodeOpts struct {
+	codec  Codec
+	cookie string
+}
+
+//
+// WithCodec sets codec of messages to other nodes. Default is GobCodec()
+//
+func (op *NodeOpts) WithCodec(codec Codec) *NodeOpts {
+
+	op.codec = codec
+
+	return op
+}
+
+//
+// WithCookie sets cookie shared by the nodes. Nodes with different cookies
+//  are not connected. Default is empty cookie, node without cookie should
+//  listen address reachable by trusted hosts only
+//
+func (op *NodeOpts) WithCookie(cookie string) *NodeOpts {
+
+	op.cookie = cookie
+
+	return op
+}
+
+//
+// Addr returns address the node listens
+//
+func (n *Node) Addr() string {
+	return n.ln.Addr().String()
+}
+
+//
+// Connect connects to the node listening addr, returns its name
+//
+func (n *Node) Connect(addr string) (string, error) {
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return "", err
+	}
+
+	_ = conn.SetDeadline(time.Now().Add(nodeHandshakeTimeout))
+
+	c := newNodeConn(n, conn)
+	peer, err := c.connectHandshake()
+	if err != nil {
+		_ = conn.Close()
+		return "", err
+	}
+
+	_ = conn.SetDeadline(time.Time{})
+
+	if err = n.addConn(peer, c); err != nil {
+		_ = conn.Close()
+		return "", err
+	}
+
+	return peer, nil
+}
+
+//
+// Disconnect closes connection to the node
+//
+func (n *Node) Disconnect(node string) error {
+	c := n.conn(node)
+	if c == nil {
+		return NotRegError
+	}
+	c.close()
+	return nil
+}
+
+//
+// Dropped returns count of messages from other nodes dropped because they
+//  could not be decoded, e.g. of types not registered with RegisterTerm
+//
+func (n *Node) Dropped() uint64 {
+	return atomic.LoadUint64(&n.dropped)
+}
+
+//
+// Nodes returns sorted names of connected nodes
+//
+func (n *Node) Nodes() []string {
+
+	n.mu.RLock()
+	names := make([]string, 0, len(n.conns))
+	for name := range n.conns {
+		names = append(names, name)
+	}
+	n.mu.RUnlock()
+
+	sort.Strings(names)
+
+	return names
+}
+
+//
+// Stop closes all connections and stops the node
+//
+func (n *Node) Stop() error {
+
+	n.mu.Lock()
+	if n.stopped {
+		n.mu.Unlock()
+		return nil
+	}
+	n.stopped = true
+	conns := make([]*nodeConn, 0, len(n.conns))
+	for _, c := range n.conns {
+		conns = append(conns, c)
+	}
+	erlConns := make([]*erlConn, 0, len(n.erlConns))
+	for _, c := range n.erlConns {
+		erlConns = append(erlConns, c)
+	}
+	n.mu.Unlock()
+
+	err := n.ln.Close()
+	for _, c := range conns {
+		c.close()
+	}
+	for _, c := range erlConns {
+		c.close()
+	}
+
+	nodes.mu.Lock()
+	delete(nodes.byName, n.name)
+	delete(nodes.byEnv, n.env.uid)
+	nodes.mu.Unlock()
+	n.env.node.Store((*Node)(nil))
+
+	_ = n.pid.Stop()
+
+	return err
+}
+
+// ---------------------------------------------------------------------------
+// Locals
+// ---------------------------------------------------------------------------
+
+func (n *Node) acceptLoop() {
+	for {
+		conn, err := n.ln.Accept()
+		if err != nil {
+			return
+		}
+		go n.accept(conn)
+	}
+}
+
+func (n *Node) accept(conn net.Conn) {
+
+	_ = conn.SetDeadline(time.Now().Add(nodeHandshakeTimeout))
+
+	c := newNodeConn(n, conn)
+
+	peer, err := c.acceptHandshake()
+	if err != nil {
+		_ = conn.Close()
+		return
+	}
+	_ = conn.SetDeadline(time.Time{})
+
+	frame, err := encodeNodeFrame(&nodeMsg{Op: nodeOpAck})
+	if err != nil {
+		_ = conn.Close()
+		return
+	}
+
+	// connection is added before ack, so connected node is known to both
+	//  nodes when Connect returns, and ack is the last frame of handshake
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+
+	if err = n.addConn(peer, c); err != nil {
+		_ = conn.Close()
+		return
+	}
+	if _, err = conn.Write(frame); err != nil {
+		_ = conn.Close()
+	}
+}
+
+func (n *Node) addConn(name string, c *nodeConn) error {
+
+	if name == n.name {
+		return BadArgError
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.stopped {
+		return NoProcError
+	}
+	if _, ok := n.conns[name]; ok {
+		return AlreadyRegError
+	}
+
+	c.peer = name
+	n.conns[name] = c
+
+	go c.readLoop()
+
+	return nil
+}
+
+func (n *Node) removeConn(c *nodeConn) {
+	n.mu.Lock()
+	if n.conns[c.peer] == c {
+		delete(n.conns, c.peer)
+	}
+	n.mu.Unlock()
+}
+
+func (n *Node) conn(name string) *nodeConn {
+	n.mu.RLock()
+	c := n.conns[name]
+	n.mu.RUnlock()
+	return c
+}
+
+//
+// export makes local process known to other nodes until it exits
+//
+func (n *Node) export(pid *Pid) {
+
+	if _, loaded := n.exported.LoadOrStore(pid.id, pid); loaded {
+		return
+	}
+
+	ref := n.env.makeRef()
+	n.exportedRefs.Store(ref, pid.id)
+	pid.monitorMe(n.pid, ref)
+}
+
+func (n *Node) exportedPid(id uint64) *Pid {
+	if pid, ok := n.exported.Load(id); ok {
+		return pid.(*Pid)
+	}
+	return nil
+}
+
+//
+// down forgets exported process, it is called in goroutine of the exited
+//  process
+//
+func (n *Node) down(ref Ref, reason string) {
+	if id, ok := n.exportedRefs.Load(ref); ok {
+		n.exportedRefs.Delete(ref)
+		n.exported.Delete(id)
+	}
+}
+
+//
+// nodeGs is a process of the node. Exported processes are monitored by it
+//
+type nodeGs struct {
+	GenServerSys
+
+	n *Node
+}
+
+func (gs *nodeGs) Init(args ...Term) Term {
+
+	gs.Self().RegisterMonitorDownFunc(gs.n.down)
+
+	return gs.InitOk()
+}
+
+//
+// Names of nodes are indexed to show them in pids and refs
+//
+func nodeIndex(name string) uint32 {
+
+	nodes.mu.RLock()
+	i, ok := nodes.index[name]
+	nodes.mu.RUnlock()
+	if ok {
+		return i
+	}
+
+	nodes.mu.Lock()
+	defer nodes.mu.Unlock()
+
+	return nodeIndexLocked(name)
+}
+
+func nodeIndexLocked(name string) uint32 {
+	if i, ok := nodes.index[name]; ok {
+		return i
+	}
+	nodes.names = append(nodes.names, name)
+	i := uint32(len(nodes.names))
+	nodes.index[name] = i
+	return i
+}
+
+func nodeIndexName(i uint32) string {
+	nodes.mu.RLock()
+	defer nodes.mu.RUnlock()
+
+	if i == 0 || int(i) > len(nodes.names) {
+		return ""
+	}
+	return nodes.names[i-1]
+}
+
+func localNode(name string) *Node {
+	nodes.mu.RLock()
+	defer nodes.mu.RUnlock()
+
+	return nodes.byName[name]
+}
+
+func envNode(envID uint32) *Node {
+	nodes.mu.RLock()
+	defer nodes.mu.RUnlock()
+
+	return nodes.byEnv[envID]
+}
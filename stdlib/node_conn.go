@@ -0,0 +1,701 @@
+package stdlib
+
+//
+// Connection to other node. Messages are gob encoded frames with 4 bytes
+// length prefix. Payload of the message is encoded separately, so message
+// with payload of unknown type is rejected without closing the connection.
+//
+// Connecting node sends its name and challenge, accepting node replies with
+// its name, own challenge and digest of the received challenge with the
+// cookie. Connecting node checks the digest and replies with the digest of
+// the challenge of accepting node, which checks it and acknowledges the
+// connection. Digests of two sides differ, so answer of one side can not be
+// reflected as the answer of the other side.
+//
+// Connection tracks links and monitors between local and remote processes
+// to deliver exits and downs with reason NoConnection when it is closed
+//
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+)
+
+type nodeOp int
+
+const (
+	nodeOpHello nodeOp = iota + 1
+	nodeOpSend
+	nodeOpCall
+	nodeOpReply
+	nodeOpMonitor
+	nodeOpDemonitor
+	nodeOpDown
+	nodeOpWhereis
+	nodeOpChallengeReply
+	nodeOpAck
+)
+
+const (
+	nodeMaxFrame      = 64 << 20
+	nodeChallengeSize = 16
+)
+
+//
+// sides of the handshake whose digests differ
+//
+const (
+	nodeSideConnect byte = 'c'
+	nodeSideAccept  byte = 'a'
+)
+
+var errNodeHandshake = errors.New("node handshake failed")
+
+//
+// nodeMsg is a message between nodes
+//
+type nodeMsg struct {
+	Op     nodeOp
+	Node   string
+	Seq    uint64
+	To     *Pid
+	From   *Pid
+	Ref    Ref
+	Type   callType
+	Data   []byte
+	Err    string
+	Reason string
+	Prefix string
+
+	// handshake
+	Challenge []byte
+	Digest    []byte
+
+	// decoded Data
+	term    Term
+	termErr error
+}
+
+type nodeLink struct {
+	pid   *Pid
+	envID uint32
+	id    uint64
+}
+
+type nodeConn struct {
+	node *Node
+	conn net.Conn
+	r    *bufio.Reader
+	peer string
+
+	wmu sync.Mutex
+
+	mu     sync.Mutex
+	closed bool
+	done   chan struct{}
+	seq    uint64
+	calls  map[uint64]chan *nodeMsg
+	// links between local and remote processes
+	links map[nodeLink]bool
+	// monitors of remote processes by local processes
+	monitors map[Ref]*Pid
+	// monitors of local processes by remote processes
+	monitoredBy map[Ref]*Pid
+}
+
+func newNodeConn(n *Node, conn net.Conn) *nodeConn {
+	return &nodeConn{
+		node:        n,
+		conn:        conn,
+		r:           bufio.NewReader(conn),
+		done:        make(chan struct{}),
+		calls:       make(map[uint64]chan *nodeMsg),
+		links:       make(map[nodeLink]bool),
+		monitors:    make(map[Ref]*Pid),
+		monitoredBy: make(map[Ref]*Pid),
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Handshake
+// ---------------------------------------------------------------------------
+
+//
+// connectHandshake authenticates connection to the accepting node, returns
+//  its name
+//
+func (c *nodeConn) connectHandshake() (string, error) {
+
+	n := c.node
+
+	own, err := nodeChallenge()
+	if err != nil {
+		return "", err
+	}
+	err = c.write(&nodeMsg{Op: nodeOpHello, Node: n.name, Challenge: own})
+	if err != nil {
+		return "", err
+	}
+
+	hello, err := c.readMsg()
+	if err != nil {
+		return "", err
+	}
+	if hello.Op != nodeOpHello || hello.Node == "" ||
+		len(hello.Challenge) != nodeChallengeSize ||
+		!hmac.Equal(hello.Digest, nodeDigest(n.cookie, nodeSideAccept, own)) {
+
+		return "", errNodeHandshake
+	}
+
+	err = c.write(&nodeMsg{
+		Op:     nodeOpChallengeReply,
+		Digest: nodeDigest(n.cookie, nodeSideConnect, hello.Challenge),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	ack, err := c.readMsg()
+	if err != nil || ack.Op != nodeOpAck {
+		return "", errNodeHandshake
+	}
+
+	return hello.Node, nil
+}
+
+//
+// acceptHandshake authenticates accepted connection, returns name of the
+//  connecting node. Ack is sent by caller
+//
+func (c *nodeConn) acceptHandshake() (string, error) {
+
+	n := c.node
+
+	hello, err := c.readMsg()
+	if err != nil {
+		return "", err
+	}
+	if hello.Op != nodeOpHello || hello.Node == "" ||
+		len(hello.Challenge) != nodeChallengeSize {
+
+		return "", errNodeHandshake
+	}
+
+	own, err := nodeChallenge()
+	if err != nil {
+		return "", err
+	}
+	err = c.write(&nodeMsg{
+		Op:        nodeOpHello,
+		Node:      n.name,
+		Challenge: own,
+		Digest:    nodeDigest(n.cookie, nodeSideAccept, hello.Challenge),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	reply, err := c.readMsg()
+	if err != nil {
+		return "", err
+	}
+	if reply.Op != nodeOpChallengeReply ||
+		!hmac.Equal(reply.Digest, nodeDigest(n.cookie, nodeSideConnect, own)) {
+
+		return "", errNodeHandshake
+	}
+
+	return hello.Node, nil
+}
+
+func nodeChallenge() ([]byte, error) {
+	challenge := make([]byte, nodeChallengeSize)
+	if _, err := rand.Read(challenge); err != nil {
+		return nil, err
+	}
+	return challenge, nil
+}
+
+//
+// nodeDigest returns digest of the challenge answered by the side with the
+//  cookie
+//
+func nodeDigest(cookie string, side byte, challenge []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(cookie))
+	mac.Write([]byte{side})
+	mac.Write(challenge)
+	return mac.Sum(nil)
+}
+
+// ---------------------------------------------------------------------------
+// Requests to remote processes
+// ---------------------------------------------------------------------------
+
+func (c *nodeConn) send(to *Pid, ct callType, data Term) error {
+
+	if ct == callTypeSys {
+		switch r := data.(type) {
+		case *LinkPidReq:
+			c.link(r.Pid, to)
+		case *UnlinkPidReq:
+			c.unlink(r.Pid, to)
+		case *ExitPidReq:
+			if r.Exit {
+				c.unlink(r.From, to)
+			}
+		}
+	}
+
+	b, err := c.node.codec.Encode(data)
+	if err != nil {
+		return err
+	}
+
+	return c.write(&nodeMsg{Op: nodeOpSend, To: to, Type: ct, Data: b})
+}
+
+func (c *nodeConn) call(to *Pid, ct callType, data Term) (Term, error) {
+
+	b, err := c.node.codec.Encode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := c.request(&nodeMsg{Op: nodeOpCall, To: to, Type: ct, Data: b})
+	if err != nil {
+		return nil, err
+	}
+
+	// links are returned in the request
+	if r, ok := data.(*ProcessLinksReq); ok {
+		if links, ok := m.term.(*ProcessLinksReq); ok {
+			r.Links = links.Links
+			return true, nil
+		}
+	}
+
+	return m.term, nil
+}
+
+func (c *nodeConn) whereis(prefix string, name Term) (*Pid, error) {
+
+	b, err := c.node.codec.Encode(name)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := c.request(&nodeMsg{Op: nodeOpWhereis, Prefix: prefix, Data: b})
+	if err != nil {
+		return nil, err
+	}
+
+	return m.From, nil
+}
+
+func (c *nodeConn) request(m *nodeMsg) (*nodeMsg, error) {
+
+	replyChan := make(chan *nodeMsg, 1)
+
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil, NoConnectionError
+	}
+	c.seq++
+	m.Seq = c.seq
+	c.calls[m.Seq] = replyChan
+	c.mu.Unlock()
+
+	if err := c.write(m); err != nil {
+		c.mu.Lock()
+		delete(c.calls, m.Seq)
+		c.mu.Unlock()
+		return nil, err
+	}
+
+	var reply *nodeMsg
+	select {
+	case reply = <-replyChan:
+	case <-c.done:
+		return nil, NoConnectionError
+	}
+
+	if reply.Err != "" {
+		return nil, nodeError(reply.Err)
+	}
+	if reply.termErr != nil {
+		return nil, reply.termErr
+	}
+
+	return reply, nil
+}
+
+func (c *nodeConn) monitor(to, by *Pid, ref Ref) {
+
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		go by.demonitorByMe(true, ref, NoConnection)
+		return
+	}
+	c.monitors[ref] = by
+	c.mu.Unlock()
+
+	// down is delivered by close on error
+	_ = c.write(&nodeMsg{Op: nodeOpMonitor, To: to, From: by, Ref: ref})
+}
+
+func (c *nodeConn) demonitor(to *Pid, ref Ref) {
+
+	c.mu.Lock()
+	delete(c.monitors, ref)
+	c.mu.Unlock()
+
+	_ = c.write(&nodeMsg{Op: nodeOpDemonitor, To: to, Ref: ref})
+}
+
+//
+// down sends down of local process to remote monitoring process
+//
+func (c *nodeConn) down(to *Pid, ref Ref, reason string) {
+
+	c.mu.Lock()
+	delete(c.monitoredBy, ref)
+	c.mu.Unlock()
+
+	_ = c.write(&nodeMsg{Op: nodeOpDown, To: to, Ref: ref, Reason: reason})
+}
+
+// ---------------------------------------------------------------------------
+// Requests from remote processes
+// ---------------------------------------------------------------------------
+
+func (c *nodeConn) readLoop() {
+
+	defer c.close()
+
+	for {
+		m, err := c.readMsg()
+		if err != nil {
+			return
+		}
+		c.handle(m)
+	}
+}
+
+func (c *nodeConn) handle(m *nodeMsg) {
+	switch m.Op {
+	case nodeOpSend:
+		c.handleSend(m)
+	case nodeOpCall:
+		go c.handleCall(m)
+	case nodeOpWhereis:
+		go c.handleWhereis(m)
+	case nodeOpReply:
+		c.handleReply(m)
+	case nodeOpMonitor:
+		c.handleMonitor(m)
+	case nodeOpDemonitor:
+		c.handleDemonitor(m)
+	case nodeOpDown:
+		c.handleDown(m)
+	}
+}
+
+func (c *nodeConn) handleSend(m *nodeMsg) {
+
+	if m.termErr != nil {
+		atomic.AddUint64(&c.node.dropped, 1)
+		return
+	}
+
+	pid := c.target(m.To)
+	data := m.term
+
+	if m.Type == callTypeSys {
+		switch r := data.(type) {
+		case *LinkPidReq:
+			c.link(pid, r.Pid)
+		case *UnlinkPidReq:
+			c.unlink(pid, r.Pid)
+		case *ExitPidReq:
+			if r.Exit {
+				c.unlink(pid, r.From)
+			}
+		}
+	}
+
+	err := error(NoProcError)
+	if pid != nil {
+		err = pid.send(m.Type, data)
+	}
+
+	// link to exited process
+	if r, ok := data.(*LinkPidReq); ok && IsNoProcError(err) {
+		c.unlink(pid, r.Pid)
+		_ = m.To.exitReason(r.Pid, NoProc, true)
+	}
+}
+
+func (c *nodeConn) handleCall(m *nodeMsg) {
+
+	reply := &nodeMsg{Op: nodeOpReply, Seq: m.Seq}
+
+	err := m.termErr
+	if err == nil {
+		var r Term
+		if pid := c.target(m.To); pid == nil {
+			err = NoProcError
+		} else if r, err = pid.call(m.Type, m.term); err == nil {
+			if links, ok := m.term.(*ProcessLinksReq); ok {
+				r = links
+			}
+			reply.Data, err = c.node.codec.Encode(r)
+		}
+	}
+	if err != nil {
+		reply.Err = err.Error()
+	}
+
+	_ = c.write(reply)
+}
+
+func (c *nodeConn) handleWhereis(m *nodeMsg) {
+
+	reply := &nodeMsg{Op: nodeOpReply, Seq: m.Seq}
+
+	err := m.termErr
+	if err == nil {
+		if m.Prefix == "" {
+			reply.From, err = c.node.env.whereis(m.term)
+		} else {
+			reply.From, err = c.node.env.whereisPrefix(m.Prefix, m.term)
+		}
+	}
+	if err != nil {
+		reply.Err = err.Error()
+	}
+
+	_ = c.write(reply)
+}
+
+func (c *nodeConn) handleReply(m *nodeMsg) {
+
+	c.mu.Lock()
+	replyChan, ok := c.calls[m.Seq]
+	delete(c.calls, m.Seq)
+	c.mu.Unlock()
+
+	if ok {
+		replyChan <- m
+	}
+}
+
+func (c *nodeConn) handleMonitor(m *nodeMsg) {
+
+	pid := c.target(m.To)
+	if pid == nil || m.From == nil {
+		c.down(m.From, m.Ref, NoProc)
+		return
+	}
+
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return
+	}
+	c.monitoredBy[m.Ref] = pid
+	c.mu.Unlock()
+
+	pid.monitorMe(m.From, m.Ref)
+}
+
+func (c *nodeConn) handleDemonitor(m *nodeMsg) {
+
+	c.mu.Lock()
+	delete(c.monitoredBy, m.Ref)
+	c.mu.Unlock()
+
+	if pid := c.target(m.To); pid != nil {
+		pid.demonitorMe(m.Ref)
+	}
+}
+
+func (c *nodeConn) handleDown(m *nodeMsg) {
+
+	c.mu.Lock()
+	by, ok := c.monitors[m.Ref]
+	delete(c.monitors, m.Ref)
+	c.mu.Unlock()
+
+	if ok {
+		by.demonitorByMe(true, m.Ref, m.Reason)
+	}
+}
+
+//
+// target returns local process addressed by the message
+//
+func (c *nodeConn) target(to *Pid) *Pid {
+	if to == nil || to.remote == nil || to.remote.node != c.node.name ||
+		to.remote.envID != c.node.env.uid {
+		return nil
+	}
+	return c.node.exportedPid(to.id)
+}
+
+// ---------------------------------------------------------------------------
+// Links
+// ---------------------------------------------------------------------------
+
+func (c *nodeConn) link(pid, remote *Pid) {
+	if pid == nil || pid.remote != nil || !remote.isRemote() {
+		return
+	}
+
+	c.mu.Lock()
+	if !c.closed {
+		c.links[nodeLink{pid, remote.remote.envID, remote.id}] = true
+	}
+	c.mu.Unlock()
+}
+
+func (c *nodeConn) unlink(pid, remote *Pid) {
+	if pid == nil || !remote.isRemote() {
+		return
+	}
+
+	c.mu.Lock()
+	delete(c.links, nodeLink{pid, remote.remote.envID, remote.id})
+	c.mu.Unlock()
+}
+
+//
+// close closes the connection, linked processes get exit and monitoring
+//  processes get down with NoConnection reason
+//
+func (c *nodeConn) close() {
+
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return
+	}
+	c.closed = true
+	close(c.done)
+
+	links, monitors, monitoredBy := c.links, c.monitors, c.monitoredBy
+	c.links, c.monitors, c.monitoredBy = nil, nil, nil
+	c.calls = nil
+	c.mu.Unlock()
+
+	_ = c.conn.Close()
+	c.node.removeConn(c)
+
+	for l := range links {
+		from := &Pid{
+			id:     l.id,
+			remote: &remotePid{node: c.peer, envID: l.envID, via: c.node},
+		}
+		_ = l.pid.send(callTypeSys, &ExitPidReq{from, NoConnection, true})
+	}
+	for ref, pid := range monitors {
+		pid.demonitorByMe(true, ref, NoConnection)
+	}
+	for ref, pid := range monitoredBy {
+		pid.demonitorMe(ref)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Frames
+// ---------------------------------------------------------------------------
+
+func (c *nodeConn) write(m *nodeMsg) error {
+
+	frame, err := encodeNodeFrame(m)
+	if err != nil {
+		return err
+	}
+
+	c.wmu.Lock()
+	_, err = c.conn.Write(frame)
+	c.wmu.Unlock()
+
+	if err != nil {
+		c.close()
+		return NoConnectionError
+	}
+
+	return nil
+}
+
+func encodeNodeFrame(m *nodeMsg) ([]byte, error) {
+
+	var buf bytes.Buffer
+	buf.Write(make([]byte, 4))
+	if err := gob.NewEncoder(&buf).Encode(m); err != nil {
+		return nil, err
+	}
+
+	frame := buf.Bytes()
+	binary.BigEndian.PutUint32(frame, uint32(len(frame)-4))
+
+	return frame, nil
+}
+
+func (c *nodeConn) readMsg() (*nodeMsg, error) {
+
+	var size [4]byte
+	if _, err := io.ReadFull(c.r, size[:]); err != nil {
+		return nil, err
+	}
+	n := binary.BigEndian.Uint32(size[:])
+	if n > nodeMaxFrame {
+		return nil, fmt.Errorf("node %s: frame of %d bytes", c.peer, n)
+	}
+	frame := make([]byte, n)
+	if _, err := io.ReadFull(c.r, frame); err != nil {
+		return nil, err
+	}
+
+	m := &nodeMsg{}
+	if err := gob.NewDecoder(bytes.NewReader(frame)).Decode(m); err != nil {
+		return nil, err
+	}
+	m.To.setVia(c.node)
+	m.From.setVia(c.node)
+	if len(m.Data) > 0 {
+		m.term, m.termErr = decodeVia(c.node.codec, m.Data, c.node)
+	}
+
+	return m, nil
+}
+
+//
+// nodeError makes error of the remote node
+//
+func nodeError(s string) error {
+	for _, err := range []error{
+		NoProcError, NilPidError, ChannelFullError, AlreadyRegError,
+		NotRegError, NameEmptyError, PrefixEmptyError, BadArgError,
+		NoConnectionError,
+	} {
+		if s == err.Error() {
+			return err
+		}
+	}
+	return errors.New(s)
+}
@@ -0,0 +1,351 @@
+package stdlib
+
+//
+// Pids and refs of other nodes. Pid of remote process keeps name of its node
+// and the local node it is received by, requests to it are forwarded over
+// the connection of the local node. Pids and refs are gob encoded with name
+// of their node. Gob decoder does not know the local node, so pids decoded by
+// gob are routed by any local node connected to their node
+//
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+var errNodeID = errors.New("bad node id")
+
+type remotePid struct {
+	node  string
+	envID uint32
+	// local node the pid is received by
+	via *Node
+	// process of Erlang node
+	erl *erlProc
+}
+
+func (pid *Pid) nodeName() string {
+	if pid.remote != nil {
+		return pid.remote.node
+	}
+	if n := pid.env.Node(); n != nil {
+		return n.name
+	}
+	return ""
+}
+
+//
+// route returns local process, if pid is the process of the node it is
+//  received by, or connection to node of the process
+//
+func (r *remotePid) route(pid *Pid) (*Pid, *nodeConn, error) {
+
+	n := r.via
+	if n == nil {
+		n = nodeFor(r.node)
+	}
+	if n == nil {
+		return nil, nil, NoConnectionError
+	}
+
+	if r.node == n.name {
+		if r.envID == n.env.uid {
+			if p := n.exportedPid(pid.id); p != nil {
+				return p, nil, nil
+			}
+		}
+		return nil, nil, NoProcError
+	}
+
+	if c := n.conn(r.node); c != nil {
+		return nil, c, nil
+	}
+
+	return nil, nil, NoConnectionError
+}
+
+func (r *remotePid) alive(pid *Pid) error {
+	if r.erl != nil {
+		_, err := r.erlConn()
+		return err
+	}
+	p, _, err := r.route(pid)
+	if p != nil {
+		return p.Alive()
+	}
+	return err
+}
+
+func (r *remotePid) send(pid *Pid, ct callType, data Term) error {
+	if r.erl != nil {
+		return r.erlSend(ct, data)
+	}
+	p, c, err := r.route(pid)
+	switch {
+	case err != nil:
+		return err
+	case p != nil:
+		return p.send(ct, data)
+	default:
+		return c.send(pid, ct, data)
+	}
+}
+
+func (r *remotePid) call(pid *Pid, ct callType, data Term) (Term, error) {
+	if r.erl != nil {
+		return r.erlCall(ct, data)
+	}
+	p, c, err := r.route(pid)
+	switch {
+	case err != nil:
+		return nil, err
+	case p != nil:
+		return p.call(ct, data)
+	default:
+		return c.call(pid, ct, data)
+	}
+}
+
+func (r *remotePid) monitor(pid, by *Pid, ref Ref) {
+	if r.erl != nil {
+		r.erlMonitor(by, ref)
+		return
+	}
+	p, c, err := r.route(pid)
+	switch {
+	case err != nil:
+		go by.demonitorByMe(true, ref, err.Error())
+	case p != nil:
+		p.monitorMe(by, ref)
+	default:
+		c.monitor(pid, by, ref)
+	}
+}
+
+func (r *remotePid) demonitor(pid *Pid, ref Ref) {
+	if r.erl != nil {
+		r.erlDemonitor(ref)
+		return
+	}
+	p, c, err := r.route(pid)
+	switch {
+	case err != nil:
+	case p != nil:
+		p.demonitorMe(ref)
+	default:
+		c.demonitor(pid, ref)
+	}
+}
+
+//
+// down is called when process monitored by remote pid exits
+//
+func (r *remotePid) down(pid *Pid, onStop bool, ref Ref, reason string) {
+	if !onStop || r.erl != nil {
+		return
+	}
+	p, c, err := r.route(pid)
+	switch {
+	case err != nil:
+	case p != nil:
+		p.demonitorByMe(true, ref, reason)
+	default:
+		c.down(pid, ref, reason)
+	}
+}
+
+//
+// nodeFor returns local node to route pid of node decoded outside of node
+//  connection
+//
+func nodeFor(name string) *Node {
+
+	nodes.mu.RLock()
+	defer nodes.mu.RUnlock()
+
+	if n, ok := nodes.byName[name]; ok {
+		return n
+	}
+	for _, n := range nodes.byName {
+		if n.conn(name) != nil {
+			return n
+		}
+	}
+	return nil
+}
+
+// ---------------------------------------------------------------------------
+// Encoding
+// ---------------------------------------------------------------------------
+
+//
+// GobEncode encodes pid with name of its node. Process must be a process of
+//  the node
+//
+func (pid *Pid) GobEncode() ([]byte, error) {
+	return pid.nodeID()
+}
+
+//
+// GobDecode decodes pid of the process of the node
+//
+func (pid *Pid) GobDecode(data []byte) error {
+	return pid.setNodeID(data, nil)
+}
+
+//
+// GobEncode encodes ref with name of its node
+//
+func (r Ref) GobEncode() ([]byte, error) {
+	return r.nodeID(), nil
+}
+
+//
+// GobDecode decodes ref, refs of local nodes are local refs
+//
+func (r *Ref) GobDecode(data []byte) error {
+	return r.setNodeID(data)
+}
+
+func (pid *Pid) nodeID() ([]byte, error) {
+
+	node, envID, err := pid.nodeOf()
+	if err != nil {
+		return nil, err
+	}
+
+	return encodeNodeID(node, envID, pid.id), nil
+}
+
+//
+// nodeOf returns names of the node and environment of the process. Local
+//  process is exported by the node
+//
+func (pid *Pid) nodeOf() (string, uint32, error) {
+
+	if pid.remote != nil {
+		if pid.remote.erl != nil {
+			return "", 0, fmt.Errorf("%s: process of Erlang node", pid)
+		}
+		return pid.remote.node, pid.remote.envID, nil
+	}
+
+	n := pid.env.Node()
+	if n == nil {
+		return "", 0, fmt.Errorf("%s: environment is not a node", pid)
+	}
+	n.export(pid)
+
+	return n.name, pid.env.uid, nil
+}
+
+func (pid *Pid) setNodeID(data []byte, via *Node) error {
+
+	node, envID, id, err := decodeNodeID(data)
+	if err != nil {
+		return err
+	}
+
+	pid.setNode(node, envID, id, via)
+
+	return nil
+}
+
+//
+// setNode sets identity of the process of the node, via is the local node
+//  the pid is received by or nil
+//
+func (pid *Pid) setNode(node string, envID uint32, id uint64, via *Node) {
+	pid.id = id
+	pid.remote = &remotePid{node: node, envID: envID, via: via}
+}
+
+//
+// setVia sets the local node the pid is received by if it is unknown
+//
+func (pid *Pid) setVia(via *Node) {
+	if pid != nil && pid.remote != nil && pid.remote.via == nil {
+		pid.remote.via = via
+	}
+}
+
+func (r Ref) nodeID() []byte {
+	return encodeNodeID(r.nodeName(), r.envID, r.id)
+}
+
+//
+// nodeName returns name of the node of ref, empty for ref of environment
+//  without node
+//
+func (r Ref) nodeName() string {
+
+	node := nodeIndexName(r.node)
+	if r.node == 0 {
+		if n := envNode(r.envID); n != nil {
+			node = n.name
+		}
+	}
+
+	return node
+}
+
+func (r *Ref) setNodeID(data []byte) error {
+
+	node, envID, id, err := decodeNodeID(data)
+	if err != nil {
+		return err
+	}
+
+	r.setNode(node, envID, id)
+
+	return nil
+}
+
+func (r *Ref) setNode(node string, envID uint32, id uint64) {
+
+	r.node = 0
+	if node != "" && localNode(node) == nil {
+		r.node = nodeIndex(node)
+	}
+	r.envID = envID
+	r.id = id
+}
+
+func encodeNodeID(node string, envID uint32, id uint64) []byte {
+
+	buf := make([]byte, 3*binary.MaxVarintLen64+len(node))
+
+	i := binary.PutUvarint(buf, uint64(len(node)))
+	i += copy(buf[i:], node)
+	i += binary.PutUvarint(buf[i:], uint64(envID))
+	i += binary.PutUvarint(buf[i:], id)
+
+	return buf[:i]
+}
+
+func decodeNodeID(
+	data []byte) (node string, envID uint32, id uint64, err error) {
+
+	err = errNodeID
+
+	size, i := binary.Uvarint(data)
+	if i <= 0 || uint64(len(data)-i) < size {
+		return
+	}
+	node = string(data[i : i+int(size)])
+	data = data[i+int(size):]
+
+	e, i := binary.Uvarint(data)
+	if i <= 0 {
+		return
+	}
+	data = data[i:]
+
+	id, i = binary.Uvarint(data)
+	if i <= 0 || i != len(data) {
+		return
+	}
+
+	return node, uint32(e), id, nil
+}
@@ -0,0 +1,353 @@
+package stdlib
+
+import (
+	"io"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNode(t *testing.T) {
+
+	eA, eB := NewEnv(), NewEnv()
+
+	nA, err := eA.StartNode("a@test", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nA.Stop()
+	nB, err := eB.StartNode("b@test", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nB.Stop()
+
+	if _, err = eA.StartNode("b@test", "127.0.0.1:0"); !IsAlreadyRegError(err) {
+		t.Fatalf("expected AlreadyRegError, actual %v", err)
+	}
+
+	name, err := nA.Connect(nB.Addr())
+	if err != nil || name != "b@test" {
+		t.Fatalf("expected b@test, actual %s, %v", name, err)
+	}
+	if nodes := nA.Nodes(); !reflect.DeepEqual(nodes, []string{"b@test"}) {
+		t.Fatalf("expected [b@test], actual %v", nodes)
+	}
+	if nodes := nB.Nodes(); !reflect.DeepEqual(nodes, []string{"a@test"}) {
+		t.Fatalf("expected [a@test], actual %v", nodes)
+	}
+
+	srv, err := eB.GenServerStart(new(ts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = srv.Register("srv"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = eA.WhereisName(RemoteName("b@test", "none")); !IsNotRegError(err) {
+		t.Fatalf("expected NotRegError, actual %v", err)
+	}
+	remote, err := eA.WhereisName(RemoteName("b@test", "srv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !remote.Equal(srv) || strings.HasPrefix(remote.String(), "<0.") {
+		t.Fatalf("expected remote pid of %s, actual %s", srv, remote)
+	}
+
+	//
+	// call, cast and send
+	//
+	if reply, err := remote.Call("ping"); err != nil || reply != "pong" {
+		t.Fatalf("expected pong, actual %v, %v", reply, err)
+	}
+	if reply, err := eA.CallName(RemoteName("b@test", "srv"), "ping"); err != nil ||
+		reply != "pong" {
+		t.Fatalf("expected pong, actual %v, %v", reply, err)
+	}
+	if err = remote.Cast("ping"); err != nil {
+		t.Fatal(err)
+	}
+
+	outA, outB := make(chan Term, 4), make(chan Term, 4)
+	fwdA, err := eA.Spawn(testForwardFunc, outA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fwdA.Stop()
+	fwdB, err := eB.Spawn(testForwardFunc, outB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fwdB.Stop()
+	if err = fwdB.RegisterPrefix("fwd", 1); err != nil {
+		t.Fatal(err)
+	}
+
+	// pid is sent to other node and back
+	remoteFwd, err := eA.WhereisName(RemotePrefixName("b@test", "fwd", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = remoteFwd.Send(fwdA); err != nil {
+		t.Fatal(err)
+	}
+	pidA, ok := testNodeRecv(t, outB).(*Pid)
+	if !ok || !pidA.Equal(fwdA) {
+		t.Fatalf("expected %s, actual %v", fwdA, pidA)
+	}
+	if err = pidA.Send("back"); err != nil {
+		t.Fatal(err)
+	}
+	if m := testNodeRecv(t, outA); m != "back" {
+		t.Fatalf("expected back, actual %v", m)
+	}
+
+	//
+	// exit of linked and monitored process
+	//
+	out := make(chan Term, 4)
+	linker, err := eA.Spawn(testNodeLinkFunc, remote, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer linker.Stop()
+	testNodeLinked(t, remote, linker)
+
+	if _, err = remote.Call("crash"); err == nil {
+		t.Fatal("expected error of crashed process")
+	}
+	exit, reason := testNodeExit(t, out)
+	if !exit.From.Equal(srv) || exit.Reason == ExitNormal {
+		t.Fatalf("expected exit of %s, actual %#v", srv, exit)
+	}
+	if reason != exit.Reason {
+		t.Fatalf("expected down with %s, actual %v", exit.Reason, reason)
+	}
+	if _, err = remote.Call("ping"); !IsNoProcError(err) {
+		t.Fatalf("expected NoProcError, actual %v", err)
+	}
+
+	// stop remote process
+	srv2, err := eB.GenServerStart(new(ts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = srv2.Register("srv2"); err != nil {
+		t.Fatal(err)
+	}
+	remote2, err := eA.WhereisName(RemoteName("b@test", "srv2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = remote2.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if err = srv2.Alive(); !IsNoProcError(err) {
+		t.Fatalf("expected NoProcError, actual %v", err)
+	}
+
+	//
+	// lost connection
+	//
+	srv3, err := eB.GenServerStart(new(ts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = srv3.Register("srv3"); err != nil {
+		t.Fatal(err)
+	}
+	remote3, err := eA.WhereisName(RemoteName("b@test", "srv3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	linker3, err := eA.Spawn(testNodeLinkFunc, remote3, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer linker3.Stop()
+	testNodeLinked(t, remote3, linker3)
+
+	if err = nA.Disconnect("b@test"); err != nil {
+		t.Fatal(err)
+	}
+
+	exit, reason = testNodeExit(t, out)
+	if !exit.From.Equal(srv3) || exit.Reason != NoConnection {
+		t.Fatalf("expected exit %s of %s, actual %#v", NoConnection, srv3, exit)
+	}
+	if reason != NoConnection {
+		t.Fatalf("expected down with %s, actual %v", NoConnection, reason)
+	}
+	if _, err = remote3.Call("ping"); !IsNoConnectionError(err) {
+		t.Fatalf("expected NoConnectionError, actual %v", err)
+	}
+
+	// linked process of other node exits with noconnection
+	for i := 0; srv3.Alive() == nil; i++ {
+		if i == 100 {
+			t.Fatalf("expected %s exited", srv3)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func testNodeLinkFunc(gp GenProc, args ...Term) error {
+
+	remote := args[0].(*Pid)
+	out := args[1].(chan Term)
+
+	gps := gp.(*GenProcSys)
+	gps.SetTrapExit(true)
+	gps.Self().RegisterMonitorDownFunc(func(ref Ref, reason string) {
+		out <- reason
+	})
+	gps.Link(remote)
+	gps.MonitorProcessPid(remote)
+
+	return testForwardFunc(gp, out)
+}
+
+//
+// testNodeLinked waits remote process links the process
+//
+func testNodeLinked(t *testing.T, remote, pid *Pid) {
+	t.Helper()
+
+	for i := 0; i < 100; i++ {
+		links, err := remote.ProcessLinks()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, link := range links {
+			if link.Equal(pid) {
+				return
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("%s is not linked to %s", pid, remote)
+}
+
+//
+// testNodeExit returns exit and down reason, sent to linked and monitoring
+//  process in any order
+//
+func testNodeExit(t *testing.T, out chan Term) (*ExitPidReq, string) {
+	t.Helper()
+
+	var (
+		exit   *ExitPidReq
+		reason string
+	)
+	for exit == nil || reason == "" {
+		switch m := testNodeRecv(t, out).(type) {
+		case *ExitPidReq:
+			exit = m
+		case string:
+			reason = m
+		default:
+			t.Fatalf("unexpected message %#v", m)
+		}
+	}
+	return exit, reason
+}
+
+func testNodeRecv(t *testing.T, out chan Term) Term {
+	t.Helper()
+
+	select {
+	case m := <-out:
+		return m
+	case <-time.After(time.Second):
+		t.Fatal("no message")
+	}
+	return nil
+}
+
+func TestNodeCookie(t *testing.T) {
+
+	eA, eB, eC := NewEnv(), NewEnv(), NewEnv()
+
+	nA, err := eA.StartNodeOpts("a@cookie", "127.0.0.1:0",
+		NewNodeOpts().WithCookie("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nA.Stop()
+	nB, err := eB.StartNodeOpts("b@cookie", "127.0.0.1:0",
+		NewNodeOpts().WithCookie("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nB.Stop()
+	nC, err := eC.StartNodeOpts("c@cookie", "127.0.0.1:0",
+		NewNodeOpts().WithCookie("other"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nC.Stop()
+
+	if name, err := nA.Connect(nB.Addr()); err != nil || name != "b@cookie" {
+		t.Fatalf("expected b@cookie, actual %s, %v", name, err)
+	}
+	if _, err = nA.Connect(nC.Addr()); err == nil {
+		t.Fatal("expected connection with other cookie failed")
+	}
+	if _, err = nC.Connect(nB.Addr()); err == nil {
+		t.Fatal("expected connection with other cookie failed")
+	}
+	if nodes := nA.Nodes(); !reflect.DeepEqual(nodes, []string{"b@cookie"}) {
+		t.Fatalf("expected [b@cookie], actual %v", nodes)
+	}
+	for _, n := range []*Node{nB, nC} {
+		for i := 0; i < 100 && len(n.Nodes()) > 1; i++ {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	if nodes := nB.Nodes(); !reflect.DeepEqual(nodes, []string{"a@cookie"}) {
+		t.Fatalf("expected [a@cookie], actual %v", nodes)
+	}
+	if nodes := nC.Nodes(); len(nodes) != 0 {
+		t.Fatalf("expected no nodes, actual %v", nodes)
+	}
+
+	// messages before handshake close the connection
+	conn, err := net.Dial("tcp", nB.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	frame, err := encodeNodeFrame(&nodeMsg{Op: nodeOpSend, Data: []byte{1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = conn.Write(frame); err != nil {
+		t.Fatal(err)
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err = conn.Read(make([]byte, 1)); err != io.EOF {
+		t.Fatalf("expected closed connection, actual %v", err)
+	}
+
+	// messages which can not be decoded are counted
+	srv, err := eB.GenServerStart(new(ts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Stop()
+	err = nA.conn("b@cookie").write(
+		&nodeMsg{Op: nodeOpSend, To: srv, Type: callTypeUsr, Data: []byte{1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100 && nB.Dropped() == 0; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	if n := nB.Dropped(); n != 1 {
+		t.Fatalf("expected 1 dropped message, actual %d", n)
+	}
+}
@@ -28,6 +28,11 @@ type sysOpts struct {
 	linkPid               *Pid
 	returnPidIfRegistered bool
 	tracer                Tracer
+	traceFlags            TraceFlags
+	behaviour             string
+	via                   *viaName
+	// error of the name set by WithRegName, returned by spawn
+	regNameErr error
 }
 
 //
@@ -101,3 +106,13 @@ func (op *SpawnOpts) WithTracer(t Tracer) *SpawnOpts {
 
 	return op
 }
+
+//
+// WithTraceFlags sets kinds of trace events emitted for the process
+//
+func (op *SpawnOpts) WithTraceFlags(flags TraceFlags) *SpawnOpts {
+
+	op.traceFlags = flags
+
+	return op
+}
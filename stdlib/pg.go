@@ -0,0 +1,434 @@
+package stdlib
+
+//
+// Process groups. Process can join many groups, many times, without naming
+// itself. Members are monitored by envGs and leave all groups when they die.
+// Processes monitoring the group get *GroupJoin and *GroupLeave messages to
+// their usr channel
+//
+
+import (
+	"sort"
+	"sync"
+)
+
+//
+// GroupJoin is a message to process monitoring the group when processes join
+//  the group
+//
+type GroupJoin struct {
+	Ref   Ref
+	Group string
+	Pids  []*Pid
+}
+
+//
+// GroupLeave is a message to process monitoring the group when processes
+//  leave the group. Reason is an exit reason of the process if it left the
+//  group because of exit
+//
+type GroupLeave struct {
+	Ref    Ref
+	Group  string
+	Pids   []*Pid
+	Reason string
+}
+
+//
+// GroupReply is a reply of the group member to Multicall
+//
+type GroupReply struct {
+	Pid   *Pid
+	Reply Term
+	Err   error
+}
+
+//
+// Join adds process to the group. Process joined the group many times must
+//  leave it the same number of times
+//
+func (pid *Pid) Join(group string) error {
+	if pid == nil {
+		return NilPidError
+	}
+	if group == "" {
+		return NameEmptyError
+	}
+	// groups of processes of other nodes are on their nodes
+	if pid.remote != nil {
+		return BadArgError
+	}
+	return pid.env.pgJoin(group, pid)
+}
+
+//
+// Leave removes process from the group
+//
+func (pid *Pid) Leave(group string) error {
+	if pid == nil {
+		return NilPidError
+	}
+	if group == "" {
+		return NameEmptyError
+	}
+	// groups of processes of other nodes are on their nodes
+	if pid.remote != nil {
+		return BadArgError
+	}
+	return pid.env.pgLeave(group, pid)
+}
+
+//
+// MonitorGroup subscribes process to join and leave notifications of the
+//  group. Returns reference of the monitor and current members of the group
+//
+func (pid *Pid) MonitorGroup(group string) (Ref, []*Pid, error) {
+	if pid == nil {
+		return Ref{}, nil, NilPidError
+	}
+	if group == "" {
+		return Ref{}, nil, NameEmptyError
+	}
+	if pid.remote != nil {
+		return Ref{}, nil, BadArgError
+	}
+	return pid.env.pgMonitor(group, pid)
+}
+
+//
+// DemonitorGroup removes the group monitor of the process identified by ref
+//
+func (pid *Pid) DemonitorGroup(ref Ref) error {
+	if pid == nil {
+		return NilPidError
+	}
+	if pid.remote != nil {
+		return BadArgError
+	}
+	return pid.env.pgDemonitor(ref, pid)
+}
+
+//
+// Members returns members of the group in default environment
+//
+func Members(group string) []*Pid {
+	return env.Members(group)
+}
+
+//
+// Members returns members of the group in specified environment
+//
+func (e *Env) Members(group string) []*Pid {
+
+	e.eGs.pgMu.RLock()
+	defer e.eGs.pgMu.RUnlock()
+
+	return e.eGs.pgMembers(group)
+}
+
+//
+// Broadcast sends message to all members of the group in default
+//  environment. Returns count of members the message was sent to
+//
+func Broadcast(group string, msg Term) int {
+	return env.Broadcast(group, msg)
+}
+
+//
+// Broadcast sends message to all members of the group in specified
+//  environment. Returns count of members the message was sent to
+//
+func (e *Env) Broadcast(group string, msg Term) int {
+
+	n := 0
+	for _, pid := range e.Members(group) {
+		if pid.Send(msg) == nil {
+			n++
+		}
+	}
+
+	return n
+}
+
+//
+// Multicall calls all members of the group in default environment in
+//  parallel
+//
+func Multicall(group string, req Term) []GroupReply {
+	return env.Multicall(group, req)
+}
+
+//
+// Multicall calls all members of the group in specified environment in
+//  parallel. Replies are in order of members
+//
+func (e *Env) Multicall(group string, req Term) []GroupReply {
+
+	members := e.Members(group)
+	replies := make([]GroupReply, len(members))
+
+	var wg sync.WaitGroup
+	wg.Add(len(members))
+
+	for i, pid := range members {
+		go func(r *GroupReply, pid *Pid) {
+			defer wg.Done()
+			r.Pid = pid
+			r.Reply, r.Err = pid.Call(req)
+		}(&replies[i], pid)
+	}
+
+	wg.Wait()
+
+	return replies
+}
+
+// ---------------------------------------------------------------------------
+// Locals
+// ---------------------------------------------------------------------------
+
+type pgGroup struct {
+	// member -> count of joins
+	members map[*Pid]int
+	// monitor ref -> subscriber
+	subs map[Ref]*Pid
+}
+
+//
+// pgPid is a process monitored by envGs because of groups
+//
+type pgPid struct {
+	ref Ref
+	// group -> count of joins
+	groups map[string]int
+	// monitor ref -> group
+	subs map[Ref]string
+}
+
+func (e *Env) pgJoin(group string, pid *Pid) error {
+
+	e.eGs.pgMu.Lock()
+	defer e.eGs.pgMu.Unlock()
+
+	if err := pid.Alive(); err != nil {
+		return err
+	}
+
+	gs := e.eGs
+	p := gs.pgMonitorPid(pid)
+	p.groups[group]++
+
+	g := gs.pgGroup(group)
+	g.members[pid]++
+
+	gs.pgNotify(g, func(ref Ref) Term {
+		return &GroupJoin{ref, group, []*Pid{pid}}
+	})
+
+	return nil
+}
+
+func (e *Env) pgLeave(group string, pid *Pid) error {
+
+	e.eGs.pgMu.Lock()
+	defer e.eGs.pgMu.Unlock()
+
+	gs := e.eGs
+	p, ok := gs.pgPids[pid]
+	if !ok || p.groups[group] == 0 {
+		return NotRegError
+	}
+
+	if p.groups[group]--; p.groups[group] == 0 {
+		delete(p.groups, group)
+	}
+	gs.pgDemonitorPid(pid, p)
+
+	g := gs.pgGroups[group]
+	if g.members[pid]--; g.members[pid] == 0 {
+		delete(g.members, pid)
+	}
+
+	gs.pgNotify(g, func(ref Ref) Term {
+		return &GroupLeave{ref, group, []*Pid{pid}, ""}
+	})
+	gs.pgReleaseGroup(group, g)
+
+	return nil
+}
+
+func (e *Env) pgMonitor(group string, pid *Pid) (Ref, []*Pid, error) {
+
+	e.eGs.pgMu.Lock()
+	defer e.eGs.pgMu.Unlock()
+
+	if err := pid.Alive(); err != nil {
+		return Ref{}, nil, err
+	}
+
+	gs := e.eGs
+	ref := gs.newRef()
+
+	p := gs.pgMonitorPid(pid)
+	p.subs[ref] = group
+
+	g := gs.pgGroup(group)
+	g.subs[ref] = pid
+
+	return ref, gs.pgMembers(group), nil
+}
+
+func (e *Env) pgDemonitor(ref Ref, pid *Pid) error {
+
+	e.eGs.pgMu.Lock()
+	defer e.eGs.pgMu.Unlock()
+
+	gs := e.eGs
+	p, ok := gs.pgPids[pid]
+	if !ok {
+		return NotRegError
+	}
+	group, ok := p.subs[ref]
+	if !ok {
+		return NotRegError
+	}
+
+	delete(p.subs, ref)
+	gs.pgDemonitorPid(pid, p)
+
+	g := gs.pgGroups[group]
+	delete(g.subs, ref)
+	gs.pgReleaseGroup(group, g)
+
+	return nil
+}
+
+func (gs *envGs) pgGroup(group string) *pgGroup {
+
+	if gs.pgGroups == nil {
+		gs.pgGroups = make(map[string]*pgGroup)
+	}
+
+	g, ok := gs.pgGroups[group]
+	if !ok {
+		g = &pgGroup{
+			members: make(map[*Pid]int),
+			subs:    make(map[Ref]*Pid),
+		}
+		gs.pgGroups[group] = g
+	}
+
+	return g
+}
+
+func (gs *envGs) pgReleaseGroup(group string, g *pgGroup) {
+	if len(g.members) == 0 && len(g.subs) == 0 {
+		delete(gs.pgGroups, group)
+	}
+}
+
+//
+// pgMembers returns alive members of the group ordered by pid
+//
+func (gs *envGs) pgMembers(group string) []*Pid {
+
+	g, ok := gs.pgGroups[group]
+	if !ok {
+		return nil
+	}
+
+	pids := make([]*Pid, 0, len(g.members))
+	for pid := range g.members {
+		if pid.Alive() == nil {
+			pids = append(pids, pid)
+		}
+	}
+
+	sort.Slice(pids, func(i, j int) bool {
+		return pids[i].id < pids[j].id
+	})
+
+	return pids
+}
+
+func (gs *envGs) pgNotify(g *pgGroup, msg func(ref Ref) Term) {
+	for ref, sub := range g.subs {
+		_ = sub.Send(msg(ref))
+	}
+}
+
+//
+// pgMonitorPid monitors the process if it is not monitored yet
+//
+func (gs *envGs) pgMonitorPid(pid *Pid) *pgPid {
+
+	if gs.pgPids == nil {
+		gs.pgPids = make(map[*Pid]*pgPid)
+		gs.pgPidByRef = make(map[Ref]*Pid)
+	}
+
+	p, ok := gs.pgPids[pid]
+	if !ok {
+		p = &pgPid{
+			ref:    gs.newRef(),
+			groups: make(map[string]int),
+			subs:   make(map[Ref]string),
+		}
+		pid.monitorMe(gs.pid, p.ref)
+		gs.pid.monitorByMe(pid, p.ref)
+
+		gs.pgPids[pid] = p
+		gs.pgPidByRef[p.ref] = pid
+	}
+
+	return p
+}
+
+//
+// pgDemonitorPid removes the monitor if process is not in groups and does not
+//  monitor groups
+//
+func (gs *envGs) pgDemonitorPid(pid *Pid, p *pgPid) {
+
+	if len(p.groups) > 0 || len(p.subs) > 0 {
+		return
+	}
+
+	gs.DemonitorProcessPid(p.ref)
+	delete(gs.pgPidByRef, p.ref)
+	delete(gs.pgPids, pid)
+}
+
+//
+// pgDown removes exited process from groups
+//
+func (gs *envGs) pgDown(ref Ref, reason string) {
+
+	gs.pgMu.Lock()
+	defer gs.pgMu.Unlock()
+
+	pid, ok := gs.pgPidByRef[ref]
+	if !ok {
+		return
+	}
+	p := gs.pgPids[pid]
+
+	delete(gs.pgPidByRef, ref)
+	delete(gs.pgPids, pid)
+
+	for subRef, group := range p.subs {
+		g := gs.pgGroups[group]
+		delete(g.subs, subRef)
+		gs.pgReleaseGroup(group, g)
+	}
+
+	for group := range p.groups {
+		g := gs.pgGroups[group]
+		delete(g.members, pid)
+
+		gs.pgNotify(g, func(ref Ref) Term {
+			return &GroupLeave{ref, group, []*Pid{pid}, reason}
+		})
+		gs.pgReleaseGroup(group, g)
+	}
+}
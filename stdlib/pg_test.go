@@ -0,0 +1,177 @@
+package stdlib
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPg(t *testing.T) {
+
+	e := NewEnv()
+
+	out := make(chan Term, 16)
+	sub, err := e.Spawn(testForwardFunc, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Stop()
+
+	a, err := e.GenServerStart(new(ts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := e.GenServerStart(new(ts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Stop()
+
+	ref, members, err := sub.MonitorGroup("g")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(members) != 0 {
+		t.Fatalf("expected no members, actual %v", members)
+	}
+
+	expect := func(msg Term) {
+		t.Helper()
+		select {
+		case m := <-out:
+			if !reflect.DeepEqual(m, msg) {
+				t.Fatalf("expected %#v, actual %#v", msg, m)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("no message %#v", msg)
+		}
+	}
+
+	if err = a.Join("g"); err != nil {
+		t.Fatal(err)
+	}
+	expect(&GroupJoin{ref, "g", []*Pid{a}})
+
+	for i := 0; i < 2; i++ {
+		if err = b.Join("g"); err != nil {
+			t.Fatal(err)
+		}
+		expect(&GroupJoin{ref, "g", []*Pid{b}})
+	}
+	if err = b.Join("other"); err != nil {
+		t.Fatal(err)
+	}
+
+	if members = e.Members("g"); !reflect.DeepEqual(members, []*Pid{a, b}) {
+		t.Fatalf("expected members %v, actual %v", []*Pid{a, b}, members)
+	}
+
+	if n := e.Broadcast("g", "hello"); n != 2 {
+		t.Fatalf("expected broadcast to 2 members, actual %d", n)
+	}
+
+	replies := e.Multicall("g", "ping")
+	if len(replies) != 2 {
+		t.Fatalf("expected 2 replies, actual %v", replies)
+	}
+	for i, r := range replies {
+		if r.Pid != members[i] || r.Err != nil || r.Reply != "pong" {
+			t.Fatalf("unexpected reply %#v", r)
+		}
+	}
+
+	// joined twice, leaves twice
+	for i := 0; i < 2; i++ {
+		if err = b.Leave("g"); err != nil {
+			t.Fatal(err)
+		}
+		expect(&GroupLeave{ref, "g", []*Pid{b}, ""})
+	}
+	if err = b.Leave("g"); !IsNotRegError(err) {
+		t.Fatalf("expected NotRegError, actual %v", err)
+	}
+	if members = e.Members("other"); !reflect.DeepEqual(members, []*Pid{b}) {
+		t.Fatalf("expected members %v, actual %v", []*Pid{b}, members)
+	}
+
+	// exited member leaves groups
+	if err = a.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	expect(&GroupLeave{ref, "g", []*Pid{a}, ExitNormal})
+	if members = e.Members("g"); len(members) != 0 {
+		t.Fatalf("expected no members, actual %v", members)
+	}
+
+	if err = sub.DemonitorGroup(ref); err != nil {
+		t.Fatal(err)
+	}
+	if err = sub.DemonitorGroup(ref); !IsNotRegError(err) {
+		t.Fatalf("expected NotRegError, actual %v", err)
+	}
+	if err = b.Leave("other"); err != nil {
+		t.Fatal(err)
+	}
+
+	e.eGs.pgMu.RLock()
+	defer e.eGs.pgMu.RUnlock()
+
+	if len(e.eGs.pgGroups) != 0 || len(e.eGs.pgPids) != 0 ||
+		len(e.eGs.pgPidByRef) != 0 {
+		t.Fatalf("expected no groups, actual %v, %v",
+			e.eGs.pgGroups, e.eGs.pgPids)
+	}
+}
+
+func TestPgErrors(t *testing.T) {
+
+	e := NewEnv()
+
+	pid, err := e.GenServerStart(new(ts))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = pid.Join(""); !IsNameEmptyError(err) {
+		t.Fatalf("expected NameEmptyError, actual %v", err)
+	}
+	if err = pid.Leave("g"); !IsNotRegError(err) {
+		t.Fatalf("expected NotRegError, actual %v", err)
+	}
+	if err = pid.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if err = pid.Join("g"); !IsNoProcError(err) {
+		t.Fatalf("expected NoProcError, actual %v", err)
+	}
+	if _, _, err = pid.MonitorGroup("g"); !IsNoProcError(err) {
+		t.Fatalf("expected NoProcError, actual %v", err)
+	}
+	if replies := e.Multicall("g", "ping"); len(replies) != 0 {
+		t.Fatalf("expected no replies, actual %v", replies)
+	}
+}
+
+func TestPgRemotePid(t *testing.T) {
+
+	goaPid := new(Pid)
+	goaPid.setNode("other@host", 1, 1, nil)
+
+	for _, pid := range []*Pid{
+		erlPidOf(ErlPid{Node: "erl@host", ID: 1, Creation: 1}, nil),
+		goaPid,
+	} {
+		if err := pid.Join("g"); !IsBadArgError(err) {
+			t.Fatalf("expected BadArgError, actual %v", err)
+		}
+		if err := pid.Leave("g"); !IsBadArgError(err) {
+			t.Fatalf("expected BadArgError, actual %v", err)
+		}
+		if _, _, err := pid.MonitorGroup("g"); !IsBadArgError(err) {
+			t.Fatalf("expected BadArgError, actual %v", err)
+		}
+		if err := pid.DemonitorGroup(Ref{}); !IsBadArgError(err) {
+			t.Fatalf("expected BadArgError, actual %v", err)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package stdlib
 import (
 	"fmt"
 	"sync"
+	"sync/atomic"
 )
 
 //
@@ -22,6 +23,21 @@ type Pid struct {
 	mu           sync.RWMutex
 	monitorsByMe map[Ref]*Pid
 	monitors     map[Ref]*Pid
+
+	// *pidTrace
+	trace atomic.Value
+	// *SeqTraceToken of messages sent by the process
+	seqTrace atomic.Value
+	// set while *SendEvent of the process is handled by its tracer
+	tracingSend int32
+
+	// type of GenProc object, prefix and name of the process set at spawn
+	behaviour   string
+	spawnPrefix string
+	spawnName   string
+
+	// identity of the process of other node, nil for local processes
+	remote *remotePid
 }
 
 func newPid(id uint64, e *Env, usrChanSize, sysChanSize int) *Pid {
@@ -56,6 +72,14 @@ func (pid *Pid) String() string {
 		return "<nil>"
 	}
 
+	if pid.remote != nil {
+		if erl := pid.remote.erl; erl != nil && erl.name != "" {
+			return fmt.Sprintf("{%s,%s}", erl.name, pid.remote.node)
+		}
+		return fmt.Sprintf("<%d.%d.%d>",
+			nodeIndex(pid.remote.node), pid.remote.envID, pid.id)
+	}
+
 	return fmt.Sprintf("<0.%d.%d>", pid.env.id(), pid.id)
 }
 
@@ -70,7 +94,8 @@ func (pid *Pid) Equal(pid2 *Pid) bool {
 		return false
 	}
 
-	return pid.id == pid2.id && pid.env.id() == pid2.env.id()
+	return pid.id == pid2.id && pid.envID() == pid2.envID() &&
+		pid.nodeName() == pid2.nodeName() && pid.erlName() == pid2.erlName()
 }
 
 //
@@ -82,6 +107,10 @@ func (pid *Pid) Alive() error {
 		return NilPidError
 	}
 
+	if pid.remote != nil {
+		return pid.remote.alive(pid)
+	}
+
 	// fmt.Println(pid, "alive: exitChan=", pid.exitChan)
 
 	select {
@@ -115,3 +144,25 @@ func (pid *Pid) GetSysChannel() <-chan Term {
 
 	return pid.sysChan
 }
+
+func (pid *Pid) envID() uint32 {
+	if pid.remote != nil {
+		return pid.remote.envID
+	}
+	return pid.env.id()
+}
+
+func (pid *Pid) isRemote() bool {
+	return pid != nil && pid.remote != nil
+}
+
+func spawnName(prefix string, name Term) string {
+	switch {
+	case name == nil:
+		return ""
+	case prefix == "":
+		return fmt.Sprintf("%v", name)
+	default:
+		return fmt.Sprintf("%s/%v", prefix, name)
+	}
+}
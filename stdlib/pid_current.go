@@ -0,0 +1,83 @@
+package stdlib
+
+//
+// Current process of the goroutine. Process goroutine is bound to its pid
+// while the process runs, so Send, Cast and Call made by the process know the
+// sender without passing it. The sender is needed only to trace sends and to
+// carry sequential trace token, so it is looked up only after a tracer with
+// TraceFlagSend or a token is set for any process
+//
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	// goroutine id -> *Pid
+	currentPids sync.Map
+	// set when the sender of the message is needed
+	currentPidsOn int32
+)
+
+//
+// bindCurrentPid binds current goroutine to the process. Returns id of the
+//  goroutine to unbind
+//
+func bindCurrentPid(pid *Pid) uint64 {
+	id := goroutineID()
+	if id != 0 {
+		currentPids.Store(id, pid)
+	}
+	return id
+}
+
+func unbindCurrentPid(id uint64) {
+	if id != 0 {
+		currentPids.Delete(id)
+	}
+}
+
+//
+// currentPidNeeded enables lookup of the current process
+//
+func currentPidNeeded() {
+	if atomic.LoadInt32(&currentPidsOn) == 0 {
+		atomic.StoreInt32(&currentPidsOn, 1)
+	}
+}
+
+//
+// currentPid returns process of the current goroutine, nil if the goroutine
+//  is not a process or the sender is not needed
+//
+func currentPid() *Pid {
+	if atomic.LoadInt32(&currentPidsOn) == 0 {
+		return nil
+	}
+	if pid, ok := currentPids.Load(goroutineID()); ok {
+		return pid.(*Pid)
+	}
+	return nil
+}
+
+//
+// goroutineID parses id of the current goroutine from the header of its
+//  stack, "goroutine 1 [running]:", 0 if the header is not recognized
+//
+func goroutineID() uint64 {
+
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+
+	b = bytes.TrimPrefix(b, []byte("goroutine "))
+	if i := bytes.IndexByte(b, ' '); i > 0 {
+		if id, err := strconv.ParseUint(string(b[:i]), 10, 64); err == nil {
+			return id
+		}
+	}
+	return 0
+}
@@ -13,6 +13,11 @@ func (pid *Pid) RegisterMonitorDownFunc(fn MonitorDownFunc) {
 }
 
 func (pid *Pid) monitorMe(mPid *Pid, ref Ref) {
+	if pid.remote != nil {
+		pid.remote.monitor(pid, mPid, ref)
+		return
+	}
+
 	pid.mu.Lock()
 	defer pid.mu.Unlock()
 
@@ -23,6 +28,11 @@ func (pid *Pid) monitorMe(mPid *Pid, ref Ref) {
 }
 
 func (pid *Pid) demonitorMe(ref Ref) {
+	if pid.remote != nil {
+		pid.remote.demonitor(pid, ref)
+		return
+	}
+
 	pid.mu.Lock()
 	defer pid.mu.Unlock()
 
@@ -41,11 +51,17 @@ func (pid *Pid) monitorByMe(mPid *Pid, ref Ref) {
 	}
 	pid.monitorsByMe[ref] = mPid
 
+	pid.traceMonitor(mPid, ref)
 }
 
 // func (pid *Pid) demonitorByMe(ref Ref) *Pid {
 func (pid *Pid) demonitorByMe(onStop bool, ref Ref, reason string) *Pid {
 
+	if pid.remote != nil {
+		pid.remote.down(pid, onStop, ref, reason)
+		return nil
+	}
+
 	var (
 		ok   bool
 		mPid *Pid
@@ -71,6 +87,8 @@ func (pid *Pid) demonitorByMe(onStop bool, ref Ref, reason string) *Pid {
 		pid.mu.Lock()
 		delete(pid.monitorsByMe, ref)
 		pid.mu.Unlock()
+
+		pid.traceDemonitor(mPid, ref, reason)
 	}
 
 	if onStop && pid.monitorDownFunc != nil {
@@ -102,3 +120,18 @@ func (pid *Pid) onStop(reason string) {
 // 	// fmt.Println(pidTo, "monitorDown:", reason, ref)
 // 	pidTo.demonitorByMe(true, ref, reason)
 // }
+
+//
+// Trace
+//
+func (pid *Pid) traceMonitor(target *Pid, ref Ref) {
+	if t := pid.tracerFor(TraceFlagMonitors); t != nil {
+		t.Event(&MonitorEvent{pid.traceEvent(), target, ref})
+	}
+}
+
+func (pid *Pid) traceDemonitor(target *Pid, ref Ref, reason string) {
+	if t := pid.tracerFor(TraceFlagMonitors); t != nil {
+		t.Event(&DemonitorEvent{pid.traceEvent(), target, ref, reason})
+	}
+}
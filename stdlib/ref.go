@@ -8,6 +8,8 @@ import (
 // Ref is a unique reference
 //
 type Ref struct {
+	// index of the node of the ref made by remote node, 0 for local refs
+	node  uint32
 	envID uint32
 	id    uint64
 }
@@ -30,7 +32,7 @@ func (e *Env) MakeRef() Ref {
 // String returns string presentation of ref
 //
 func (r Ref) String() string {
-	return fmt.Sprintf("#ref<0.%d.%d>", r.envID, r.id)
+	return fmt.Sprintf("#ref<%d.%d.%d>", r.node, r.envID, r.id)
 }
 
 //
@@ -38,6 +40,10 @@ func (r Ref) String() string {
 //
 func CompareRefs(a, b Ref) int {
 	switch {
+	case a.node > b.node:
+		return 1
+	case a.node < b.node:
+		return -1
 	case a.envID > b.envID:
 		return 1
 	case a.envID < b.envID:
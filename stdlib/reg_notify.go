@@ -0,0 +1,253 @@
+package stdlib
+
+//
+// Notifications of name registrations. Subscriber gets *NameRegistered and
+// *NameUnregistered messages to its usr channel, including unregistrations
+// of exited processes. Events are queued for the subscriber under the lock of
+// the name, so they are in order, and are sent after the lock is released
+//
+
+import (
+	"strings"
+	"sync"
+)
+
+//
+// nameSubQueueSize is a max count of events queued for the subscriber
+//
+const nameSubQueueSize = 1024
+
+//
+// NameRegistered is a message to subscriber when process registered the name
+//
+type NameRegistered struct {
+	Ref    Ref
+	Prefix string
+	Name   Term
+	Pid    *Pid
+}
+
+//
+// NameUnregistered is a message to subscriber when the name is unregistered.
+//  Reason is an exit reason of the process if the name is unregistered
+//  because the process exited, empty otherwise
+//
+type NameUnregistered struct {
+	Ref    Ref
+	Prefix string
+	Name   Term
+	Pid    *Pid
+	Reason string
+}
+
+//
+// NamesLost is a message to subscriber when Count events were not delivered
+//  because its usr channel was full. It is sent before the next delivered
+//  event, registered names should be read again with Whereare
+//
+type NamesLost struct {
+	Ref   Ref
+	Count int
+}
+
+//
+// SubscribeNames subscribes process to registrations of names with prefix in
+//  default environment
+//
+func SubscribeNames(pid *Pid, prefix string) (Ref, error) {
+	return env.SubscribeNames(pid, prefix)
+}
+
+//
+// UnsubscribeNames removes subscription in default environment
+//
+func UnsubscribeNames(ref Ref) error {
+	return env.UnsubscribeNames(ref)
+}
+
+//
+// SubscribeNames subscribes process to registrations of names with prefix in
+//  specified environment. Empty prefix matches names registered without
+//  prefix, prefix ending with '*' matches all prefixes starting with it, so
+//  "*" matches all names. Subscription of exited subscriber is removed on
+//  next event.
+//
+// Events are not sent for names registered before the subscription, so
+//  Whereare should be called after subscribing
+//
+func (e *Env) SubscribeNames(pid *Pid, prefix string) (Ref, error) {
+
+	if err := pid.Alive(); err != nil {
+		return Ref{}, err
+	}
+
+	gs := e.eGs
+	gs.subsMu.Lock()
+	defer gs.subsMu.Unlock()
+
+	if gs.nameSubs == nil {
+		gs.nameSubs = make(map[Ref]*nameSub)
+	}
+
+	ref := gs.newRef()
+	gs.nameSubs[ref] = &nameSub{pid: pid, prefix: prefix}
+
+	return ref, nil
+}
+
+//
+// UnsubscribeNames removes subscription in specified environment
+//
+func (e *Env) UnsubscribeNames(ref Ref) error {
+
+	e.eGs.subsMu.Lock()
+	defer e.eGs.subsMu.Unlock()
+
+	if _, ok := e.eGs.nameSubs[ref]; !ok {
+		return NotRegError
+	}
+	delete(e.eGs.nameSubs, ref)
+
+	return nil
+}
+
+// ---------------------------------------------------------------------------
+// Locals
+// ---------------------------------------------------------------------------
+
+type nameSub struct {
+	pid    *Pid
+	prefix string
+
+	mu      sync.Mutex
+	queue   []Term
+	lost    int
+	sending bool
+}
+
+func (sub *nameSub) match(prefix string) bool {
+	if strings.HasSuffix(sub.prefix, "*") {
+		return strings.HasPrefix(prefix, strings.TrimSuffix(sub.prefix, "*"))
+	}
+	return prefix == sub.prefix
+}
+
+//
+// registered traces and notifies registration of the name, called with
+//  shard of the name locked
+//
+func (gs *envGs) registered(pid *Pid, prefix string, name Term) {
+	pid.traceRegister(prefix, name)
+	gs.notifyNames(prefix, func(ref Ref) Term {
+		return &NameRegistered{ref, prefix, name, pid}
+	})
+}
+
+//
+// unregistered traces and notifies unregistration of the name, called with
+//  shard of the name locked
+//
+func (gs *envGs) unregistered(
+	pid *Pid, prefix string, name Term, reason string) {
+
+	pid.traceUnregister(prefix, name)
+	gs.notifyNames(prefix, func(ref Ref) Term {
+		return &NameUnregistered{ref, prefix, name, pid, reason}
+	})
+}
+
+func (gs *envGs) notifyNames(prefix string, msg func(ref Ref) Term) {
+
+	gs.subsMu.RLock()
+	defer gs.subsMu.RUnlock()
+
+	for ref, sub := range gs.nameSubs {
+		if sub.match(prefix) {
+			gs.pushName(ref, sub, msg(ref))
+		}
+	}
+}
+
+//
+// pushName queues event for the subscriber and starts delivery if it is not
+//  running
+//
+func (gs *envGs) pushName(ref Ref, sub *nameSub, msg Term) {
+
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+
+	if len(sub.queue) < nameSubQueueSize {
+		sub.queue = append(sub.queue, msg)
+	} else {
+		sub.lost++
+	}
+
+	if !sub.sending {
+		sub.sending = true
+		go gs.deliverNames(ref, sub)
+	}
+}
+
+//
+// deliverNames sends queued events to the subscriber until the queue is
+//  empty. Count of lost events is kept until the next event if the subscriber
+//  channel is still full
+//
+func (gs *envGs) deliverNames(ref Ref, sub *nameSub) {
+
+	for {
+		sub.mu.Lock()
+		queue, lost := sub.queue, sub.lost
+		sub.queue, sub.lost = nil, 0
+		if len(queue) == 0 {
+			sub.lost = lost
+			sub.sending = false
+			sub.mu.Unlock()
+			return
+		}
+		sub.mu.Unlock()
+
+		for _, msg := range queue {
+			if lost > 0 {
+				if err := sub.pid.Send(&NamesLost{ref, lost}); err != nil {
+					if gs.nameSubExited(ref, err) {
+						return
+					}
+					lost++
+					continue
+				}
+				lost = 0
+			}
+			if err := sub.pid.Send(msg); err != nil {
+				if gs.nameSubExited(ref, err) {
+					return
+				}
+				lost++
+			}
+		}
+
+		if lost > 0 {
+			sub.mu.Lock()
+			sub.lost += lost
+			sub.mu.Unlock()
+		}
+	}
+}
+
+//
+// nameSubExited removes subscription if send error is the exit of the
+//  subscriber
+//
+func (gs *envGs) nameSubExited(ref Ref, err error) bool {
+
+	if !IsNoProcError(err) {
+		return false
+	}
+
+	gs.subsMu.Lock()
+	delete(gs.nameSubs, ref)
+	gs.subsMu.Unlock()
+
+	return true
+}
@@ -0,0 +1,203 @@
+package stdlib
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSubscribeNames(t *testing.T) {
+
+	e := NewEnv()
+
+	out := make(chan Term, 16)
+	sub, err := e.Spawn(testForwardFunc, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Stop()
+
+	ref, err := e.SubscribeNames(sub, "route*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	allRef, err := e.SubscribeNames(sub, "*")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := func(msgs ...Term) {
+		t.Helper()
+		for _, msg := range msgs {
+			select {
+			case m := <-out:
+				if !reflect.DeepEqual(m, msg) {
+					t.Fatalf("expected %#v, actual %#v", msg, m)
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("no message %#v", msg)
+			}
+		}
+	}
+
+	pid, err := e.GenServerStart(new(ts))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = pid.Register("local"); err != nil {
+		t.Fatal(err)
+	}
+	expect(&NameRegistered{allRef, "", "local", pid})
+
+	if err = pid.RegisterPrefix("routes", "a"); err != nil {
+		t.Fatal(err)
+	}
+	// order of subscribers is not defined
+	m1, m2 := <-out, <-out
+	if m1.(*NameRegistered).Ref == allRef {
+		m1, m2 = m2, m1
+	}
+	if !reflect.DeepEqual(m1, &NameRegistered{ref, "routes", "a", pid}) ||
+		!reflect.DeepEqual(m2, &NameRegistered{allRef, "routes", "a", pid}) {
+		t.Fatalf("unexpected messages %#v, %#v", m1, m2)
+	}
+
+	if err = e.UnsubscribeNames(allRef); err != nil {
+		t.Fatal(err)
+	}
+	if err = e.UnsubscribeNames(allRef); !IsNotRegError(err) {
+		t.Fatalf("expected NotRegError, actual %v", err)
+	}
+
+	if err = pid.UnregisterPrefix("routes", "a"); err != nil {
+		t.Fatal(err)
+	}
+	expect(&NameUnregistered{ref, "routes", "a", pid, ""})
+
+	if err = pid.RegisterPrefix("routes", "b"); err != nil {
+		t.Fatal(err)
+	}
+	expect(&NameRegistered{ref, "routes", "b", pid})
+
+	// unregistered on exit
+	if err = pid.StopReason("bye"); err != nil {
+		t.Fatal(err)
+	}
+	expect(&NameUnregistered{ref, "routes", "b", pid, "bye"})
+
+	// exited subscriber is removed
+	if err = sub.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if pid, err = e.GenServerStartOpts(
+		new(ts), NewSpawnOpts().WithPrefix("routes").WithName("c")); err != nil {
+		t.Fatal(err)
+	}
+	defer pid.Stop()
+
+	subs := func() int {
+		e.eGs.subsMu.RLock()
+		defer e.eGs.subsMu.RUnlock()
+		return len(e.eGs.nameSubs)
+	}
+	for i := 0; i < 100 && subs() != 0; i++ {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if n := subs(); n != 0 {
+		t.Fatalf("expected no subscribers, actual %d", n)
+	}
+}
+
+func TestSubscribeNamesLost(t *testing.T) {
+
+	e := NewEnv()
+
+	// subscriber is blocked on the first message
+	out := make(chan Term)
+	sub, err := e.SpawnWithOpts(
+		testForwardFunc, NewSpawnOpts().WithUsrChannelSize(2), out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ref, err := e.SubscribeNames(sub, "*")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pid, err := e.GenServerStart(new(ts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pid.Stop()
+
+	const n = 8
+	for i := 0; i < n; i++ {
+		if err = pid.RegisterPrefix("lost", i); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	e.eGs.subsMu.RLock()
+	ns := e.eGs.nameSubs[ref]
+	e.eGs.subsMu.RUnlock()
+
+	waitSent := func() {
+		sending := func() bool {
+			ns.mu.Lock()
+			defer ns.mu.Unlock()
+			return ns.sending
+		}
+		for i := 0; i < 100 && sending(); i++ {
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+	waitSent()
+
+	received := 0
+	for done := false; !done; {
+		select {
+		case <-out:
+			received++
+		case <-time.After(50 * time.Millisecond):
+			done = true
+		}
+	}
+	if received == n {
+		t.Fatal("expected lost events")
+	}
+
+	// lost events are reported before the next event
+	if err = pid.RegisterPrefix("lost", n); err != nil {
+		t.Fatal(err)
+	}
+	for _, msg := range []Term{
+		&NamesLost{ref, n - received},
+		&NameRegistered{ref, "lost", n, pid},
+	} {
+		select {
+		case m := <-out:
+			if !reflect.DeepEqual(m, msg) {
+				t.Fatalf("expected %#v, actual %#v", msg, m)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("no message %#v", msg)
+		}
+	}
+
+	// subscriber is stopped when there are no events to deliver
+	if err = e.UnsubscribeNames(ref); err != nil {
+		t.Fatal(err)
+	}
+	waitSent()
+
+	go func() {
+		for range out {
+		}
+	}()
+	if err = sub.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	close(out)
+}
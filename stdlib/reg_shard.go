@@ -0,0 +1,248 @@
+package stdlib
+
+//
+// Registry of names is striped over shards to avoid one lock for all
+// register, unregister and lookup calls. Names without prefix are sharded by
+// hash of the name, names with prefix are sharded by hash of the prefix, so
+// Whereare reads one shard. Monitored processes are sharded by pid.
+//
+// Lock order: name or prefix shard, pid shard, subscribers
+//
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+const regShardCount = 64
+
+type regPid struct {
+	refReg
+	pid *Pid
+}
+
+type regNameShard struct {
+	mu    sync.RWMutex
+	names RegMap
+}
+
+type regPrefixShard struct {
+	mu       sync.RWMutex
+	prefixes map[string]RegMap
+}
+
+type regPidShard struct {
+	mu   sync.Mutex
+	pids map[*Pid]*regPid
+}
+
+type regShards struct {
+	names    [regShardCount]regNameShard
+	prefixes [regShardCount]regPrefixShard
+	pids     [regShardCount]regPidShard
+
+	// monitor ref -> registered pid
+	byRef sync.Map
+}
+
+type regStat struct {
+	names       int
+	prefixNames int
+	prefixes    map[string]int
+	pids        int
+	dump        []Term
+}
+
+//
+// lock locks shard of the name for write and returns names of the shard,
+//  prefix map is created if not exists
+//
+func (r *regShards) lock(prefix string, name Term) (RegMap, func()) {
+
+	if prefix == "" {
+		s := &r.names[regHash(name)%regShardCount]
+		s.mu.Lock()
+		if s.names == nil {
+			s.names = make(RegMap)
+		}
+		return s.names, s.mu.Unlock
+	}
+
+	s := r.prefixShard(prefix)
+	s.mu.Lock()
+	if s.prefixes == nil {
+		s.prefixes = make(map[string]RegMap)
+	}
+	names, ok := s.prefixes[prefix]
+	if !ok {
+		names = make(RegMap)
+		s.prefixes[prefix] = names
+	}
+	return names, s.mu.Unlock
+}
+
+//
+// rlock locks shard of the name for read, returned names may be nil
+//
+func (r *regShards) rlock(prefix string, name Term) (RegMap, func()) {
+
+	if prefix == "" {
+		s := &r.names[regHash(name)%regShardCount]
+		s.mu.RLock()
+		return s.names, s.mu.RUnlock
+	}
+
+	s := r.prefixShard(prefix)
+	s.mu.RLock()
+	return s.prefixes[prefix], s.mu.RUnlock
+}
+
+func (r *regShards) prefixShard(prefix string) *regPrefixShard {
+	return &r.prefixes[regHashString(prefix)%regShardCount]
+}
+
+func (r *regShards) pidShard(pid *Pid) *regPidShard {
+	return &r.pids[regHashUint(pid.id)%regShardCount]
+}
+
+//
+// takePid removes monitored pid by monitor ref, returns nil if ref is unknown
+//
+func (r *regShards) takePid(ref Ref) *regPid {
+
+	v, ok := r.byRef.Load(ref)
+	if !ok {
+		return nil
+	}
+	pid := v.(*Pid)
+
+	s := r.pidShard(pid)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r.byRef.Delete(ref)
+
+	reg, ok := s.pids[pid]
+	if !ok || reg.ref != ref {
+		return nil
+	}
+	delete(s.pids, pid)
+
+	return reg
+}
+
+func (r *regShards) stat(dumpNames int) *regStat {
+
+	st := &regStat{prefixes: make(map[string]int)}
+
+	for i := range r.names {
+		s := &r.names[i]
+		s.mu.RLock()
+		st.names += len(s.names)
+		for name, pid := range s.names {
+			if len(st.dump) >= dumpNames {
+				break
+			}
+			st.dump = append(st.dump, fmt.Sprintf("%v: %s", name, pid))
+		}
+		s.mu.RUnlock()
+	}
+
+	for i := range r.prefixes {
+		s := &r.prefixes[i]
+		s.mu.RLock()
+		for prefix, names := range s.prefixes {
+			st.prefixes[prefix] = len(names)
+			st.prefixNames += len(names)
+			for name, pid := range names {
+				if len(st.dump) >= dumpNames {
+					break
+				}
+				st.dump = append(st.dump,
+					fmt.Sprintf("%s/%v: %s", prefix, name, pid))
+			}
+		}
+		s.mu.RUnlock()
+	}
+
+	for i := range r.pids {
+		s := &r.pids[i]
+		s.mu.Lock()
+		st.pids += len(s.pids)
+		s.mu.Unlock()
+	}
+
+	sort.Slice(st.dump, func(i, j int) bool {
+		return fmt.Sprint(st.dump[i]) < fmt.Sprint(st.dump[j])
+	})
+
+	return st
+}
+
+//
+// Hash. Hash of names of other types agrees with comparison of keys of the
+//  map of the shard: pointers and channels are hashed by address, numbers by
+//  value, other names by type and Go-syntax representation, which shows
+//  nested pointers as addresses too
+//
+func regHash(name Term) uint64 {
+	switch n := name.(type) {
+	case string:
+		return regHashString(n)
+	case int:
+		return regHashUint(uint64(n))
+	case int32:
+		return regHashUint(uint64(n))
+	case int64:
+		return regHashUint(uint64(n))
+	case uint:
+		return regHashUint(uint64(n))
+	case uint32:
+		return regHashUint(uint64(n))
+	case uint64:
+		return regHashUint(n)
+	case Atom:
+		return regHashString(string(n))
+	case nil:
+		return 0
+	}
+
+	v := reflect.ValueOf(name)
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Chan, reflect.UnsafePointer:
+		return regHashUint(uint64(v.Pointer()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64:
+		return regHashUint(uint64(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64, reflect.Uintptr:
+		return regHashUint(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		// -0 and 0 are equal keys
+		return regHashUint(math.Float64bits(v.Float() + 0))
+	case reflect.String:
+		return regHashString(v.String())
+	}
+
+	return regHashString(fmt.Sprintf("%#v", name))
+}
+
+func regHashString(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return h.Sum64()
+}
+
+// splitmix64 finalizer
+func regHashUint(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
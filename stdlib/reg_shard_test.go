@@ -0,0 +1,256 @@
+package stdlib
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRegShardChurn(t *testing.T) {
+
+	e := NewEnv()
+
+	const workers = 8
+	const rounds = 200
+
+	pids := make([]*Pid, workers)
+	for i := range pids {
+		pid, err := e.GenServerStart(new(ts))
+		if err != nil {
+			t.Fatal(err)
+		}
+		pids[i] = pid
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			pid := pids[w]
+			for i := 0; i < rounds; i++ {
+				name := fmt.Sprintf("churn%d", i%16)
+				if err := pid.RegisterPrefix("churn", name); err != nil &&
+					!IsAlreadyRegError(err) {
+					t.Error(err)
+					return
+				}
+				if found, err := e.WhereisPrefix("churn", name); err == nil &&
+					found == pid {
+					if err = pid.UnregisterPrefix("churn", name); err != nil &&
+						!IsNotRegError(err) {
+						t.Error(err)
+						return
+					}
+				}
+
+				local := fmt.Sprintf("churn%d/%d", w, i)
+				if err := pid.Register(local); err != nil {
+					t.Error(err)
+					return
+				}
+				if found, err := e.Whereis(local); err != nil || found != pid {
+					t.Errorf("expected %s, actual %s, %v", pid, found, err)
+					return
+				}
+				if i%2 == 0 {
+					if err := pid.Unregister(local); err != nil {
+						t.Error(err)
+						return
+					}
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	// names of stopped processes are removed
+	for _, pid := range pids {
+		if err := pid.Stop(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if n, _ := e.WhereareCount("churn"); n != 0 {
+		t.Fatalf("expected no names with prefix, actual %d", n)
+	}
+	for w := 0; w < workers; w++ {
+		name := fmt.Sprintf("churn%d/%d", w, rounds-1)
+		if _, err := e.Whereis(name); !IsNotRegError(err) {
+			t.Fatalf("%s: expected NotRegError, actual %v", name, err)
+		}
+	}
+
+	st := e.eGs.reg.stat(0)
+	if st.names != 0 || st.prefixNames != 0 || st.pids != 0 {
+		t.Fatalf("expected empty registry, actual %+v", st)
+	}
+}
+
+func TestRegShardPointerName(t *testing.T) {
+
+	type key struct{ n int }
+
+	e := NewEnv()
+	pid, err := e.GenServerStart(new(ts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pid.Stop()
+
+	name := &key{1}
+	if err = pid.Register(name); err != nil {
+		t.Fatal(err)
+	}
+
+	// name is found by address after change of its content
+	name.n = 2
+	if found, err := e.Whereis(name); err != nil || found != pid {
+		t.Fatalf("expected %s, actual %s, %v", pid, found, err)
+	}
+	if _, err = e.Whereis(&key{2}); !IsNotRegError(err) {
+		t.Fatalf("expected NotRegError, actual %v", err)
+	}
+	if err = pid.Unregister(name); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRegShardHash(t *testing.T) {
+
+	type key struct {
+		node string
+		n    int
+	}
+	type id int
+
+	// equal names have equal hashes
+	zero := 0.0
+	for _, pair := range [][2]Term{
+		{key{"a", 1}, key{"a", 1}},
+		{id(7), id(7)},
+		{zero, -zero},
+		{[2]string{"a", "b"}, [2]string{"a", "b"}},
+	} {
+		if regHash(pair[0]) != regHash(pair[1]) {
+			t.Fatalf("expected equal hashes of %#v and %#v", pair[0], pair[1])
+		}
+	}
+
+	// names of other types are spread over shards
+	shards := make(map[uint64]bool)
+	for i := 0; i < 64; i++ {
+		shards[regHash(key{"a", i})%regShardCount] = true
+	}
+	if len(shards) < regShardCount/4 {
+		t.Fatalf("expected names spread over shards, actual %d shards",
+			len(shards))
+	}
+
+	e := NewEnv()
+	pid, err := e.GenServerStart(new(ts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pid.Stop()
+
+	if err = pid.Register(key{"srv", 1}); err != nil {
+		t.Fatal(err)
+	}
+	if found, err := e.Whereis(key{"srv", 1}); err != nil || found != pid {
+		t.Fatalf("expected %s, actual %s, %v", pid, found, err)
+	}
+}
+
+func BenchmarkRegisterUnregister(b *testing.B) {
+
+	e := NewEnv()
+	pid, err := e.GenServerStart(new(ts))
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer pid.Stop()
+
+	var n uint64
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		name := fmt.Sprintf("bench%d", atomic.AddUint64(&n, 1))
+		for pb.Next() {
+			if err := pid.Register(name); err != nil {
+				b.Error(err)
+				return
+			}
+			if err := pid.Unregister(name); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
+
+func BenchmarkWhereis(b *testing.B) {
+
+	e := NewEnv()
+	pid, err := e.GenServerStart(new(ts))
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer pid.Stop()
+
+	const names = 1024
+	for i := 0; i < names; i++ {
+		if err = pid.Register(i); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			if _, err := e.Whereis(i % names); err != nil {
+				b.Error(err)
+				return
+			}
+			i++
+		}
+	})
+}
+
+func BenchmarkWhereisChurn(b *testing.B) {
+
+	e := NewEnv()
+	pid, err := e.GenServerStart(new(ts))
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer pid.Stop()
+
+	var n uint64
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		w := atomic.AddUint64(&n, 1)
+		name := fmt.Sprintf("bench%d", w)
+		i := 0
+		for pb.Next() {
+			// every 4th goroutine churns names, others look up
+			if w%4 == 0 {
+				if err := pid.Register(name); err == nil {
+					_ = pid.Unregister(name)
+				}
+			} else {
+				_, _ = e.Whereis(fmt.Sprintf("bench%d", i%8))
+			}
+			i++
+		}
+	})
+}
@@ -0,0 +1,173 @@
+package stdlib
+
+//
+// Sequential trace. Trace token is set for the process with SeqTraceSet and
+// is carried by messages sent by the process with Call, CallSys and Cast.
+// Sender is the process of the current goroutine or is passed explicitly with
+// CallFrom, CallSysFrom and CastFrom. GenServerSys and GenProcSys.HandleSysMsg
+// set token of the message as the token of the process for the time the
+// message is handled, so messages sent while handling inherit the token. Each
+// message carrying the token gets new serial, *SeqTraceEvent events tagged
+// with the token are sent to the tracer of the token.
+//
+// Messages sent with Send and SendSys are not wrapped, so they do not carry
+// the token and do not emit events
+//
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+//
+// SeqTraceToken is a token of sequential trace
+//
+type SeqTraceToken struct {
+	// Label and ID identify the trace
+	Label Term
+	ID    uint64
+	// Serial of the message carrying the token
+	Serial uint64
+	// Prev is serial of the message handled by sender when the message was
+	//  sent, 0 for the first message
+	Prev uint64
+	// Span is the span context of sender callback
+	Span SpanContext
+
+	tracer  Tracer
+	counter *uint64
+}
+
+//
+// SeqTraceKind is a kind of sequential trace event
+//
+type SeqTraceKind int
+
+//
+// Sequential trace event kinds
+//
+const (
+	SeqTraceSend SeqTraceKind = iota
+	SeqTraceReceive
+)
+
+//
+// SeqTraceEvent is an event of sequential trace. Pid is the sender for send
+//  events and the receiver for receive events. From is nil for receive
+//  events
+//
+type SeqTraceEvent struct {
+	TraceEvent
+	Kind  SeqTraceKind
+	From  *Pid
+	To    *Pid
+	Token SeqTraceToken
+	Msg   Term
+}
+
+//
+// NewSeqTraceToken makes new trace with label. Events of the trace are sent
+//  to tracer t
+//
+func NewSeqTraceToken(label Term, t Tracer) *SeqTraceToken {
+	return &SeqTraceToken{
+		Label:   label,
+		ID:      atomic.AddUint64(&seqTraceID, 1),
+		tracer:  t,
+		counter: new(uint64),
+	}
+}
+
+//
+// SeqTraceSet sets token of the process for messages sent by the process.
+//  Nil token resets it
+//
+func (pid *Pid) SeqTraceSet(tok *SeqTraceToken) {
+	if pid == nil {
+		return
+	}
+	if tok != nil {
+		currentPidNeeded()
+	}
+	pid.seqTrace.Store(tok)
+}
+
+//
+// SeqTraceGet returns token of the process
+//
+func (pid *Pid) SeqTraceGet() *SeqTraceToken {
+	if pid == nil {
+		return nil
+	}
+	tok, _ := pid.seqTrace.Load().(*SeqTraceToken)
+	return tok
+}
+
+// ---------------------------------------------------------------------------
+// Locals
+// ---------------------------------------------------------------------------
+
+var seqTraceID uint64
+
+//
+// seqTraceSend returns token for the message sent by process from to pid,
+//  nil if sender has no token
+//
+func seqTraceSend(from, to *Pid, msg Term) *SeqTraceToken {
+
+	cur := from.SeqTraceGet()
+	if cur == nil {
+		return nil
+	}
+
+	tok := *cur
+	tok.Prev = cur.Serial
+	tok.Serial = atomic.AddUint64(tok.counter, 1)
+
+	tok.emit(SeqTraceSend, from, from, to, msg)
+
+	return &tok
+}
+
+//
+// seqTraceReceive sets token of the message as token of process pid.
+//  Returns token to restore by seqTraceDone
+//
+func seqTraceReceive(
+	pid *Pid, tok *SeqTraceToken, msg Term) *SeqTraceToken {
+
+	prev := pid.SeqTraceGet()
+	if tok == nil {
+		return prev
+	}
+
+	pid.SeqTraceSet(tok)
+	tok.emit(SeqTraceReceive, pid, nil, pid, msg)
+
+	return prev
+}
+
+//
+// seqTraceDone restores token of the process after the message is handled
+//
+func seqTraceDone(pid *Pid, prev *SeqTraceToken) {
+	if pid.SeqTraceGet() != prev {
+		pid.SeqTraceSet(prev)
+	}
+}
+
+func (tok *SeqTraceToken) emit(
+	kind SeqTraceKind, pid, from, to *Pid, msg Term) {
+
+	if tok.tracer == nil {
+		return
+	}
+	tok.tracer.Event(&SeqTraceEvent{
+		TraceEvent: TraceEvent{pid, time.Now()},
+		Kind:       kind,
+		From:       from,
+		To:         to,
+		Token:      *tok,
+		Msg:        msg,
+	})
+}
@@ -0,0 +1,281 @@
+package stdlib
+
+import (
+	"errors"
+	"testing"
+)
+
+type tsSeq struct {
+	GenServerSys
+
+	next *Pid
+	done chan Term
+}
+
+func (gs *tsSeq) HandleCall(req Term, from From) Term {
+	if gs.next == nil {
+		return gs.CallReply(req)
+	}
+	reply, err := gs.next.CallFrom(gs.Self(), req)
+	if err != nil {
+		return err
+	}
+	return gs.CallReply(reply)
+}
+
+func (gs *tsSeq) HandleCast(req Term) Term {
+	if gs.next == nil {
+		gs.done <- req
+	} else {
+		_ = gs.next.CastFrom(gs.Self(), req)
+	}
+	return gs.NoReply()
+}
+
+func TestSeqTrace(t *testing.T) {
+
+	done := make(chan Term, 1)
+
+	var next *Pid
+	pids := make([]*Pid, 3)
+	for i := range pids {
+		pid, err := GenServerStart(&tsSeq{next: next, done: done})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer pid.Stop()
+		pids[len(pids)-1-i] = pid
+		next = pid
+	}
+
+	// no token, no events
+	tr := new(traceTestCollector)
+	if _, err := pids[0].Call("untraced"); err != nil {
+		t.Fatal(err)
+	}
+
+	tok := NewSeqTraceToken("req", tr)
+	client, err := Spawn(testSeqClientFunc, tok, pids[0], done)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Stop()
+
+	got := map[Term]bool{<-done: true, <-done: true}
+	if !got["cast"] || !got["client"] {
+		t.Fatalf("expected cast and client, actual %v", got)
+	}
+
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	if len(tr.events) != 12 {
+		t.Fatalf("expected 12 events, actual %d: %v", len(tr.events), tr.events)
+	}
+	for _, pid := range pids {
+		if pid.SeqTraceGet() != nil {
+			t.Fatalf("expected no token of %s after handling", pid)
+		}
+	}
+
+	sends := make(map[uint64]*SeqTraceEvent)
+	receives := make(map[uint64]*SeqTraceEvent)
+	for _, evt := range tr.events {
+		evt := evt.(*SeqTraceEvent)
+		if evt.Token.ID != tok.ID || evt.Token.Label != "req" {
+			t.Fatalf("unexpected token %#v", evt.Token)
+		}
+		if evt.Kind == SeqTraceSend {
+			sends[evt.Token.Serial] = evt
+		} else {
+			receives[evt.Token.Serial] = evt
+		}
+	}
+
+	// reconstruct the chains
+	for serial, recv := range receives {
+		send, ok := sends[serial]
+		if !ok || !send.To.Equal(recv.Pid) {
+			t.Fatalf("no send for receive %#v", recv)
+		}
+		if send.Token.Prev == 0 {
+			if !send.From.Equal(client) || !recv.Pid.Equal(pids[0]) {
+				t.Fatalf("unexpected first message %#v", send)
+			}
+			continue
+		}
+		cause := receives[send.Token.Prev]
+		if cause == nil || !cause.Pid.Equal(send.From) {
+			t.Fatalf("no cause of message %#v", send)
+		}
+	}
+
+	for serial := uint64(1); serial <= 6; serial++ {
+		if _, ok := receives[serial]; !ok {
+			t.Fatalf("expected receive of message %d", serial)
+		}
+	}
+	if !receives[3].Pid.Equal(pids[2]) || !receives[6].Pid.Equal(pids[2]) {
+		t.Fatalf("expected last messages received by %s", pids[2])
+	}
+}
+
+//
+// testSeqClientFunc calls and casts with token, plain messages carry no token
+//
+func testSeqClientFunc(gp GenProc, args ...Term) error {
+
+	tok := args[0].(*SeqTraceToken)
+	to := args[1].(*Pid)
+	done := args[2].(chan Term)
+
+	self := gp.Self()
+	self.SeqTraceSet(tok)
+	if self.SeqTraceGet() != tok {
+		return errors.New("expected token of the process")
+	}
+
+	if _, err := to.CallFrom(self, "call"); err != nil {
+		return err
+	}
+	if err := to.SendFrom(self, "raw"); err != nil {
+		return err
+	}
+	if err := to.CastFrom(self, "cast"); err != nil {
+		return err
+	}
+
+	self.SeqTraceSet(nil)
+	done <- "client"
+
+	for m := range self.GetSysChannel() {
+		if err := gp.HandleSysMsg(m); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//
+// tsSeqPlain passes messages to the next server with plain Call and Cast
+//
+type tsSeqPlain struct {
+	GenServerSys
+
+	next *Pid
+	done chan Term
+}
+
+func (gs *tsSeqPlain) HandleCall(req Term, from From) Term {
+	if gs.next == nil {
+		return gs.CallReply(req)
+	}
+	reply, err := gs.next.Call(req)
+	if err != nil {
+		return err
+	}
+	return gs.CallReply(reply)
+}
+
+func (gs *tsSeqPlain) HandleCast(req Term) Term {
+	if gs.next == nil {
+		gs.done <- req
+	} else {
+		_ = gs.next.Cast(req)
+	}
+	return gs.NoReply()
+}
+
+func TestSeqTracePlain(t *testing.T) {
+
+	done := make(chan Term, 1)
+
+	var next *Pid
+	pids := make([]*Pid, 5)
+	for i := range pids {
+		pid, err := GenServerStart(&tsSeqPlain{next: next, done: done})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer pid.Stop()
+		pids[len(pids)-1-i] = pid
+		next = pid
+	}
+
+	tr := new(traceTestCollector)
+	tok := NewSeqTraceToken("plain", tr)
+
+	client, err := Spawn(func(gp GenProc, args ...Term) error {
+		self := gp.Self()
+		self.SeqTraceSet(tok)
+		if _, err := pids[0].Call("call"); err != nil {
+			return err
+		}
+		if err := pids[0].Cast("cast"); err != nil {
+			return err
+		}
+		self.SeqTraceSet(nil)
+
+		for m := range self.GetSysChannel() {
+			if err := gp.HandleSysMsg(m); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Stop()
+
+	if m := <-done; m != "cast" {
+		t.Fatalf("expected cast, actual %v", m)
+	}
+
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	sends := make(map[uint64]*SeqTraceEvent)
+	receives := make(map[uint64]*SeqTraceEvent)
+	for _, evt := range tr.events {
+		evt := evt.(*SeqTraceEvent)
+		if evt.Kind == SeqTraceSend {
+			sends[evt.Token.Serial] = evt
+		} else {
+			receives[evt.Token.Serial] = evt
+		}
+	}
+	if len(sends) != 2*len(pids) || len(receives) != 2*len(pids) {
+		t.Fatalf("expected %d sends and receives, actual %d, %d",
+			2*len(pids), len(sends), len(receives))
+	}
+
+	// walk each chain back from the last server to the client
+	for _, msg := range []Term{"call", "cast"} {
+		var last *SeqTraceEvent
+		for _, recv := range receives {
+			if recv.Pid.Equal(pids[len(pids)-1]) && recv.Msg == msg {
+				last = recv
+			}
+		}
+		if last == nil {
+			t.Fatalf("no %s received by the last server", msg)
+		}
+		for i := len(pids) - 1; i >= 0; i-- {
+			send := sends[last.Token.Serial]
+			if send == nil || !last.Pid.Equal(pids[i]) {
+				t.Fatalf("expected %s received by %s, actual %#v", msg, pids[i], last)
+			}
+			if i == 0 {
+				if !send.From.Equal(client) || send.Token.Prev != 0 {
+					t.Fatalf("expected %s sent by the client, actual %#v", msg, send)
+				}
+				break
+			}
+			if last = receives[send.Token.Prev]; last == nil {
+				t.Fatalf("no cause of %#v", send)
+			}
+		}
+	}
+}
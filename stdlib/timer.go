@@ -25,7 +25,9 @@ func (pid *Pid) SendAfter(data Term, timeoutMs uint32) *Timer {
 	timer := time.AfterFunc(
 		time.Duration(timeoutMs)*time.Millisecond,
 		func() {
-			_ = pid.Send(data)
+			if pid.Send(data) == nil {
+				pid.traceTimer(data)
+			}
 		},
 	)
 
@@ -7,24 +7,30 @@ package stdlib
 import (
 	"errors"
 	"fmt"
+	"sync"
 	"time"
 )
 
 var (
+	timerMu  sync.RWMutex
 	timerPid *Pid
 )
 
 //
 // TimerServerStart starts timer GenServer
 //
-func TimerServerStart() (err error) {
+func TimerServerStart() error {
 
-	timerPid, err = GenServerStart(
+	pid, err := GenServerStart(
 		new(tgs),
 		NewSpawnOpts().
 			WithName("timer_gs").
 			WithSpawnOrLocate())
 
+	timerMu.Lock()
+	timerPid = pid
+	timerMu.Unlock()
+
 	return err
 }
 
@@ -40,7 +46,7 @@ func TimerSendAfter(timeMs uint32, pid *Pid, msg Term) (Term, error) {
 	timeout := time.Duration(timeMs) * time.Millisecond
 	r := &timerAfterReq{timeout, &timerArgs{pid, msg}, time.Now()}
 
-	tref, err := timerPid.Call(r)
+	tref, err := timerServer().Call(r)
 	if err != nil {
 		return nil, err
 	}
@@ -60,7 +66,7 @@ func TimerSendInterval(timeMs uint32, pid *Pid, msg Term) (Term, error) {
 	timeout := time.Duration(timeMs) * time.Millisecond
 	r := &timerIntervalReq{timeout, &timerArgs{pid, msg}, time.Now(), timeMs}
 
-	tref, err := timerPid.Call(r)
+	tref, err := timerServer().Call(r)
 	if err != nil {
 		return nil, err
 	}
@@ -73,7 +79,7 @@ func TimerSendInterval(timeMs uint32, pid *Pid, msg Term) (Term, error) {
 //
 func TimerCancel(tref Term) error {
 
-	_, err := timerPid.Call(tref)
+	_, err := timerServer().Call(tref)
 
 	return err
 }
@@ -284,13 +290,24 @@ func (gs *tgs) cancelTimersByPid(pid *Pid) {
 }
 
 func (gs *tgs) send(op *timerArgs) {
-	_ = op.pid.Send(op.msg)
+	if op.pid.Send(op.msg) == nil {
+		op.pid.traceTimer(op.msg)
+	}
+}
+
+func timerServer() *Pid {
+	timerMu.RLock()
+	defer timerMu.RUnlock()
+	return timerPid
 }
 
 //
 // test
 //
 func timerServerStop() {
-	_ = timerPid.Stop()
+	_ = timerServer().Stop()
+
+	timerMu.Lock()
 	timerPid = nil
+	timerMu.Unlock()
 }
@@ -15,18 +15,83 @@ import (
 func TraceToConsole() TracerFunc {
 	return func(events ...Term) {
 		for _, evt := range events {
-			switch evt := evt.(type) {
+			if s := FormatTraceEvent(evt); s != "" {
+				fmt.Println(s)
+			}
+		}
+	}
+}
 
-			case *Call:
-				fmt.Printf("%s %s call -> %s(%#v)\n",
-					evt.Time.Truncate(time.Microsecond),
-					evt.Pid, evt.Tag, evt.Arg)
+//
+// FormatTraceEvent returns one line presentation of the trace event.
+//  Returns empty string for unknown events
+//
+func FormatTraceEvent(evt Term) string {
 
-			case *CallResult:
-				fmt.Printf("%s %s call <- %s(%#v)=%#v, %s\n",
-					evt.Time.Truncate(time.Microsecond),
-					evt.Pid, evt.Tag, evt.Arg, evt.Result, evt.Duration)
-			}
+	switch evt := evt.(type) {
+
+	case *Call:
+		return fmt.Sprintf("%s %s call -> %s(%#v)",
+			evt.Time.Truncate(time.Microsecond),
+			evt.Pid, evt.Tag, evt.Arg)
+
+	case *CallResult:
+		return fmt.Sprintf("%s %s call <- %s(%#v)=%#v, %s",
+			evt.Time.Truncate(time.Microsecond),
+			evt.Pid, evt.Tag, evt.Arg, evt.Result, evt.Duration)
+
+	case *SendEvent:
+		return formatTraceEvent(evt.TraceEvent, "send", evt.To, evt.Msg)
+
+	case *ReceiveEvent:
+		return formatTraceEvent(evt.TraceEvent, "receive", evt.Msg)
+
+	case *SpawnEvent:
+		return formatTraceEvent(evt.TraceEvent, "spawn", evt.Prefix, evt.Name)
+
+	case *ExitEvent:
+		return formatTraceEvent(evt.TraceEvent, "exit", evt.Reason)
+
+	case *LinkEvent:
+		return formatTraceEvent(evt.TraceEvent, "link", evt.To)
+
+	case *UnlinkEvent:
+		return formatTraceEvent(evt.TraceEvent, "unlink", evt.To)
+
+	case *MonitorEvent:
+		return formatTraceEvent(evt.TraceEvent, "monitor", evt.Target)
+
+	case *DemonitorEvent:
+		return formatTraceEvent(evt.TraceEvent, "demonitor", evt.Target,
+			evt.Reason)
+
+	case *RegisterEvent:
+		return formatTraceEvent(
+			evt.TraceEvent, "register", evt.Prefix, evt.Name)
+
+	case *UnregisterEvent:
+		return formatTraceEvent(
+			evt.TraceEvent, "unregister", evt.Prefix, evt.Name)
+
+	case *TimerEvent:
+		return formatTraceEvent(evt.TraceEvent, "timer", evt.Msg)
+
+	case *CrashEvent:
+		return formatTraceEvent(evt.TraceEvent, "crash", evt.Reason)
+
+	case *SeqTraceEvent:
+		kind := "seq_send"
+		if evt.Kind == SeqTraceReceive {
+			kind = "seq_receive"
 		}
+		return formatTraceEvent(evt.TraceEvent, kind, evt.Token.Label,
+			evt.Token.Serial, evt.Token.Prev, evt.From, evt.To, evt.Msg)
 	}
+
+	return ""
+}
+
+func formatTraceEvent(evt TraceEvent, kind string, args ...Term) string {
+	return fmt.Sprintf("%s %s %s %#v",
+		evt.Time.Truncate(time.Microsecond), evt.Pid, kind, args)
 }
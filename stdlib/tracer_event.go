@@ -0,0 +1,269 @@
+package stdlib
+
+//
+// Process trace events. Kinds of events emitted for the process are selected
+// by trace flags of the process. *Call, *CallResult, *CrashEvent and
+// *ExitEvent events are emitted regardless of trace flags.
+//
+// Events of the process may be emitted from other goroutines, e.g.
+// *TimerEvent from the timer goroutine. Sends are traced for the sender, the
+// process of the goroutine or the process passed to SendFrom, CastFrom,
+// CallFrom and CallSysFrom, so messages sent from goroutines that are not
+// processes are not traced
+//
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+//
+// TraceFlags is a set of kinds of trace events emitted for the process
+//
+type TraceFlags uint32
+
+//
+// Trace flags
+//
+const (
+	// TraceFlagSend emits *SendEvent when the process sends message
+	TraceFlagSend TraceFlags = 1 << iota
+	// TraceFlagReceive emits *ReceiveEvent when the process takes message
+	TraceFlagReceive
+	// TraceFlagProcs emits *SpawnEvent, *ExitEvent is emitted without flags
+	TraceFlagProcs
+	// TraceFlagLinks emits *LinkEvent and *UnlinkEvent
+	TraceFlagLinks
+	// TraceFlagMonitors emits *MonitorEvent and *DemonitorEvent
+	TraceFlagMonitors
+	// TraceFlagNames emits *RegisterEvent and *UnregisterEvent
+	TraceFlagNames
+	// TraceFlagTimers emits *TimerEvent
+	TraceFlagTimers
+	// TraceFlagSpans sets new span context for each callback of the process,
+	//  see SpanTracer
+	TraceFlagSpans
+
+	// TraceFlagAll emits all kinds of events
+	TraceFlagAll = TraceFlagSend | TraceFlagReceive | TraceFlagProcs |
+		TraceFlagLinks | TraceFlagMonitors | TraceFlagNames | TraceFlagTimers |
+		TraceFlagSpans
+)
+
+//
+// TraceEvent is a common part of process trace events
+//
+type TraceEvent struct {
+	Pid  *Pid
+	Time time.Time
+}
+
+//
+// SendEvent is fired when message sent by the process is put to the channel
+//  of the receiver To
+//
+type SendEvent struct {
+	TraceEvent
+	To  *Pid
+	Msg Term
+	Sys bool
+}
+
+//
+// ReceiveEvent is fired when the process takes message from its channel
+//
+type ReceiveEvent struct {
+	TraceEvent
+	Msg Term
+	Sys bool
+}
+
+//
+// SpawnEvent is fired when the process starts
+//
+type SpawnEvent struct {
+	TraceEvent
+	Prefix string
+	Name   Term
+}
+
+//
+// ExitEvent is fired when the process exits
+//
+type ExitEvent struct {
+	TraceEvent
+	Reason string
+}
+
+//
+// LinkEvent is fired when link to other process is set
+//
+type LinkEvent struct {
+	TraceEvent
+	To *Pid
+}
+
+//
+// UnlinkEvent is fired when link to other process is removed
+//
+type UnlinkEvent struct {
+	TraceEvent
+	To *Pid
+}
+
+//
+// MonitorEvent is fired when the process starts to monitor other process
+//
+type MonitorEvent struct {
+	TraceEvent
+	Target *Pid
+	Ref    Ref
+}
+
+//
+// DemonitorEvent is fired when monitor set by the process is removed.
+//  Reason is not empty if monitored process died
+//
+type DemonitorEvent struct {
+	TraceEvent
+	Target *Pid
+	Ref    Ref
+	Reason string
+}
+
+//
+// RegisterEvent is fired when name of the process is registered
+//
+type RegisterEvent struct {
+	TraceEvent
+	Prefix string
+	Name   Term
+}
+
+//
+// UnregisterEvent is fired when name of the process is unregistered
+//
+type UnregisterEvent struct {
+	TraceEvent
+	Prefix string
+	Name   Term
+}
+
+//
+// TimerEvent is fired when timer sends message to the process
+//
+type TimerEvent struct {
+	TraceEvent
+	Msg Term
+}
+
+//
+// CrashEvent is fired when GenServerSys recovers a panic. Tracers may attach
+//  recent events of the process to the crash report
+//
+type CrashEvent struct {
+	TraceEvent
+	Reason string
+	Stack  []byte
+	Recent []Term
+}
+
+//
+// TraceEventPid returns the process of the trace event or nil for unknown
+//  events
+//
+func TraceEventPid(evt Term) *Pid {
+	if evt, ok := evt.(interface{ tracePid() *Pid }); ok {
+		return evt.tracePid()
+	}
+	return nil
+}
+
+func (evt *TraceEvent) tracePid() *Pid {
+	return evt.Pid
+}
+
+func (evt *Call) tracePid() *Pid {
+	return evt.Pid
+}
+
+//
+// TraceFlags returns trace flags of the process
+//
+func (pid *Pid) TraceFlags() TraceFlags {
+	if pid == nil {
+		return 0
+	}
+	if pt := pid.loadTrace(); pt != nil {
+		return pt.flags
+	}
+	return 0
+}
+
+// ---------------------------------------------------------------------------
+// Locals
+// ---------------------------------------------------------------------------
+
+//
+// pidTrace is a tracer of the process accessible from other goroutines
+//
+type pidTrace struct {
+	tracer Tracer
+	flags  TraceFlags
+}
+
+func (pid *Pid) loadTrace() *pidTrace {
+	pt, _ := pid.trace.Load().(*pidTrace)
+	return pt
+}
+
+func (pid *Pid) setTrace(t Tracer, flags TraceFlags) {
+	if t != nil && flags&TraceFlagSend != 0 {
+		currentPidNeeded()
+	}
+	pid.trace.Store(&pidTrace{t, flags})
+}
+
+//
+// tracerFor returns tracer of the process if flag is set
+//
+func (pid *Pid) tracerFor(flag TraceFlags) Tracer {
+	if pid == nil {
+		return nil
+	}
+	pt := pid.loadTrace()
+	if pt == nil || pt.tracer == nil || pt.flags&flag == 0 {
+		return nil
+	}
+	return pt.tracer
+}
+
+func (pid *Pid) traceEvent() TraceEvent {
+	return TraceEvent{pid, time.Now()}
+}
+
+//
+// traceSend emits *SendEvent, messages sent by the tracer while it handles
+//  the event are not traced
+//
+func (pid *Pid) traceSend(to *Pid, msg Term, sys bool) {
+	t := pid.tracerFor(TraceFlagSend)
+	if t == nil || !atomic.CompareAndSwapInt32(&pid.tracingSend, 0, 1) {
+		return
+	}
+	t.Event(&SendEvent{pid.traceEvent(), to, msg, sys})
+	atomic.StoreInt32(&pid.tracingSend, 0)
+}
+
+func (pid *Pid) traceReceive(msg Term, sys bool) {
+	if t := pid.tracerFor(TraceFlagReceive); t != nil {
+		t.Event(&ReceiveEvent{pid.traceEvent(), msg, sys})
+	}
+}
+
+func (pid *Pid) traceTimer(msg Term) {
+	if t := pid.tracerFor(TraceFlagTimers); t != nil {
+		t.Event(&TimerEvent{pid.traceEvent(), msg})
+	}
+}
+
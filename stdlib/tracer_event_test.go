@@ -0,0 +1,290 @@
+package stdlib
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTraceEvents(t *testing.T) {
+
+	e := NewEnv()
+	tr := new(traceTestCollector)
+
+	target, err := e.GenServerStart(new(GenServerSys))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts := NewSpawnOpts().
+		WithName("traced").
+		WithTracer(tr).
+		WithTraceFlags(TraceFlagAll)
+
+	pid, err := e.SpawnWithOpts(testTraceFunc, opts, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = pid.Send("hello"); err != nil {
+		t.Fatal(err)
+	}
+
+	tr.waitFor(t, "*stdlib.SendEvent", 4)
+	tr.waitFor(t, "*stdlib.DemonitorEvent", 1)
+
+	if err = target.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	tr.waitFor(t, "*stdlib.DemonitorEvent", 2)
+
+	if err = pid.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	tr.waitFor(t, "*stdlib.UnregisterEvent", 1)
+
+	expected := map[string]int{
+		"*stdlib.RegisterEvent":   1,
+		"*stdlib.SpawnEvent":      1,
+		"*stdlib.MonitorEvent":    2,
+		"*stdlib.DemonitorEvent":  2,
+		"*stdlib.LinkEvent":       1,
+		"*stdlib.UnlinkEvent":     1,
+		// link and unlink requests are sys messages
+		"*stdlib.SendEvent":       4,
+		"*stdlib.ReceiveEvent":    3,
+		"*stdlib.ExitEvent":       1,
+		"*stdlib.UnregisterEvent": 1,
+	}
+	for kind, n := range expected {
+		if c := tr.count(kind); c != n {
+			t.Fatalf("expected %d %s events, actual %d: %v",
+				n, kind, c, tr.kinds())
+		}
+	}
+
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	for _, evt := range tr.events {
+		switch evt := evt.(type) {
+		case *DemonitorEvent:
+			if !evt.Target.Equal(target) {
+				t.Fatalf("expected demonitor %s, actual %s", target, evt.Target)
+			}
+		case *SendEvent:
+			if !evt.Pid.Equal(pid) {
+				t.Fatalf("expected send of %s, actual %s", pid, evt.Pid)
+			}
+			if (evt.Msg == "info" || evt.Msg == "call") && !evt.To.Equal(target) {
+				t.Fatalf("expected send %s -> %s, actual %s -> %s",
+					pid, target, evt.Pid, evt.To)
+			}
+		case *ExitEvent:
+			if evt.Reason != ExitNormal {
+				t.Fatalf("expected exit reason '%s', actual '%s'",
+					ExitNormal, evt.Reason)
+			}
+		case *SpawnEvent:
+			if evt.Name != "traced" || !evt.Pid.Equal(pid) {
+				t.Fatalf("unexpected spawn event: %#v", evt)
+			}
+		}
+	}
+}
+
+func TestTraceTimerEvent(t *testing.T) {
+
+	tr := new(traceTestCollector)
+
+	pid, err := GenServerStartOpts(new(GenServerSys), NewSpawnOpts().
+		WithTracer(tr).
+		WithTraceFlags(TraceFlagReceive|TraceFlagTimers))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pid.SendAfter("tick", 1)
+
+	tr.waitUntil(t, "receive of tick", func(evt Term) bool {
+		evt2, ok := evt.(*ReceiveEvent)
+		return ok && evt2.Msg == "tick"
+	})
+	if n := tr.count("*stdlib.TimerEvent"); n != 1 {
+		t.Fatalf("expected 1 timer event, actual %d", n)
+	}
+
+	if err = pid.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	// timer of stopped process sends nothing
+	pid.SendAfter("tick", 1)
+	time.Sleep(20 * time.Millisecond)
+	if n := tr.count("*stdlib.TimerEvent"); n != 1 {
+		t.Fatalf("expected 1 timer event, actual %d", n)
+	}
+}
+
+func TestTraceEventsNoFlags(t *testing.T) {
+
+	tr := new(traceTestCollector)
+
+	pid, err := GenServerStartOpts(
+		new(GenServerSys), NewSpawnOpts().WithTracer(tr))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = pid.Call("alive"); err != nil {
+		t.Fatal(err)
+	}
+	if err = pid.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	// exit is traced without flags
+	tr.waitFor(t, "*stdlib.ExitEvent", 1)
+
+	for _, kind := range tr.kinds() {
+		if kind != "*stdlib.Call" && kind != "*stdlib.CallResult" &&
+			kind != "*stdlib.ExitEvent" {
+			t.Fatalf("unexpected event %s", kind)
+		}
+	}
+}
+
+//
+// Locals
+//
+func testTraceFunc(gp GenProc, args ...Term) error {
+
+	target := args[0].(*Pid)
+
+	ref := gp.MonitorProcessPid(target)
+	gp.DemonitorProcessPid(ref)
+	gp.MonitorProcessPid(target)
+
+	gp.Link(target)
+	gp.Unlink(target)
+
+	if err := target.Send("info"); err != nil {
+		return err
+	}
+	if _, err := target.Call("call"); err != nil {
+		return err
+	}
+
+	for m := range gp.Self().GetSysChannel() {
+		if err := gp.HandleSysMsg(m); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type traceTestCollector struct {
+	mu     sync.Mutex
+	events []Term
+}
+
+func (c *traceTestCollector) Event(events ...Term) {
+	c.mu.Lock()
+	c.events = append(c.events, events...)
+	c.mu.Unlock()
+}
+
+func (c *traceTestCollector) kinds() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	kinds := make([]string, len(c.events))
+	for i, evt := range c.events {
+		kinds[i] = fmt.Sprintf("%T", evt)
+	}
+	return kinds
+}
+
+func (c *traceTestCollector) count(kind string) int {
+	n := 0
+	for _, k := range c.kinds() {
+		if k == kind {
+			n++
+		}
+	}
+	return n
+}
+
+func (c *traceTestCollector) waitUntil(
+	t *testing.T, what string, f func(evt Term) bool) {
+
+	t.Helper()
+
+	for i := 0; i < 100; i++ {
+		c.mu.Lock()
+		for _, evt := range c.events {
+			if f(evt) {
+				c.mu.Unlock()
+				return
+			}
+		}
+		c.mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("expected %s, actual %v", what, c.kinds())
+}
+
+func (c *traceTestCollector) waitFor(t *testing.T, kind string, n int) {
+	t.Helper()
+
+	for i := 0; i < 100; i++ {
+		if c.count(kind) >= n {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("expected %d %s events, actual %v", n, kind, c.kinds())
+}
+
+func TestTraceSendOfTracer(t *testing.T) {
+
+	e := NewEnv()
+
+	sink, err := e.GenServerStart(new(GenServerSys))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Stop()
+
+	// tracer sending send events to other process is not traced recursively
+	tr := new(traceTestCollector)
+	forward := TracerChain(tr, TracerFunc(func(events ...Term) {
+		for _, evt := range events {
+			if _, ok := evt.(*SendEvent); ok {
+				_ = sink.Send(evt)
+			}
+		}
+	}))
+
+	target, err := e.GenServerStart(new(GenServerSys))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer target.Stop()
+
+	pid, err := e.SpawnWithOpts(testTraceFunc, NewSpawnOpts().
+		WithTracer(forward).
+		WithTraceFlags(TraceFlagSend), target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pid.Stop()
+
+	tr.waitFor(t, "*stdlib.SendEvent", 4)
+	time.Sleep(10 * time.Millisecond)
+
+	if n := tr.count("*stdlib.SendEvent"); n != 4 {
+		t.Fatalf("expected 4 send events, actual %d", n)
+	}
+}
@@ -0,0 +1,259 @@
+package stdlib
+
+//
+// Sampling and filtering tracers. Each of them wraps next tracer and passes
+// it part of events, so they can be used as members of TracerChain:
+//
+//	TracerChain(
+//		TraceFilter(TraceToConsole(), TraceTags("HandleCall"),
+//			TraceMinDuration(100*time.Millisecond)),
+//		metrics)
+//
+// Sampling decisions are made for each event, so *Call and *CallResult events
+// of the same callback can be split by sampling. *CallResult contains
+// argument and duration of the callback, so it is enough to sample results
+//
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+//
+// TraceFilterFunc reports whether the event is passed to next tracer
+//
+type TraceFilterFunc func(evt Term) bool
+
+//
+// TraceFilter passes to next tracer events accepted by all filters
+//
+func TraceFilter(next Tracer, filters ...TraceFilterFunc) Tracer {
+
+	return TracerFunc(func(events ...Term) {
+		passed := make([]Term, 0, len(events))
+	next:
+		for _, evt := range events {
+			for _, f := range filters {
+				if !f(evt) {
+					continue next
+				}
+			}
+			passed = append(passed, evt)
+		}
+		if len(passed) > 0 {
+			next.Event(passed...)
+		}
+	})
+}
+
+//
+// TraceAny makes filter accepting events accepted by any of filters
+//
+func TraceAny(filters ...TraceFilterFunc) TraceFilterFunc {
+	return func(evt Term) bool {
+		for _, f := range filters {
+			if f(evt) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+//
+// TraceSample passes to next tracer each event with probability rate
+//  in range [0, 1]
+//
+func TraceSample(next Tracer, rate float64) Tracer {
+	return TraceFilter(next, TraceSampleFilter(rate))
+}
+
+//
+// TraceSampleFilter makes filter accepting events with probability rate
+//
+func TraceSampleFilter(rate float64) TraceFilterFunc {
+
+	var mu sync.Mutex
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	return func(evt Term) bool {
+		if rate >= 1 {
+			return true
+		}
+		if rate <= 0 {
+			return false
+		}
+		mu.Lock()
+		defer mu.Unlock()
+
+		return r.Float64() < rate
+	}
+}
+
+//
+// TraceRateLimit passes to next tracer at most perSecond events per second.
+//  Bursts up to perSecond events are allowed
+//
+func TraceRateLimit(next Tracer, perSecond int) Tracer {
+	return TraceFilter(next, TraceRateLimitFilter(perSecond))
+}
+
+//
+// TraceRateLimitFilter makes filter accepting at most perSecond events per
+//  second
+//
+func TraceRateLimitFilter(perSecond int) TraceFilterFunc {
+
+	var mu sync.Mutex
+	tokens := float64(perSecond)
+	last := time.Now()
+
+	return func(evt Term) bool {
+		mu.Lock()
+		defer mu.Unlock()
+
+		now := time.Now()
+		tokens += now.Sub(last).Seconds() * float64(perSecond)
+		if tokens > float64(perSecond) {
+			tokens = float64(perSecond)
+		}
+		last = now
+
+		if tokens < 1 {
+			return false
+		}
+		tokens--
+		return true
+	}
+}
+
+//
+// TraceTags makes filter accepting *Call and *CallResult events with one of
+//  tags, like "HandleCall"
+//
+func TraceTags(tags ...string) TraceFilterFunc {
+
+	set := make(map[string]struct{}, len(tags))
+	for _, tag := range tags {
+		set[tag] = struct{}{}
+	}
+
+	return func(evt Term) bool {
+		var tag string
+		switch evt := evt.(type) {
+		case *Call:
+			tag = evt.Tag
+		case *CallResult:
+			tag = evt.Tag
+		default:
+			return false
+		}
+		_, ok := set[tag]
+		return ok
+	}
+}
+
+//
+// TraceMinDuration makes filter accepting *CallResult events of callbacks
+//  lasted at least d
+//
+func TraceMinDuration(d time.Duration) TraceFilterFunc {
+	return func(evt Term) bool {
+		res, ok := evt.(*CallResult)
+		return ok && res.Duration >= d
+	}
+}
+
+//
+// TraceMsgTypes makes filter accepting events with message or callback
+//  argument of the same type as one of samples. Events without message are
+//  rejected
+//
+func TraceMsgTypes(samples ...Term) TraceFilterFunc {
+
+	types := make(map[reflect.Type]struct{}, len(samples))
+	for _, sample := range samples {
+		types[reflect.TypeOf(sample)] = struct{}{}
+	}
+
+	return func(evt Term) bool {
+		msg, ok := traceEventMsg(evt)
+		if !ok {
+			return false
+		}
+		_, ok = types[reflect.TypeOf(msg)]
+		return ok
+	}
+}
+
+//
+// TracePids makes filter accepting events of processes
+//
+func TracePids(pids ...*Pid) TraceFilterFunc {
+
+	set := make(map[*Pid]struct{}, len(pids))
+	for _, pid := range pids {
+		set[pid] = struct{}{}
+	}
+
+	return func(evt Term) bool {
+		_, ok := set[TraceEventPid(evt)]
+		return ok
+	}
+}
+
+//
+// TracePrefix makes filter accepting events of processes spawned with
+//  registered name with prefix. Prefix ending with '*' matches all prefixes
+//  starting with it
+//
+func TracePrefix(prefix string) TraceFilterFunc {
+
+	match := func(p string) bool { return p == prefix }
+	if strings.HasSuffix(prefix, "*") {
+		start := strings.TrimSuffix(prefix, "*")
+		match = func(p string) bool { return strings.HasPrefix(p, start) }
+	}
+
+	return func(evt Term) bool {
+		pid := TraceEventPid(evt)
+		return pid != nil && pid.spawnName != "" && match(pid.spawnPrefix)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Locals
+// ---------------------------------------------------------------------------
+
+func traceEventMsg(evt Term) (Term, bool) {
+
+	switch evt := evt.(type) {
+	case *Call:
+		return traceCallMsg(evt.Arg), true
+	case *CallResult:
+		return traceCallMsg(evt.Arg), true
+	case *SendEvent:
+		return evt.Msg, true
+	case *ReceiveEvent:
+		return evt.Msg, true
+	case *TimerEvent:
+		return evt.Msg, true
+	case *SeqTraceEvent:
+		return evt.Msg, true
+	}
+
+	return nil, false
+}
+
+//
+// traceCallMsg returns message boxed in requests of HandleSysMsg
+//
+func traceCallMsg(arg Term) Term {
+	if r, ok := arg.(*SysReq); ok {
+		return r.Data
+	}
+	return arg
+}
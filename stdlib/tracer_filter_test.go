@@ -0,0 +1,148 @@
+package stdlib
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTraceFilter(t *testing.T) {
+
+	e := NewEnv()
+	tr := new(traceTestCollector)
+
+	opts := NewSpawnOpts().
+		WithPrefix("filter").
+		WithName("srv").
+		WithTracer(TracerChain(
+			TraceFilter(tr, TraceTags(traceFuncDoCall), TracePrefix("filt*"))))
+
+	pid, err := e.GenServerStartOpts(new(ts), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pid.Stop()
+
+	for i := 0; i < 3; i++ {
+		if _, err = pid.Call("ping"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = pid.Cast("ping"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = pid.Call("ping"); err != nil {
+		t.Fatal(err)
+	}
+
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	if len(tr.events) != 8 {
+		t.Fatalf("expected 8 events, actual %d: %v", len(tr.events), tr.events)
+	}
+	for _, evt := range tr.events {
+		switch evt := evt.(type) {
+		case *Call:
+			if evt.Tag != traceFuncDoCall {
+				t.Fatalf("unexpected tag %s", evt.Tag)
+			}
+		case *CallResult:
+			if evt.Tag != traceFuncDoCall {
+				t.Fatalf("unexpected tag %s", evt.Tag)
+			}
+		default:
+			t.Fatalf("unexpected event %#v", evt)
+		}
+	}
+}
+
+func TestTraceFilterFuncs(t *testing.T) {
+
+	e := NewEnv()
+	pid, err := e.GenServerStartOpts(new(ts),
+		NewSpawnOpts().WithPrefix("pfx").WithName("one"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pid.Stop()
+
+	other, err := e.GenServerStart(new(ts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Stop()
+
+	now := time.Now()
+	fast := &CallResult{Call{pid, &now, traceFuncDoCall, "a"}, nil,
+		time.Millisecond}
+	slow := &CallResult{Call{other, &now, traceFuncDoCast, 1}, nil,
+		time.Second}
+	send := &SendEvent{TraceEvent{pid, now}, other, "msg", false}
+	sysCall := &Call{other, &now, traceFuncHSM, &SysReq{Data: 1}}
+
+	cases := []struct {
+		name   string
+		filter TraceFilterFunc
+		pass   []bool
+	}{
+		{"tags", TraceTags(traceFuncDoCall, traceFuncHSM),
+			[]bool{true, false, false, true}},
+		{"min duration", TraceMinDuration(100 * time.Millisecond),
+			[]bool{false, true, false, false}},
+		{"msg types", TraceMsgTypes(0),
+			[]bool{false, true, false, true}},
+		{"pids", TracePids(other),
+			[]bool{false, true, false, true}},
+		{"prefix", TracePrefix("pfx"),
+			[]bool{true, false, true, false}},
+		{"prefix mismatch", TracePrefix("pf"),
+			[]bool{false, false, false, false}},
+		{"any", TraceAny(TraceMinDuration(time.Second), TracePids(pid)),
+			[]bool{true, true, true, false}},
+	}
+
+	for _, c := range cases {
+		for i, evt := range []Term{fast, slow, send, sysCall} {
+			if c.filter(evt) != c.pass[i] {
+				t.Fatalf("%s: expected %v for event %d", c.name, c.pass[i], i)
+			}
+		}
+	}
+}
+
+func TestTraceSampling(t *testing.T) {
+
+	now := time.Now()
+	evt := &Call{nil, &now, traceFuncDoCall, nil}
+
+	count := func(wrap func(next Tracer) Tracer, n int) int {
+		tr := new(traceTestCollector)
+		tracer := wrap(tr)
+		for i := 0; i < n; i++ {
+			tracer.Event(evt)
+		}
+		return len(tr.events)
+	}
+
+	if n := count(func(next Tracer) Tracer {
+		return TraceSample(next, 0)
+	}, 100); n != 0 {
+		t.Fatalf("expected no events, actual %d", n)
+	}
+	if n := count(func(next Tracer) Tracer {
+		return TraceSample(next, 1)
+	}, 100); n != 100 {
+		t.Fatalf("expected 100 events, actual %d", n)
+	}
+	if n := count(func(next Tracer) Tracer {
+		return TraceSample(next, 0.5)
+	}, 10000); n < 4000 || n > 6000 {
+		t.Fatalf("expected about 5000 events, actual %d", n)
+	}
+
+	if n := count(func(next Tracer) Tracer {
+		return TraceRateLimit(next, 10)
+	}, 100); n < 10 || n > 11 {
+		t.Fatalf("expected 10 events, actual %d", n)
+	}
+}
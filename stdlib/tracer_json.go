@@ -0,0 +1,452 @@
+package stdlib
+
+//
+// JSON Lines tracer. Writes one JSON object per *Call and *CallResult event
+// to io.Writer or rotating file. Events are encoded by the traced process,
+// as its terms may change after the event, and the lines are buffered in the
+// channel of the tracer process, so slow writer does not block traced process.
+// Events are dropped if the channel is full
+//
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+//
+// TraceTermFormatter renders term to a value encoded to JSON
+//
+type TraceTermFormatter func(t Term) interface{}
+
+//
+// TraceFormatSprint renders term with fmt %+v verb. Result is truncated to
+//  maxLen bytes if maxLen > 0
+//
+func TraceFormatSprint(maxLen int) TraceTermFormatter {
+	return func(t Term) interface{} {
+		s := fmt.Sprintf("%+v", t)
+		if maxLen > 0 && len(s) > maxLen {
+			s = s[:maxLen] + "..."
+		}
+		return s
+	}
+}
+
+//
+// TraceFormatJSON renders term as is if it can be encoded to JSON, otherwise
+//  with fmt %+v verb
+//
+func TraceFormatJSON() TraceTermFormatter {
+	return func(t Term) interface{} {
+		data, err := json.Marshal(t)
+		if err != nil {
+			return fmt.Sprintf("%+v", t)
+		}
+		return json.RawMessage(data)
+	}
+}
+
+//
+// TraceJSONOpts is the structure to hold options of JSON Lines tracer
+//
+type TraceJSONOpts struct {
+	formatter TraceTermFormatter
+	bufSize   int
+	maxSize   int64
+	maxFiles  int
+}
+
+//
+// NewTraceJSONOpts makes options object and returns object to manipulate
+//
+func NewTraceJSONOpts() *TraceJSONOpts {
+	return new(TraceJSONOpts)
+}
+
+//
+// WithFormatter sets formatter of event arguments and results. Formatter is
+//  called by the traced process. Default is TraceFormatJSON()
+//
+func (op *TraceJSONOpts) WithFormatter(f TraceTermFormatter) *TraceJSONOpts {
+
+	op.formatter = f
+
+	return op
+}
+
+//
+// WithBufferSize sets count of event batches buffered in the tracer process
+//  channel. Default is 1024
+//
+func (op *TraceJSONOpts) WithBufferSize(size int) *TraceJSONOpts {
+
+	op.bufSize = size
+
+	return op
+}
+
+//
+// WithMaxFileSize sets size of the file in bytes after which the file is
+//  rotated. Default is 0, the file is not rotated
+//
+func (op *TraceJSONOpts) WithMaxFileSize(size int64) *TraceJSONOpts {
+
+	op.maxSize = size
+
+	return op
+}
+
+//
+// WithMaxFiles sets count of rotated files to keep. Default is 5
+//
+func (op *TraceJSONOpts) WithMaxFiles(n int) *TraceJSONOpts {
+
+	op.maxFiles = n
+
+	return op
+}
+
+//
+// TraceJSON is a JSON Lines tracer
+//
+type TraceJSON struct {
+	pid       *Pid
+	formatter TraceTermFormatter
+	dropped   uint64
+}
+
+//
+// TraceToJSON starts JSON Lines tracer writing to w
+//
+func TraceToJSON(w io.Writer, opts *TraceJSONOpts) (*TraceJSON, error) {
+	return startTraceJSON(&traceJSONWriter{bufio.NewWriter(w)}, opts)
+}
+
+//
+// TraceToJSONFile starts JSON Lines tracer writing to file. The file is
+//  rotated to path.1, path.2, ... when its size exceeds max file size
+//
+func TraceToJSONFile(path string, opts *TraceJSONOpts) (*TraceJSON, error) {
+
+	if opts == nil {
+		opts = NewTraceJSONOpts()
+	}
+
+	rf, err := openTraceRotateFile(path, opts.maxSize, opts.maxFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	tj, err := startTraceJSON(rf, opts)
+	if err != nil {
+		rf.Close()
+		return nil, err
+	}
+
+	return tj, nil
+}
+
+//
+// Event implements Tracer
+//
+func (tj *TraceJSON) Event(events ...Term) {
+
+	var lines traceJSONLines
+	for _, evt := range events {
+		data, err := tj.encode(evt)
+		if err != nil {
+			if lines.err == nil {
+				lines.err = err
+			}
+			continue
+		}
+		if data != nil {
+			lines.data = append(lines.data, data)
+		}
+	}
+	if len(lines.data) == 0 && lines.err == nil {
+		return
+	}
+
+	if err := tj.pid.Send(lines); err != nil {
+		atomic.AddUint64(&tj.dropped, uint64(len(lines.data)))
+	}
+}
+
+//
+// Dropped returns count of events dropped because the tracer process
+//  channel was full
+//
+func (tj *TraceJSON) Dropped() uint64 {
+	return atomic.LoadUint64(&tj.dropped)
+}
+
+//
+// Close writes buffered events, closes the file and stops tracer process.
+//  Returns first write error
+//
+func (tj *TraceJSON) Close() error {
+	_, err := tj.pid.Call(traceJSONClose{})
+	return err
+}
+
+// ---------------------------------------------------------------------------
+// Locals
+// ---------------------------------------------------------------------------
+
+//
+// Encoded lines of events and the first encoding error
+//
+type traceJSONLines struct {
+	data [][]byte
+	err  error
+}
+
+type traceJSONClose struct{}
+
+type traceJSONOut interface {
+	io.Writer
+	Flush() error
+	Close() error
+}
+
+//
+// Line of the event
+//
+type traceJSONLine struct {
+	Event    string      `json:"event"`
+	Pid      string      `json:"pid"`
+	Tag      string      `json:"tag"`
+	Time     time.Time   `json:"time"`
+	Start    *time.Time  `json:"start,omitempty"`
+	Duration int64       `json:"duration_ns,omitempty"`
+	Arg      interface{} `json:"arg"`
+	Result   interface{} `json:"result,omitempty"`
+}
+
+func startTraceJSON(out traceJSONOut, opts *TraceJSONOpts) (*TraceJSON, error) {
+
+	if opts == nil {
+		opts = NewTraceJSONOpts()
+	}
+	formatter := opts.formatter
+	if formatter == nil {
+		formatter = TraceFormatJSON()
+	}
+	bufSize := opts.bufSize
+	if bufSize == 0 {
+		bufSize = 1024
+	}
+
+	pid, err := GenServerStartOpts(
+		&traceJSONGs{out: out}, NewSpawnOpts().WithUsrChannelSize(bufSize))
+	if err != nil {
+		return nil, err
+	}
+
+	return &TraceJSON{pid: pid, formatter: formatter}, nil
+}
+
+//
+// Tracer process
+//
+type traceJSONGs struct {
+	GenServerSys
+
+	out traceJSONOut
+	err error
+}
+
+func (gs *traceJSONGs) HandleCall(req Term, from From) Term {
+
+	switch req.(type) {
+	case traceJSONClose:
+		gs.setErr(gs.out.Close())
+		if gs.err != nil {
+			return gs.CallStop(ExitNormal, gs.err)
+		}
+		return gs.CallStop(ExitNormal, true)
+	}
+
+	return gs.CallReply(BadArgError)
+}
+
+func (gs *traceJSONGs) HandleInfo(req Term) Term {
+
+	switch req := req.(type) {
+	case traceJSONLines:
+		gs.setErr(req.err)
+		for _, data := range req.data {
+			_, err := gs.out.Write(data)
+			gs.setErr(err)
+		}
+		// flush when there are no more events
+		if len(gs.Self().usrChan) == 0 {
+			gs.setErr(gs.out.Flush())
+		}
+	}
+
+	return gs.NoReply()
+}
+
+//
+// encode returns line of the event, nil if the event is not written
+//
+func (tj *TraceJSON) encode(evt Term) ([]byte, error) {
+
+	var line *traceJSONLine
+
+	switch evt := evt.(type) {
+
+	case *Call:
+		line = &traceJSONLine{
+			Event: "call",
+			Pid:   evt.Pid.String(),
+			Tag:   evt.Tag,
+			Time:  *evt.Time,
+			Arg:   tj.formatter(evt.Arg),
+		}
+
+	case *CallResult:
+		start := evt.Time.Add(-evt.Duration)
+		line = &traceJSONLine{
+			Event:    "result",
+			Pid:      evt.Pid.String(),
+			Tag:      evt.Tag,
+			Time:     *evt.Time,
+			Start:    &start,
+			Duration: int64(evt.Duration),
+			Arg:      tj.formatter(evt.Arg),
+			Result:   tj.formatter(evt.Result),
+		}
+
+	default:
+		return nil, nil
+	}
+
+	data, err := json.Marshal(line)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(data, '\n'), nil
+}
+
+func (gs *traceJSONGs) setErr(err error) {
+	if gs.err == nil {
+		gs.err = err
+	}
+}
+
+//
+// Buffered writer
+//
+type traceJSONWriter struct {
+	*bufio.Writer
+}
+
+func (w *traceJSONWriter) Close() error {
+	return w.Flush()
+}
+
+//
+// Rotating file. Each Write is a whole line, lines are not split between
+//  files
+//
+type traceRotateFile struct {
+	path     string
+	maxSize  int64
+	maxFiles int
+
+	f    *os.File
+	bw   *bufio.Writer
+	size int64
+}
+
+func openTraceRotateFile(
+	path string, maxSize int64, maxFiles int) (*traceRotateFile, error) {
+
+	if maxFiles == 0 {
+		maxFiles = 5
+	}
+
+	rf := &traceRotateFile{path: path, maxSize: maxSize, maxFiles: maxFiles}
+	if err := rf.open(); err != nil {
+		return nil, err
+	}
+
+	return rf, nil
+}
+
+func (rf *traceRotateFile) open() error {
+
+	f, err := os.OpenFile(rf.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	rf.f = f
+	rf.bw = bufio.NewWriter(f)
+	rf.size = fi.Size()
+
+	return nil
+}
+
+func (rf *traceRotateFile) Write(p []byte) (int, error) {
+
+	if rf.maxSize > 0 && rf.size > 0 && rf.size+int64(len(p)) > rf.maxSize {
+		if err := rf.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := rf.bw.Write(p)
+	rf.size += int64(n)
+
+	return n, err
+}
+
+//
+// rotate renames path.N-1 to path.N, ..., path to path.1
+//
+func (rf *traceRotateFile) rotate() error {
+
+	if err := rf.Close(); err != nil {
+		return err
+	}
+
+	for i := rf.maxFiles - 1; i > 0; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", rf.path, i),
+			fmt.Sprintf("%s.%d", rf.path, i+1))
+	}
+	if err := os.Rename(rf.path, rf.path+".1"); err != nil {
+		return err
+	}
+
+	return rf.open()
+}
+
+func (rf *traceRotateFile) Flush() error {
+	return rf.bw.Flush()
+}
+
+func (rf *traceRotateFile) Close() error {
+
+	err := rf.bw.Flush()
+	if cerr := rf.f.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
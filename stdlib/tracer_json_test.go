@@ -0,0 +1,183 @@
+package stdlib
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTraceJSON(t *testing.T) {
+
+	var buf bytes.Buffer
+
+	tj, err := TraceToJSON(&buf, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pid, err := GenServerStartOpts(
+		new(GenServerSys), NewSpawnOpts().WithTracer(tj))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = pid.Call(map[string]int{"a": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err = pid.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tj.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		calls, results int
+		found          bool
+	)
+
+	sc := bufio.NewScanner(&buf)
+	for sc.Scan() {
+		var line map[string]interface{}
+		if err := json.Unmarshal(sc.Bytes(), &line); err != nil {
+			t.Fatalf("bad line %s: %s", sc.Text(), err)
+		}
+		if line["pid"] != pid.String() {
+			t.Fatalf("expected pid %s, actual %v", pid, line["pid"])
+		}
+
+		switch line["event"] {
+		case "call":
+			calls++
+		case "result":
+			results++
+			if _, ok := line["duration_ns"]; !ok {
+				t.Fatalf("expected duration in %s", sc.Text())
+			}
+		}
+
+		if line["tag"] == traceFuncDoCall {
+			arg, _ := line["arg"].(map[string]interface{})
+			found = found || arg["a"] == float64(1)
+		}
+	}
+
+	if calls == 0 || calls != results {
+		t.Fatalf("expected equal count of calls and results, actual %d, %d",
+			calls, results)
+	}
+	if !found {
+		t.Fatalf("expected HandleCall event with JSON argument:\n%s",
+			buf.String())
+	}
+}
+
+func TestTraceJSONFormatter(t *testing.T) {
+
+	type bStruct struct {
+		A int
+		B []int
+	}
+
+	cases := []struct {
+		f        TraceTermFormatter
+		arg      Term
+		expected string
+	}{
+		{TraceFormatSprint(5), "long argument", `"arg":"long ..."`},
+		{TraceFormatSprint(0), bStruct{1, nil}, `"arg":"{A:1 B:[]}"`},
+		{TraceFormatJSON(), bStruct{1, nil}, `"arg":{"A":1,"B":null}`},
+		{TraceFormatJSON(), make(chan int), `"arg":"0x`},
+	}
+
+	for _, c := range cases {
+
+		var buf bytes.Buffer
+
+		tj, err := TraceToJSON(&buf, NewTraceJSONOpts().WithFormatter(c.f))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		TraceCall(tj, nil, "tag", c.arg)
+
+		if err = tj.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		if !strings.Contains(buf.String(), c.expected) {
+			t.Fatalf("expected %s, actual %s", c.expected, buf.String())
+		}
+	}
+}
+
+func TestTraceJSONEncodesOnEvent(t *testing.T) {
+
+	var buf bytes.Buffer
+
+	tj, err := TraceToJSON(&buf, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// argument changed after the event is written as it was
+	arg := map[string]int{"a": 1}
+	TraceCall(tj, nil, "tag", arg)
+	arg["a"] = 2
+
+	if err = tj.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := `"arg":{"a":1}`; !strings.Contains(buf.String(), expected) {
+		t.Fatalf("expected %s, actual %s", expected, buf.String())
+	}
+}
+
+func TestTraceJSONFileRotation(t *testing.T) {
+
+	dir := gtsTestDir(t)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "trace.jsonl")
+
+	tj, err := TraceToJSONFile(path, NewTraceJSONOpts().
+		WithMaxFileSize(512).
+		WithMaxFiles(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 100; i++ {
+		TraceCall(tj, nil, "tag", fmt.Sprintf("event %d", i))
+	}
+
+	if err = tj.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{path, path + ".1", path + ".2"} {
+		data, err := ioutil.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(data) > 512 || !bytes.HasSuffix(data, []byte("\n")) {
+			t.Fatalf("%s: unexpected size %d or split line", name, len(data))
+		}
+	}
+
+	if _, err = os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Fatalf("expected only 2 rotated files, actual %v", err)
+	}
+
+	if tj.Dropped() != 0 {
+		t.Fatalf("expected no dropped events, actual %d", tj.Dropped())
+	}
+}
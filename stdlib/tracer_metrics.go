@@ -0,0 +1,542 @@
+package stdlib
+
+//
+// Metrics tracer. Aggregates trace events into histograms of callbacks
+// durations, counters of messages, exits, crashes and restarts and gauges of
+// mailbox depth. Metrics are rendered in Prometheus text exposition format.
+//
+// Labels: behaviour is a type of GenProc object of the process, name is a
+// registered name of the process or the name it was spawned with, tag is a
+// tag of *CallResult event. Message and restart counters require trace flags
+// TraceFlagSend, TraceFlagReceive and TraceFlagProcs. Mailbox gauges are
+// reported for alive processes seen in events, dead processes are pruned
+// when count of processes doubles.
+//
+// Metrics are striped over shards by name or pid of the process, so events
+// of different processes do not wait for one lock
+//
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+//
+// TraceMetricsOpts is the structure to hold options of metrics tracer
+//
+type TraceMetricsOpts struct {
+	namespace string
+	buckets   []time.Duration
+}
+
+//
+// NewTraceMetricsOpts makes options object and returns object to manipulate
+//
+func NewTraceMetricsOpts() *TraceMetricsOpts {
+	return new(TraceMetricsOpts)
+}
+
+//
+// WithNamespace sets prefix of metric names. Default is "goa"
+//
+func (op *TraceMetricsOpts) WithNamespace(ns string) *TraceMetricsOpts {
+
+	op.namespace = ns
+
+	return op
+}
+
+//
+// WithBuckets sets upper bounds of histogram buckets of callbacks durations
+//
+func (op *TraceMetricsOpts) WithBuckets(
+	buckets ...time.Duration) *TraceMetricsOpts {
+
+	op.buckets = buckets
+
+	return op
+}
+
+//
+// TraceMetrics is a metrics tracer and http.Handler of metrics
+//
+type TraceMetrics struct {
+	// count of processes of mailbox gauges and the count to prune dead ones
+	//  at, first to be aligned for atomic access
+	pidsCount int64
+	pidsPrune int64
+	pruning   int32
+
+	namespace string
+	buckets   []time.Duration
+
+	shards [traceMetricsShards]traceMetricsShard
+
+	// *Pid -> struct{} of mailbox gauges
+	pids sync.Map
+}
+
+//
+// NewTraceMetrics makes metrics tracer with given options
+//
+func NewTraceMetrics(opts *TraceMetricsOpts) *TraceMetrics {
+
+	if opts == nil {
+		opts = NewTraceMetricsOpts()
+	}
+
+	buckets := opts.buckets
+	if len(buckets) == 0 {
+		buckets = traceMetricsBuckets
+	}
+
+	tm := &TraceMetrics{
+		pidsPrune: traceMetricsPrune,
+		namespace: opts.namespace,
+		buckets:   append([]time.Duration(nil), buckets...),
+	}
+	for i := range tm.shards {
+		tm.shards[i].init()
+	}
+
+	if tm.namespace == "" {
+		tm.namespace = "goa"
+	}
+	sort.Slice(tm.buckets, func(i, j int) bool {
+		return tm.buckets[i] < tm.buckets[j]
+	})
+
+	return tm
+}
+
+//
+// Event implements Tracer
+//
+func (tm *TraceMetrics) Event(events ...Term) {
+
+	for _, evt := range events {
+
+		pid := TraceEventPid(evt)
+		if pid == nil {
+			continue
+		}
+
+		key := traceMetricKey{
+			behaviour: pid.behaviour,
+			name:      traceMetricName(pid),
+		}
+
+		s := tm.shard(pid, key.name)
+		s.mu.Lock()
+		exited := s.event(tm.buckets, key, evt)
+		s.mu.Unlock()
+
+		if exited {
+			tm.removePid(pid)
+		} else {
+			tm.addPid(pid)
+		}
+	}
+}
+
+//
+// ServeHTTP implements http.Handler
+//
+func (tm *TraceMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_ = tm.WritePrometheus(w)
+}
+
+//
+// WritePrometheus writes metrics in Prometheus text exposition format
+//
+func (tm *TraceMetrics) WritePrometheus(w io.Writer) error {
+
+	m := tm.merge()
+	pw := &traceMetricsWriter{w: w}
+
+	name := tm.namespace + "_callback_duration_seconds"
+	pw.header(name, "histogram", "Duration of process callbacks.")
+	for _, key := range sortedTraceMetricKeys(m.durations) {
+		h := m.durations[key]
+		labels := key.labels("tag")
+		for i, le := range tm.buckets {
+			pw.sample(name+"_bucket", labels+`,le="`+
+				formatTraceMetric(le.Seconds())+`"`, float64(h.counts[i]))
+		}
+		pw.sample(name+"_bucket", labels+`,le="+Inf"`, float64(h.count))
+		pw.sample(name+"_sum", labels, h.sum.Seconds())
+		pw.sample(name+"_count", labels, float64(h.count))
+	}
+
+	tm.writeCounter(pw, tm.namespace+"_messages_total",
+		"Messages sent to and received by processes.",
+		"direction", m.messages)
+	tm.writeCounter(pw, tm.namespace+"_exits_total",
+		"Exits of processes.", "normal", m.exits)
+	tm.writeCounter(pw, tm.namespace+"_crashes_total",
+		"Panics recovered in processes.", "", m.crashes)
+	tm.writeCounter(pw, tm.namespace+"_restarts_total",
+		"Spawns of processes with the name of exited process.", "",
+		m.restarts)
+
+	name = tm.namespace + "_mailbox_depth"
+	pw.header(name, "gauge", "Count of messages in process channels.")
+	for _, pid := range tm.sortedPids() {
+		if pid.Alive() != nil {
+			tm.removePid(pid)
+			continue
+		}
+		key := traceMetricKey{
+			behaviour: pid.behaviour,
+			name:      traceMetricName(pid),
+		}
+		labels := key.labels("") + `,pid="` + pid.String() + `"`
+		pw.sample(name, labels+`,channel="usr"`, float64(len(pid.usrChan)))
+		pw.sample(name, labels+`,channel="sys"`, float64(len(pid.sysChan)))
+	}
+
+	return pw.err
+}
+
+// ---------------------------------------------------------------------------
+// Locals
+// ---------------------------------------------------------------------------
+
+const (
+	traceMetricsShards = 16
+	// min count of processes of mailbox gauges to prune dead processes at
+	traceMetricsPrune = 1024
+)
+
+var traceMetricsBuckets = []time.Duration{
+	10 * time.Microsecond,
+	50 * time.Microsecond,
+	100 * time.Microsecond,
+	500 * time.Microsecond,
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+}
+
+//
+// Shard of metrics. Restarts are counted by shard of the name, so spawns and
+//  exits of processes with one name are seen by one shard
+//
+type traceMetricsShard struct {
+	mu        sync.Mutex
+	durations map[traceMetricKey]*traceHistogram
+	messages  map[traceMetricKey]uint64
+	exits     map[traceMetricKey]uint64
+	crashes   map[traceMetricKey]uint64
+	restarts  map[traceMetricKey]uint64
+
+	// names of exited processes to count restarts
+	exitedNames map[string]struct{}
+}
+
+func (s *traceMetricsShard) init() {
+	s.durations = make(map[traceMetricKey]*traceHistogram)
+	s.messages = make(map[traceMetricKey]uint64)
+	s.exits = make(map[traceMetricKey]uint64)
+	s.crashes = make(map[traceMetricKey]uint64)
+	s.restarts = make(map[traceMetricKey]uint64)
+	s.exitedNames = make(map[string]struct{})
+}
+
+//
+// event counts the event of the shard locked by caller, returns true if the
+//  process exited
+//
+func (s *traceMetricsShard) event(
+	buckets []time.Duration, key traceMetricKey, evt Term) bool {
+
+	switch evt := evt.(type) {
+
+	case *CallResult:
+		key.label = evt.Tag
+		h, ok := s.durations[key]
+		if !ok {
+			h = &traceHistogram{counts: make([]uint64, len(buckets))}
+			s.durations[key] = h
+		}
+		h.observe(buckets, evt.Duration)
+
+	case *SendEvent:
+		key.label = "send"
+		s.messages[key]++
+
+	case *ReceiveEvent:
+		key.label = "receive"
+		s.messages[key]++
+
+	case *SpawnEvent:
+		if _, ok := s.exitedNames[key.name]; ok {
+			delete(s.exitedNames, key.name)
+			s.restarts[key]++
+		}
+
+	case *ExitEvent:
+		key.label = strconv.FormatBool(evt.Reason == ExitNormal)
+		s.exits[key]++
+		if key.name != "" {
+			s.exitedNames[key.name] = struct{}{}
+		}
+		return true
+
+	case *CrashEvent:
+		s.crashes[key]++
+	}
+
+	return false
+}
+
+func (tm *TraceMetrics) shard(pid *Pid, name string) *traceMetricsShard {
+	if name != "" {
+		return &tm.shards[regHashString(name)%traceMetricsShards]
+	}
+	return &tm.shards[regHashUint(pid.id)%traceMetricsShards]
+}
+
+//
+// merge returns sum of metrics of the shards
+//
+func (tm *TraceMetrics) merge() *traceMetricsShard {
+
+	m := new(traceMetricsShard)
+	m.init()
+
+	for i := range tm.shards {
+		s := &tm.shards[i]
+		s.mu.Lock()
+		for k, h := range s.durations {
+			mh, ok := m.durations[k]
+			if !ok {
+				mh = &traceHistogram{counts: make([]uint64, len(h.counts))}
+				m.durations[k] = mh
+			}
+			mh.add(h)
+		}
+		for _, c := range []struct{ to, from map[traceMetricKey]uint64 }{
+			{m.messages, s.messages},
+			{m.exits, s.exits},
+			{m.crashes, s.crashes},
+			{m.restarts, s.restarts},
+		} {
+			for k, v := range c.from {
+				c.to[k] += v
+			}
+		}
+		s.mu.Unlock()
+	}
+
+	return m
+}
+
+//
+// traceMetricName returns registered name of the process, the name it was
+//  spawned with if it is not registered
+//
+func traceMetricName(pid *Pid) string {
+	if pid.remote == nil && pid.env != nil {
+		if name := pid.env.eGs.regName(pid); name != "" {
+			return name
+		}
+	}
+	return pid.spawnName
+}
+
+//
+// Processes of mailbox gauges
+//
+func (tm *TraceMetrics) addPid(pid *Pid) {
+
+	if pid.remote != nil {
+		return
+	}
+	if _, ok := tm.pids.Load(pid); ok {
+		return
+	}
+	if _, loaded := tm.pids.LoadOrStore(pid, struct{}{}); loaded {
+		return
+	}
+
+	if atomic.AddInt64(&tm.pidsCount, 1) >= atomic.LoadInt64(&tm.pidsPrune) {
+		tm.prunePids()
+	}
+}
+
+func (tm *TraceMetrics) removePid(pid *Pid) {
+	if _, ok := tm.pids.LoadAndDelete(pid); ok {
+		atomic.AddInt64(&tm.pidsCount, -1)
+	}
+}
+
+//
+// prunePids removes dead processes and sets count to prune at to twice the
+//  count of alive processes, so pruning is amortized over added processes
+//
+func (tm *TraceMetrics) prunePids() {
+
+	if !atomic.CompareAndSwapInt32(&tm.pruning, 0, 1) {
+		return
+	}
+
+	tm.pids.Range(func(k, _ interface{}) bool {
+		if pid := k.(*Pid); pid.Alive() != nil {
+			tm.removePid(pid)
+		}
+		return true
+	})
+
+	prune := 2 * atomic.LoadInt64(&tm.pidsCount)
+	if prune < traceMetricsPrune {
+		prune = traceMetricsPrune
+	}
+	atomic.StoreInt64(&tm.pidsPrune, prune)
+
+	atomic.StoreInt32(&tm.pruning, 0)
+}
+
+type traceMetricKey struct {
+	behaviour string
+	name      string
+	label     string
+}
+
+func (k traceMetricKey) labels(labelName string) string {
+	s := `behaviour="` + escapeTraceMetricLabel(k.behaviour) +
+		`",name="` + escapeTraceMetricLabel(k.name) + `"`
+	if labelName != "" {
+		s += `,` + labelName + `="` + escapeTraceMetricLabel(k.label) + `"`
+	}
+	return s
+}
+
+func sortedTraceMetricKeys(m interface{}) []traceMetricKey {
+
+	var keys []traceMetricKey
+
+	switch m := m.(type) {
+	case map[traceMetricKey]*traceHistogram:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	case map[traceMetricKey]uint64:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.behaviour != b.behaviour {
+			return a.behaviour < b.behaviour
+		}
+		if a.name != b.name {
+			return a.name < b.name
+		}
+		return a.label < b.label
+	})
+
+	return keys
+}
+
+func (tm *TraceMetrics) sortedPids() []*Pid {
+
+	pids := make([]*Pid, 0, atomic.LoadInt64(&tm.pidsCount))
+	tm.pids.Range(func(k, _ interface{}) bool {
+		pids = append(pids, k.(*Pid))
+		return true
+	})
+
+	sort.Slice(pids, func(i, j int) bool {
+		if pids[i].env.id() != pids[j].env.id() {
+			return pids[i].env.id() < pids[j].env.id()
+		}
+		return pids[i].id < pids[j].id
+	})
+
+	return pids
+}
+
+func (tm *TraceMetrics) writeCounter(pw *traceMetricsWriter,
+	name, help, labelName string, m map[traceMetricKey]uint64) {
+
+	pw.header(name, "counter", help)
+	for _, key := range sortedTraceMetricKeys(m) {
+		pw.sample(name, key.labels(labelName), float64(m[key]))
+	}
+}
+
+//
+// Histogram with cumulative buckets
+//
+type traceHistogram struct {
+	counts []uint64
+	count  uint64
+	sum    time.Duration
+}
+
+func (h *traceHistogram) observe(buckets []time.Duration, d time.Duration) {
+	for i, le := range buckets {
+		if d <= le {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += d
+}
+
+func (h *traceHistogram) add(h2 *traceHistogram) {
+	for i, n := range h2.counts {
+		h.counts[i] += n
+	}
+	h.count += h2.count
+	h.sum += h2.sum
+}
+
+//
+// Text exposition writer, keeps first error
+//
+type traceMetricsWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (pw *traceMetricsWriter) header(name, typ, help string) {
+	pw.printf("# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+func (pw *traceMetricsWriter) sample(name, labels string, v float64) {
+	pw.printf("%s{%s} %s\n", name, labels, formatTraceMetric(v))
+}
+
+func (pw *traceMetricsWriter) printf(format string, args ...interface{}) {
+	if pw.err == nil {
+		_, pw.err = fmt.Fprintf(pw.w, format, args...)
+	}
+}
+
+func formatTraceMetric(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var traceMetricLabelEscaper = strings.NewReplacer(
+	`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+func escapeTraceMetricLabel(s string) string {
+	return traceMetricLabelEscaper.Replace(s)
+}
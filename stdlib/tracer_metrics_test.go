@@ -0,0 +1,137 @@
+package stdlib
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTraceMetrics(t *testing.T) {
+
+	tm := NewTraceMetrics(NewTraceMetricsOpts().
+		WithNamespace("test").
+		WithBuckets(time.Hour, time.Nanosecond))
+
+	e := NewEnv()
+	opts := NewSpawnOpts().
+		WithName("metrics").
+		WithTracer(tm).
+		WithTraceFlags(TraceFlagSend | TraceFlagReceive | TraceFlagProcs)
+
+	pid, err := e.GenServerStartOpts(new(ts), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err = pid.Call("ping"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err = pid.Call("crash"); err == nil {
+		t.Fatal("expected error, actual no error")
+	}
+
+	// restart with the same name
+	for i := 0; i < 100; i++ {
+		if pid, err = e.GenServerStartOpts(new(ts), opts); err == nil {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pid.Stop()
+
+	srv := httptest.NewServer(tm)
+	defer srv.Close()
+
+	resp, err := srv.Client().Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(body)
+
+	labels := `behaviour="*stdlib.ts",name="metrics"`
+	for _, line := range []string{
+		"# TYPE test_callback_duration_seconds histogram",
+		`test_callback_duration_seconds_bucket{` + labels +
+			`,tag="HandleCall",le="1e-09"} 0`,
+		`test_callback_duration_seconds_bucket{` + labels +
+			`,tag="HandleCall",le="3600"} 3`,
+		`test_callback_duration_seconds_count{` + labels +
+			`,tag="HandleCall"} 3`,
+		`test_messages_total{` + labels + `,direction="receive"} 4`,
+		`test_crashes_total{` + labels + `} 1`,
+		`test_exits_total{` + labels + `,normal="false"} 1`,
+		`test_restarts_total{` + labels + `} 1`,
+		`test_mailbox_depth{` + labels + `,pid="` + pid.String() +
+			`",channel="usr"} 0`,
+	} {
+		if !strings.Contains(out, line+"\n") {
+			t.Fatalf("expected line %s in:\n%s", line, out)
+		}
+	}
+}
+
+func TestTraceMetricsLabelEscape(t *testing.T) {
+	k := traceMetricKey{behaviour: `a"b`, name: "c\\d\ne"}
+	if s := k.labels(""); s != `behaviour="a\"b",name="c\\d\ne"` {
+		t.Fatalf("unexpected labels %s", s)
+	}
+}
+
+func TestTraceMetricsPids(t *testing.T) {
+
+	tm := NewTraceMetrics(NewTraceMetricsOpts().WithNamespace("test"))
+
+	e := NewEnv()
+	pid, err := e.GenServerStart(new(ts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pid.Stop()
+
+	// name registered after spawn is the label
+	if err = pid.Register("later"); err != nil {
+		t.Fatal(err)
+	}
+	tm.Event(&ReceiveEvent{TraceEvent: TraceEvent{Pid: pid}, Msg: "ping"})
+
+	var out strings.Builder
+	if err = tm.WritePrometheus(&out); err != nil {
+		t.Fatal(err)
+	}
+	line := `test_messages_total{behaviour="*stdlib.ts",name="later",` +
+		`direction="receive"} 1`
+	if !strings.Contains(out.String(), line+"\n") {
+		t.Fatalf("expected line %s in:\n%s", line, out.String())
+	}
+
+	// dead processes without exit events are pruned
+	for i := 0; i < traceMetricsPrune; i++ {
+		dead, err := e.GenServerStart(new(ts))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = dead.Stop(); err != nil {
+			t.Fatal(err)
+		}
+		tm.Event(&SendEvent{TraceEvent: TraceEvent{Pid: dead}, Msg: "ping"})
+	}
+	if n := atomic.LoadInt64(&tm.pidsCount); n > traceMetricsPrune/2 {
+		t.Fatalf("expected dead processes pruned, actual %d processes", n)
+	}
+	if _, ok := tm.pids.Load(pid); !ok {
+		t.Fatalf("expected alive process %s kept", pid)
+	}
+}
@@ -0,0 +1,312 @@
+package stdlib
+
+//
+// Flight recorder tracer. Keeps last events of each process or of each Env in
+// ring buffers for post-mortem debugging. Recent events of the process are
+// attached to *CrashEvent, so they are printed in the crash report of
+// GenServerSys. Each ring has its own lock, the map of rings is locked
+// exclusively only to add or release rings
+//
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	//
+	// flightRecorderExited is a count of exited processes whose events are
+	//  kept by per process recorder
+	//
+	flightRecorderExited = 64
+	//
+	// flightRecorderRings is a max count of rings of the recorder
+	//
+	flightRecorderRings = 4096
+)
+
+//
+// FlightRecorder is a tracer keeping last events in ring buffers
+//
+type FlightRecorder struct {
+	mu       sync.RWMutex
+	size     int
+	perEnv   bool
+	maxRings int
+	// *Pid or *Env -> ring
+	rings  map[Term]*traceRing
+	exited []*Pid
+}
+
+//
+// NewFlightRecorder makes recorder keeping last size events of each process.
+//  Events of exited process are released after flightRecorderExited other
+//  processes exit. When count of
+//  rings reaches flightRecorderRings rings of dead processes and then least
+//  recently used rings are released
+//
+func NewFlightRecorder(size int) *FlightRecorder {
+	return newFlightRecorder(size, false)
+}
+
+//
+// NewEnvFlightRecorder makes recorder keeping last size events of processes
+//  of each Env in one ring buffer
+//
+func NewEnvFlightRecorder(size int) *FlightRecorder {
+	return newFlightRecorder(size, true)
+}
+
+//
+// Event implements Tracer
+//
+func (fr *FlightRecorder) Event(events ...Term) {
+
+	for _, evt := range events {
+
+		pid := TraceEventPid(evt)
+		if pid == nil {
+			continue
+		}
+
+		ring := fr.ring(pid)
+
+		ring.mu.Lock()
+		if evt, ok := evt.(*CrashEvent); ok {
+			evt.Recent = append(evt.Recent, fr.ringEvents(ring, pid)...)
+		}
+		ring.put(evt)
+		ring.mu.Unlock()
+
+		if _, ok := evt.(*ExitEvent); ok && !fr.perEnv {
+			fr.mu.Lock()
+			fr.exit(pid)
+			fr.mu.Unlock()
+		}
+	}
+}
+
+//
+// Events returns last events of the process
+//
+func (fr *FlightRecorder) Events(pid *Pid) []Term {
+
+	fr.mu.RLock()
+	ring, ok := fr.rings[fr.key(pid)]
+	fr.mu.RUnlock()
+
+	if !ok {
+		return nil
+	}
+
+	ring.mu.Lock()
+	defer ring.mu.Unlock()
+
+	return fr.ringEvents(ring, pid)
+}
+
+//
+// EnvEvents returns last events of processes of the environment recorded by
+//  recorder made with NewEnvFlightRecorder
+//
+func (fr *FlightRecorder) EnvEvents(e *Env) []Term {
+
+	fr.mu.RLock()
+	ring, ok := fr.rings[e]
+	fr.mu.RUnlock()
+
+	if !ok {
+		return nil
+	}
+
+	ring.mu.Lock()
+	defer ring.mu.Unlock()
+
+	return ring.events()
+}
+
+//
+// Dump writes last events of the process to w
+//
+func (fr *FlightRecorder) Dump(w io.Writer, pid *Pid) {
+	dumpTraceEvents(w, fr.Events(pid))
+}
+
+//
+// DumpEnv writes last events of the environment to w
+//
+func (fr *FlightRecorder) DumpEnv(w io.Writer, e *Env) {
+	dumpTraceEvents(w, fr.EnvEvents(e))
+}
+
+// ---------------------------------------------------------------------------
+// Locals
+// ---------------------------------------------------------------------------
+
+func newFlightRecorder(size int, perEnv bool) *FlightRecorder {
+	if size <= 0 {
+		size = 1
+	}
+	return &FlightRecorder{
+		size:     size,
+		perEnv:   perEnv,
+		maxRings: flightRecorderRings,
+		rings:    make(map[Term]*traceRing),
+	}
+}
+
+func (fr *FlightRecorder) key(pid *Pid) Term {
+	if fr.perEnv {
+		return pid.env
+	}
+	return pid
+}
+
+//
+// ring returns ring of the process, marked as used now
+//
+func (fr *FlightRecorder) ring(pid *Pid) *traceRing {
+
+	key := fr.key(pid)
+
+	fr.mu.RLock()
+	ring, ok := fr.rings[key]
+	fr.mu.RUnlock()
+
+	if !ok {
+		fr.mu.Lock()
+		if ring, ok = fr.rings[key]; !ok {
+			if len(fr.rings) >= fr.maxRings {
+				fr.release()
+			}
+			ring = newTraceRing(fr.size)
+			fr.rings[key] = ring
+		}
+		fr.mu.Unlock()
+	}
+
+	atomic.StoreInt64(&ring.used, time.Now().UnixNano())
+
+	return ring
+}
+
+//
+// ringEvents returns events of the process in the ring locked by caller
+//
+func (fr *FlightRecorder) ringEvents(ring *traceRing, pid *Pid) []Term {
+
+	events := ring.events()
+	if !fr.perEnv {
+		return events
+	}
+
+	pidEvents := events[:0]
+	for _, evt := range events {
+		if TraceEventPid(evt) == pid {
+			pidEvents = append(pidEvents, evt)
+		}
+	}
+	return pidEvents
+}
+
+//
+// release releases rings of dead processes, except recently exited, and then
+//  least recently used rings down to 7/8 of max count, so it is not called
+//  for each new ring
+//
+func (fr *FlightRecorder) release() {
+
+	target := fr.maxRings - fr.maxRings/8 - 1
+
+	if !fr.perEnv {
+		exited := make(map[Term]bool, len(fr.exited))
+		for _, pid := range fr.exited {
+			exited[pid] = true
+		}
+		for key := range fr.rings {
+			pid := key.(*Pid)
+			if !exited[key] && pid.remote == nil && pid.Alive() != nil {
+				delete(fr.rings, key)
+			}
+		}
+	}
+	if len(fr.rings) <= target {
+		return
+	}
+
+	type keyUsed struct {
+		key  Term
+		used int64
+	}
+	lru := make([]keyUsed, 0, len(fr.rings))
+	for key, ring := range fr.rings {
+		lru = append(lru, keyUsed{key, atomic.LoadInt64(&ring.used)})
+	}
+	sort.Slice(lru, func(i, j int) bool { return lru[i].used < lru[j].used })
+
+	for _, ku := range lru[:len(lru)-target] {
+		delete(fr.rings, ku.key)
+	}
+}
+
+func (fr *FlightRecorder) exit(pid *Pid) {
+
+	fr.exited = append(fr.exited, pid)
+	if len(fr.exited) <= flightRecorderExited {
+		return
+	}
+
+	delete(fr.rings, fr.exited[0])
+	fr.exited[0] = nil
+	fr.exited = fr.exited[1:]
+}
+
+func dumpTraceEvents(w io.Writer, events []Term) {
+	for _, evt := range events {
+		if s := FormatTraceEvent(evt); s != "" {
+			fmt.Fprintln(w, s)
+		} else {
+			fmt.Fprintf(w, "%#v\n", evt)
+		}
+	}
+}
+
+//
+// Ring buffer of events, used is the time of the last use in nanoseconds
+//
+type traceRing struct {
+	used int64
+	mu   sync.Mutex
+	buf  []Term
+	next int
+	full bool
+}
+
+func newTraceRing(size int) *traceRing {
+	return &traceRing{buf: make([]Term, size)}
+}
+
+func (r *traceRing) put(evt Term) {
+	r.buf[r.next] = evt
+	r.next++
+	if r.next == len(r.buf) {
+		r.next = 0
+		r.full = true
+	}
+}
+
+//
+// events returns copy of events from oldest to newest
+//
+func (r *traceRing) events() []Term {
+	if !r.full {
+		return append([]Term(nil), r.buf[:r.next]...)
+	}
+	events := make([]Term, 0, len(r.buf))
+	events = append(events, r.buf[r.next:]...)
+	return append(events, r.buf[:r.next]...)
+}
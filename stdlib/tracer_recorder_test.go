@@ -0,0 +1,180 @@
+package stdlib
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFlightRecorderCrashReport(t *testing.T) {
+
+	fr := NewFlightRecorder(4)
+	tr := new(traceTestCollector)
+
+	pid, err := GenServerStartOpts(
+		new(ts), NewSpawnOpts().WithTracer(TracerChain(fr, tr)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err = pid.Call("ping"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err = pid.Call("crash"); err == nil {
+		t.Fatal("expected error, actual no error")
+	}
+
+	tr.waitFor(t, "*stdlib.CrashEvent", 1)
+
+	tr.mu.Lock()
+	var crash *CrashEvent
+	for _, evt := range tr.events {
+		if evt, ok := evt.(*CrashEvent); ok {
+			crash = evt
+		}
+	}
+	tr.mu.Unlock()
+
+	if len(crash.Recent) != 4 {
+		t.Fatalf("expected 4 recent events, actual %d", len(crash.Recent))
+	}
+	last, ok := crash.Recent[3].(*Call)
+	if !ok || last.Tag != traceFuncDoCall || last.Arg != "crash" {
+		t.Fatalf("expected last event HandleCall(crash), actual %#v",
+			crash.Recent[3])
+	}
+
+	var buf bytes.Buffer
+	fr.Dump(&buf, pid)
+	if !strings.Contains(buf.String(), "crash") {
+		t.Fatalf("expected crash event in dump, actual:\n%s", buf.String())
+	}
+}
+
+func TestFlightRecorderEnv(t *testing.T) {
+
+	fr := NewEnvFlightRecorder(3)
+
+	e := NewEnv()
+	opts := NewSpawnOpts().WithTracer(fr)
+
+	pid1, err := e.GenServerStartOpts(new(GenServerSys), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pid1.Stop()
+
+	pid2, err := e.GenServerStartOpts(new(GenServerSys), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pid2.Stop()
+
+	if _, err = pid1.Call(1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = pid2.Call(2); err != nil {
+		t.Fatal(err)
+	}
+
+	events := fr.EnvEvents(e)
+	if len(events) != 3 {
+		t.Fatalf("expected 3 events, actual %d", len(events))
+	}
+	last, ok := events[2].(*CallResult)
+	if !ok || !last.Pid.Equal(pid2) || last.Arg != 2 {
+		t.Fatalf("expected last event of %s, actual %#v", pid2, events[2])
+	}
+
+	for _, evt := range fr.Events(pid2) {
+		if !TraceEventPid(evt).Equal(pid2) {
+			t.Fatalf("expected events of %s, actual %#v", pid2, evt)
+		}
+	}
+}
+
+func TestFlightRecorderExited(t *testing.T) {
+
+	fr := NewFlightRecorder(2)
+	opts := NewSpawnOpts().
+		WithTracer(fr).
+		WithTraceFlags(TraceFlagProcs)
+
+	var first *Pid
+	for i := 0; i < flightRecorderExited+1; i++ {
+		pid, err := GenServerStartOpts(new(GenServerSys), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if first == nil {
+			first = pid
+		}
+		if err = pid.Stop(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if events := fr.Events(first); len(events) != 0 {
+		t.Fatalf("expected released events, actual %v", events)
+	}
+}
+
+func TestFlightRecorderRings(t *testing.T) {
+
+	fr := NewFlightRecorder(2)
+	fr.maxRings = 8
+
+	event := func(pid *Pid) {
+		fr.Event(&TraceEvent{Pid: pid, Time: time.Now()})
+	}
+
+	// dead processes not traced with TraceFlagProcs
+	dead, err := GenServerStart(new(GenServerSys))
+	if err != nil {
+		t.Fatal(err)
+	}
+	event(dead)
+	if err = dead.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	pids := make([]*Pid, fr.maxRings)
+	for i := range pids {
+		if pids[i], err = GenServerStart(new(GenServerSys)); err != nil {
+			t.Fatal(err)
+		}
+		defer pids[i].Stop()
+		event(pids[i])
+	}
+
+	if len(fr.Events(dead)) != 0 {
+		t.Fatal("expected released events of dead process")
+	}
+	if len(fr.rings) > fr.maxRings {
+		t.Fatalf("expected at most %d rings, actual %d",
+			fr.maxRings, len(fr.rings))
+	}
+
+	// least recently used
+	event(pids[0])
+	for i := 0; i < fr.maxRings; i++ {
+		pid, err := GenServerStart(new(GenServerSys))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer pid.Stop()
+		event(pid)
+	}
+
+	if len(fr.Events(pids[1])) != 0 {
+		t.Fatal("expected released events of least recently used process")
+	}
+	if len(fr.rings) > fr.maxRings {
+		t.Fatalf("expected at most %d rings, actual %d",
+			fr.maxRings, len(fr.rings))
+	}
+}
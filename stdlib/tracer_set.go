@@ -0,0 +1,173 @@
+package stdlib
+
+//
+// Runtime tracing control. Tracer of the live process is changed by system
+// message handled by the process, like dbg:p in Erlang
+//
+
+//
+// TraceOp is an operation on the tracer of the process
+//
+type TraceOp int
+
+//
+// Tracer operations
+//
+const (
+	// TraceOpSet sets or replaces tracer and trace flags
+	TraceOpSet TraceOp = iota
+	// TraceOpChain adds tracer to the chain of tracers of the process and
+	//  adds trace flags
+	TraceOpChain
+	// TraceOpRemove removes tracer and resets trace flags
+	TraceOpRemove
+)
+
+//
+// TraceReq is a system message to change tracer of the process
+//
+type TraceReq struct {
+	Op     TraceOp
+	Tracer Tracer
+	Flags  TraceFlags
+}
+
+//
+// SetTracer sets or replaces tracer of the live process
+//
+func (pid *Pid) SetTracer(t Tracer, flags TraceFlags) error {
+	return pid.traceReq(&TraceReq{TraceOpSet, t, flags})
+}
+
+//
+// ChainTracer adds tracer to tracers of the live process. Trace flags are
+//  added to flags of the process, so other tracers of the chain get events of
+//  added kinds too
+//
+func (pid *Pid) ChainTracer(t Tracer, flags TraceFlags) error {
+	return pid.traceReq(&TraceReq{TraceOpChain, t, flags})
+}
+
+//
+// RemoveTracer removes all tracers of the live process
+//
+func (pid *Pid) RemoveTracer() error {
+	return pid.traceReq(&TraceReq{Op: TraceOpRemove})
+}
+
+//
+// SetTracerPrefix sets tracer of processes registered with prefix in default
+//  environment. Returns count of changed processes
+//
+func SetTracerPrefix(prefix string, t Tracer, flags TraceFlags) (int, error) {
+	return env.SetTracerPrefix(prefix, t, flags)
+}
+
+//
+// ChainTracerPrefix adds tracer to processes registered with prefix in
+//  default environment. Returns count of changed processes
+//
+func ChainTracerPrefix(
+	prefix string, t Tracer, flags TraceFlags) (int, error) {
+
+	return env.ChainTracerPrefix(prefix, t, flags)
+}
+
+//
+// RemoveTracerPrefix removes tracers of processes registered with prefix in
+//  default environment. Returns count of changed processes
+//
+func RemoveTracerPrefix(prefix string) (int, error) {
+	return env.RemoveTracerPrefix(prefix)
+}
+
+//
+// SetTracerPrefix sets tracer of processes registered with prefix in
+//  specified environment. Returns count of changed processes
+//
+func (e *Env) SetTracerPrefix(
+	prefix string, t Tracer, flags TraceFlags) (int, error) {
+
+	return e.traceReqPrefix(prefix, &TraceReq{TraceOpSet, t, flags})
+}
+
+//
+// ChainTracerPrefix adds tracer to processes registered with prefix in
+//  specified environment. Returns count of changed processes
+//
+func (e *Env) ChainTracerPrefix(
+	prefix string, t Tracer, flags TraceFlags) (int, error) {
+
+	return e.traceReqPrefix(prefix, &TraceReq{TraceOpChain, t, flags})
+}
+
+//
+// RemoveTracerPrefix removes tracers of processes registered with prefix in
+//  specified environment. Returns count of changed processes
+//
+func (e *Env) RemoveTracerPrefix(prefix string) (int, error) {
+	return e.traceReqPrefix(prefix, &TraceReq{Op: TraceOpRemove})
+}
+
+// ---------------------------------------------------------------------------
+// Locals
+// ---------------------------------------------------------------------------
+
+func (pid *Pid) traceReq(r *TraceReq) error {
+	if r.Op != TraceOpRemove && r.Tracer == nil {
+		return BadArgError
+	}
+	_, err := pid.CallSys(r)
+	return err
+}
+
+//
+// traceReqPrefix sends request to each process with prefix. Processes exited
+//  meanwhile are skipped
+//
+func (e *Env) traceReqPrefix(prefix string, r *TraceReq) (int, error) {
+
+	regs, err := e.whereare(prefix)
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for _, pid := range regs {
+		switch err := pid.traceReq(r); {
+		case err == nil:
+			n++
+		case IsNoProcError(err):
+		default:
+			return n, err
+		}
+	}
+
+	return n, nil
+}
+
+//
+// doTraceReq changes tracer in the process goroutine
+//
+func (gps *GenProcSys) doTraceReq(r *TraceReq) {
+
+	switch r.Op {
+
+	case TraceOpSet:
+		gps.tracer = r.Tracer
+		gps.pid.setTrace(r.Tracer, r.Flags)
+
+	case TraceOpChain:
+		t, flags := r.Tracer, r.Flags
+		if pt := gps.pid.loadTrace(); pt != nil && gps.tracer != nil {
+			t = TracerChain(gps.tracer, t)
+			flags |= pt.flags
+		}
+		gps.tracer = t
+		gps.pid.setTrace(t, flags)
+
+	case TraceOpRemove:
+		gps.tracer = nil
+		gps.pid.setTrace(nil, 0)
+	}
+}
@@ -0,0 +1,108 @@
+package stdlib
+
+import (
+	"testing"
+)
+
+func TestTracerSet(t *testing.T) {
+
+	e := NewEnv()
+
+	pids := make([]*Pid, 2)
+	for i := range pids {
+		pid, err := e.GenServerStartOpts(new(ts),
+			NewSpawnOpts().WithPrefix("live").WithName(string(rune('a'+i))))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer pid.Stop()
+		pids[i] = pid
+	}
+	pid := pids[0]
+
+	calls := func(c *traceTestCollector) int {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		n := 0
+		for _, evt := range c.events {
+			if r, ok := evt.(*CallResult); ok && r.Tag == traceFuncDoCall {
+				n++
+			}
+		}
+		return n
+	}
+	ping := func(pid *Pid) {
+		if _, err := pid.Call("ping"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := pid.SetTracer(nil, TraceFlagAll); !IsBadArgError(err) {
+		t.Fatalf("expected BadArgError, actual %v", err)
+	}
+
+	tr1 := new(traceTestCollector)
+	if err := pid.SetTracer(tr1, TraceFlagReceive); err != nil {
+		t.Fatal(err)
+	}
+	if pid.TraceFlags() != TraceFlagReceive {
+		t.Fatalf("expected receive flag, actual %v", pid.TraceFlags())
+	}
+	ping(pid)
+	if n := calls(tr1); n != 1 {
+		t.Fatalf("expected 1 call event, actual %d", n)
+	}
+	if n := tr1.count("*stdlib.ReceiveEvent"); n != 1 {
+		t.Fatalf("expected 1 receive event, actual %d", n)
+	}
+
+	tr2 := new(traceTestCollector)
+	if err := pid.ChainTracer(tr2, TraceFlagSend); err != nil {
+		t.Fatal(err)
+	}
+	if pid.TraceFlags() != TraceFlagReceive|TraceFlagSend {
+		t.Fatalf("expected receive and send flags, actual %v", pid.TraceFlags())
+	}
+	ping(pid)
+	if n1, n2 := calls(tr1), calls(tr2); n1 != 2 || n2 != 1 {
+		t.Fatalf("expected 2 and 1 call events, actual %d and %d", n1, n2)
+	}
+
+	if err := pid.RemoveTracer(); err != nil {
+		t.Fatal(err)
+	}
+	ping(pid)
+	if n1, n2 := calls(tr1), calls(tr2); n1 != 2 || n2 != 1 {
+		t.Fatalf("expected 2 and 1 call events, actual %d and %d", n1, n2)
+	}
+
+	// all processes with prefix
+	tr3 := new(traceTestCollector)
+	n, err := e.SetTracerPrefix("live", tr3, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(pids) {
+		t.Fatalf("expected %d changed processes, actual %d", len(pids), n)
+	}
+	for _, pid := range pids {
+		ping(pid)
+	}
+	if n := calls(tr3); n != len(pids) {
+		t.Fatalf("expected %d call events, actual %d", len(pids), n)
+	}
+
+	if n, err = e.RemoveTracerPrefix("live"); err != nil || n != len(pids) {
+		t.Fatalf("expected %d changed processes, actual %d, %v",
+			len(pids), n, err)
+	}
+	ping(pid)
+	if n := calls(tr3); n != len(pids) {
+		t.Fatalf("expected %d call events, actual %d", len(pids), n)
+	}
+
+	if _, err = e.ChainTracerPrefix("dead", tr3, 0); !IsNotRegError(err) {
+		t.Fatalf("expected NotRegError, actual %v", err)
+	}
+}
@@ -0,0 +1,349 @@
+package stdlib
+
+//
+// Span tracer. Turns each callback invocation of GenServer into a span.
+// Processes with TraceFlagSpans set new span context for each callback, it is
+// returned by Pid.SpanContext while *Call, *CallResult and *CrashEvent events
+// of the callback are handled. Messages sent from the callback with Call and
+// Cast carry the span context in the trace token, so callbacks handling them
+// become child spans of the same trace. Span is made of *CallResult or
+// *CrashEvent event, so the tracer may be wrapped by sampling or filtering
+// tracers handling events synchronously
+//
+
+import (
+	crand "crypto/rand"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+//
+// SpanStatus is a status of the span
+//
+type SpanStatus int
+
+//
+// Span statuses
+//
+const (
+	SpanStatusUnset SpanStatus = iota
+	SpanStatusOk
+	SpanStatusError
+)
+
+//
+// SpanContext identifies the span of callback and its trace. ParentID is 0
+//  for the root span of the trace
+//
+type SpanContext struct {
+	TraceID  [16]byte
+	SpanID   uint64
+	ParentID uint64
+}
+
+//
+// Span is a callback invocation
+//
+type Span struct {
+	TraceID  [16]byte
+	SpanID   uint64
+	ParentID uint64
+
+	Name  string
+	Start time.Time
+	End   time.Time
+
+	Attributes map[string]string
+
+	Status        SpanStatus
+	StatusMessage string
+}
+
+//
+// SpanExporter is the interface that defines function to export ended spans
+//
+type SpanExporter interface {
+	ExportSpans(spans []*Span)
+}
+
+//
+// SpanTracer is a tracer making spans of callbacks
+//
+type SpanTracer struct {
+	mu        sync.Mutex
+	exporter  SpanExporter
+	batchSize int
+	batch     []*Span
+	// started callbacks by span id, used for spans of crashed callbacks
+	starts map[uint64]spanStart
+}
+
+//
+// NewSpanTracer makes span tracer. Ended spans are exported by batches of
+//  batchSize spans, rest of spans are exported by Flush
+//
+func NewSpanTracer(exporter SpanExporter, batchSize int) *SpanTracer {
+	if batchSize <= 0 {
+		batchSize = 1
+	}
+	return &SpanTracer{
+		exporter:  exporter,
+		batchSize: batchSize,
+		starts:    make(map[uint64]spanStart),
+	}
+}
+
+//
+// Event implements Tracer. Events without span context are skipped
+//
+func (st *SpanTracer) Event(events ...Term) {
+
+	for _, evt := range events {
+
+		switch evt := evt.(type) {
+
+		case *CallResult:
+			sc := evt.Pid.SpanContext()
+			if isSpanTag(evt.Tag) && sc.SpanID != 0 {
+				st.end(evt.Pid, sc, evt.Tag,
+					evt.Time.Add(-evt.Duration), *evt.Time, evt.Result)
+			}
+
+		case *Call:
+			sc := evt.Pid.SpanContext()
+			if isSpanTag(evt.Tag) && sc.SpanID != 0 {
+				st.start(sc.SpanID, spanStart{evt.Tag, *evt.Time})
+			}
+
+		case *CrashEvent:
+			if sc := evt.Pid.SpanContext(); sc.SpanID != 0 {
+				st.crash(evt, sc)
+			}
+		}
+	}
+}
+
+//
+// Flush exports ended spans
+//
+func (st *SpanTracer) Flush() {
+
+	st.mu.Lock()
+	batch := st.batch
+	st.batch = nil
+	st.mu.Unlock()
+
+	if len(batch) > 0 {
+		st.exporter.ExportSpans(batch)
+	}
+}
+
+//
+// SpanCollector is an in-process exporter keeping exported spans
+//
+type SpanCollector struct {
+	mu    sync.Mutex
+	spans []*Span
+}
+
+//
+// NewSpanCollector makes in-process exporter
+//
+func NewSpanCollector() *SpanCollector {
+	return new(SpanCollector)
+}
+
+//
+// ExportSpans implements SpanExporter
+//
+func (c *SpanCollector) ExportSpans(spans []*Span) {
+	c.mu.Lock()
+	c.spans = append(c.spans, spans...)
+	c.mu.Unlock()
+}
+
+//
+// Spans returns exported spans
+//
+func (c *SpanCollector) Spans() []*Span {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]*Span(nil), c.spans...)
+}
+
+//
+// Reset removes exported spans
+//
+func (c *SpanCollector) Reset() {
+	c.mu.Lock()
+	c.spans = nil
+	c.mu.Unlock()
+}
+
+//
+// SpanContext returns span context of current callback of the process, zero
+//  if the process has no TraceFlagSpans. Valid while the events of the
+//  callback are handled by the tracer of the process
+//
+func (pid *Pid) SpanContext() SpanContext {
+	if pid.TraceFlags()&TraceFlagSpans == 0 {
+		return SpanContext{}
+	}
+	if tok := pid.SeqTraceGet(); tok != nil {
+		return tok.Span
+	}
+	return SpanContext{}
+}
+
+// ---------------------------------------------------------------------------
+// Locals
+// ---------------------------------------------------------------------------
+
+// max number of callbacks kept for spans of crashes
+const spanMaxStarts = 4096
+
+type spanStart struct {
+	tag  string
+	time time.Time
+}
+
+var (
+	spanRandMu sync.Mutex
+	spanRand   = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+func newSpanID() uint64 {
+	spanRandMu.Lock()
+	defer spanRandMu.Unlock()
+
+	for {
+		if id := spanRand.Uint64(); id != 0 {
+			return id
+		}
+	}
+}
+
+func newTraceID() (id [16]byte) {
+	if _, err := crand.Read(id[:]); err != nil {
+		spanRandMu.Lock()
+		spanRand.Read(id[:])
+		spanRandMu.Unlock()
+	}
+	return
+}
+
+//
+// seqTraceSpan sets token with new span context of the callback if the
+//  process has TraceFlagSpans. Span is a child of span of the current token,
+//  new trace is started if the process has no token. Returns token to
+//  restore by seqTraceDone
+//
+func seqTraceSpan(pid *Pid) *SeqTraceToken {
+
+	prev := pid.SeqTraceGet()
+	if pid.TraceFlags()&TraceFlagSpans == 0 {
+		return prev
+	}
+
+	var tok SeqTraceToken
+	if prev != nil {
+		tok = *prev
+	} else {
+		tok.counter = new(uint64)
+	}
+	if tok.Span.TraceID == ([16]byte{}) {
+		tok.Span.TraceID = newTraceID()
+	}
+	tok.Span.ParentID = tok.Span.SpanID
+	tok.Span.SpanID = newSpanID()
+
+	pid.SeqTraceSet(&tok)
+
+	return prev
+}
+
+func isSpanTag(tag string) bool {
+	switch tag {
+	case traceFuncDoInit, traceFuncDoCall, traceFuncDoCast, traceFuncDoInfo,
+		traceFuncTerminate, traceFuncHSM:
+		return true
+	}
+	return false
+}
+
+//
+// start keeps start of the callback for the span of crash, arbitrary
+//  callback is dropped if there are too many
+//
+func (st *SpanTracer) start(spanID uint64, start spanStart) {
+
+	st.mu.Lock()
+	if len(st.starts) >= spanMaxStarts {
+		for id := range st.starts {
+			delete(st.starts, id)
+			break
+		}
+	}
+	st.starts[spanID] = start
+	st.mu.Unlock()
+}
+
+func (st *SpanTracer) crash(evt *CrashEvent, sc SpanContext) {
+
+	st.mu.Lock()
+	start, ok := st.starts[sc.SpanID]
+	st.mu.Unlock()
+	if !ok {
+		start = spanStart{"crash", evt.Time}
+	}
+
+	st.end(evt.Pid, sc, start.tag, start.time, evt.Time,
+		errors.New(evt.Reason))
+}
+
+//
+// end exports span of the callback with tag
+//
+func (st *SpanTracer) end(pid *Pid, sc SpanContext, tag string,
+	start, end time.Time, result Term) {
+
+	span := &Span{
+		TraceID:  sc.TraceID,
+		SpanID:   sc.SpanID,
+		ParentID: sc.ParentID,
+		Name:     tag,
+		Start:    start,
+		End:      end,
+		Attributes: map[string]string{
+			"pid":       pid.String(),
+			"name":      pid.spawnName,
+			"behaviour": pid.behaviour,
+			"tag":       tag,
+		},
+		Status: SpanStatusOk,
+	}
+	if err, ok := result.(error); ok {
+		span.Status = SpanStatusError
+		span.StatusMessage = err.Error()
+	}
+
+	st.mu.Lock()
+
+	delete(st.starts, sc.SpanID)
+
+	st.batch = append(st.batch, span)
+	var batch []*Span
+	if len(st.batch) >= st.batchSize {
+		batch = st.batch
+		st.batch = nil
+	}
+
+	st.mu.Unlock()
+
+	if batch != nil {
+		st.exporter.ExportSpans(batch)
+	}
+}
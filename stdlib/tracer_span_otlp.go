@@ -0,0 +1,207 @@
+package stdlib
+
+//
+// OTLP file exporter of spans. Each batch of spans is written as one line of
+// OTLP/JSON ExportTraceServiceRequest, the format of OpenTelemetry collector
+// file exporter and receiver
+//
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+//
+// SpanFileExporter writes spans to the file in OTLP/JSON format
+//
+type SpanFileExporter struct {
+	mu  sync.Mutex
+	w   io.WriteCloser
+	err error
+}
+
+//
+// NewSpanFileExporter opens file to append spans to
+//
+func NewSpanFileExporter(path string) (*SpanFileExporter, error) {
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SpanFileExporter{w: f}, nil
+}
+
+//
+// ExportSpans implements SpanExporter. Write errors are returned by Close
+//
+func (fe *SpanFileExporter) ExportSpans(spans []*Span) {
+
+	data, err := json.Marshal(newOtlpRequest(spans))
+	if err == nil {
+		data = append(data, '\n')
+	}
+
+	fe.mu.Lock()
+	defer fe.mu.Unlock()
+
+	if fe.err != nil || fe.w == nil {
+		return
+	}
+	if err != nil {
+		fe.err = err
+		return
+	}
+	_, fe.err = fe.w.Write(data)
+}
+
+//
+// Close closes the file and returns first error of the exporter
+//
+func (fe *SpanFileExporter) Close() error {
+
+	fe.mu.Lock()
+	defer fe.mu.Unlock()
+
+	if fe.w == nil {
+		return fe.err
+	}
+
+	if err := fe.w.Close(); err != nil && fe.err == nil {
+		fe.err = err
+	}
+	fe.w = nil
+
+	return fe.err
+}
+
+// ---------------------------------------------------------------------------
+// Locals
+// ---------------------------------------------------------------------------
+
+//
+// OTLP status codes
+//
+const (
+	otlpStatusUnset = 0
+	otlpStatusOk    = 1
+	otlpStatusError = 2
+)
+
+// SPAN_KIND_INTERNAL
+const otlpSpanKindInternal = 1
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+func newOtlpRequest(spans []*Span) *otlpRequest {
+
+	otlpSpans := make([]otlpSpan, 0, len(spans))
+	for _, s := range spans {
+		otlpSpans = append(otlpSpans, newOtlpSpan(s))
+	}
+
+	return &otlpRequest{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: otlpResource{
+				Attributes: []otlpAttribute{
+					{"service.name", otlpValue{"goa"}},
+				},
+			},
+			ScopeSpans: []otlpScopeSpans{{
+				Scope: otlpScope{Name: "goa"},
+				Spans: otlpSpans,
+			}},
+		}},
+	}
+}
+
+func newOtlpSpan(s *Span) otlpSpan {
+
+	span := otlpSpan{
+		// trace id is 16 bytes
+		TraceID:           fmt.Sprintf("%032x", s.TraceID),
+		SpanID:            fmt.Sprintf("%016x", s.SpanID),
+		Name:              s.Name,
+		Kind:              otlpSpanKindInternal,
+		StartTimeUnixNano: strconv.FormatInt(s.Start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(s.End.UnixNano(), 10),
+		Status:            otlpStatus{Message: s.StatusMessage},
+	}
+
+	if s.ParentID != 0 {
+		span.ParentSpanID = fmt.Sprintf("%016x", s.ParentID)
+	}
+
+	switch s.Status {
+	case SpanStatusOk:
+		span.Status.Code = otlpStatusOk
+	case SpanStatusError:
+		span.Status.Code = otlpStatusError
+	default:
+		span.Status.Code = otlpStatusUnset
+	}
+
+	keys := make([]string, 0, len(s.Attributes))
+	for k := range s.Attributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		span.Attributes = append(span.Attributes,
+			otlpAttribute{k, otlpValue{s.Attributes[k]}})
+	}
+
+	return span
+}
@@ -0,0 +1,217 @@
+package stdlib
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSpanTracer(t *testing.T) {
+
+	c := NewSpanCollector()
+	st := NewSpanTracer(c, 1)
+	// spans are made of results, calls are not needed
+	tr := TraceFilter(st, func(evt Term) bool {
+		_, ok := evt.(*Call)
+		return !ok
+	})
+
+	e := NewEnv()
+	done := make(chan Term, 1)
+
+	var next *Pid
+	pids := make([]*Pid, 3)
+	for i := range pids {
+		pid, err := e.GenServerStartOpts(&tsSeqPlain{next: next, done: done},
+			NewSpawnOpts().WithName(fmt.Sprintf("span%d", i)).
+				WithTracer(tr).WithTraceFlags(TraceFlagSpans))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer pid.Stop()
+		pids[len(pids)-1-i] = pid
+		next = pid
+	}
+
+	spansOf := func(tag string, n int) []*Span {
+		var spans []*Span
+		for i := 0; i < 100; i++ {
+			spans = spans[:0]
+			for _, s := range c.Spans() {
+				if s.Name == tag {
+					spans = append(spans, s)
+				}
+			}
+			if len(spans) >= n {
+				break
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		if len(spans) != n {
+			t.Fatalf("expected %d %s spans, actual %d", n, tag, len(spans))
+		}
+		return spans
+	}
+
+	checkChain := func(tag string) {
+		spans := spansOf(tag, len(pids))
+		byPid := make(map[string]*Span)
+		for _, s := range spans {
+			byPid[s.Attributes["pid"]] = s
+		}
+		var parent *Span
+		for _, pid := range pids {
+			s := byPid[pid.String()]
+			if s == nil {
+				t.Fatalf("no %s span of %s", tag, pid)
+			}
+			if s.Status != SpanStatusOk {
+				t.Fatalf("expected ok status of %s span, actual %v", tag, s.Status)
+			}
+			if parent == nil {
+				if s.ParentID != 0 || s.TraceID == ([16]byte{}) {
+					t.Fatalf("expected root %s span, actual parent %x",
+						tag, s.ParentID)
+				}
+			} else if s.ParentID != parent.SpanID || s.TraceID != parent.TraceID {
+				t.Fatalf("expected %s span child of %x/%x, actual %x/%x", tag,
+					parent.TraceID, parent.SpanID, s.TraceID, s.ParentID)
+			}
+			parent = s
+		}
+	}
+
+	spansOf(traceFuncDoInit, len(pids))
+
+	if _, err := pids[0].Call("call"); err != nil {
+		t.Fatal(err)
+	}
+	checkChain(traceFuncDoCall)
+
+	if err := pids[0].Cast("cast"); err != nil {
+		t.Fatal(err)
+	}
+	<-done
+	checkChain(traceFuncDoCast)
+
+	// traces of requests differ
+	if a, b := spansOf(traceFuncDoCall, len(pids))[0],
+		spansOf(traceFuncDoCast, len(pids))[0]; a.TraceID == b.TraceID {
+		t.Fatalf("expected different traces, actual %x", a.TraceID)
+	}
+
+	s := spansOf(traceFuncDoCall, len(pids))[0]
+	if s.Attributes["name"] == "" || s.Attributes["tag"] != traceFuncDoCall ||
+		s.Attributes["behaviour"] != "*stdlib.tsSeqPlain" {
+		t.Fatalf("unexpected attributes: %v", s.Attributes)
+	}
+
+	// token of spans must not leak to the process
+	for _, pid := range pids {
+		if pid.SeqTraceGet() != nil {
+			t.Fatalf("expected no token of %s", pid)
+		}
+	}
+}
+
+func TestSpanTracerCrash(t *testing.T) {
+
+	c := NewSpanCollector()
+	st := NewSpanTracer(c, 100)
+
+	pid, err := NewEnv().GenServerStartOpts(new(ts),
+		NewSpawnOpts().WithTracer(st).WithTraceFlags(TraceFlagSpans))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = pid.Call("crash"); err == nil {
+		t.Fatal("expected error, actual no error")
+	}
+
+	st.Flush()
+
+	var crashed *Span
+	for _, s := range c.Spans() {
+		if s.Name == traceFuncDoCall {
+			crashed = s
+		}
+	}
+	if crashed == nil {
+		t.Fatalf("no %s span: %v", traceFuncDoCall, c.Spans())
+	}
+	if crashed.Status != SpanStatusError || crashed.StatusMessage == "" ||
+		crashed.End.Before(crashed.Start) {
+		t.Fatalf("expected error status, actual %v %q",
+			crashed.Status, crashed.StatusMessage)
+	}
+}
+
+func TestSpanFileExporter(t *testing.T) {
+
+	path := filepath.Join(gtsTestDir(t), "spans.json")
+
+	fe, err := NewSpanFileExporter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Unix(1, 0)
+	fe.ExportSpans([]*Span{
+		{TraceID: [16]byte{15: 1}, SpanID: 2, Name: "HandleCall",
+			Start: start, End: start.Add(time.Second),
+			Attributes: map[string]string{"pid": "<0.1.0>"},
+			Status:     SpanStatusOk},
+		{TraceID: [16]byte{15: 1}, SpanID: 3, ParentID: 2, Name: "HandleCast",
+			Start: start, End: start,
+			Status: SpanStatusError, StatusMessage: "failed"},
+	})
+	if err = fe.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var lines []*otlpRequest
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		req := new(otlpRequest)
+		if err = json.Unmarshal(scanner.Bytes(), req); err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, req)
+	}
+
+	if len(lines) != 1 {
+		t.Fatalf("expected 1 line, actual %d", len(lines))
+	}
+	spans := lines[0].ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, actual %d", len(spans))
+	}
+
+	s := spans[0]
+	if s.TraceID != "00000000000000000000000000000001" ||
+		s.SpanID != "0000000000000002" || s.ParentSpanID != "" ||
+		s.StartTimeUnixNano != "1000000000" ||
+		s.EndTimeUnixNano != "2000000000" ||
+		s.Status.Code != otlpStatusOk ||
+		len(s.Attributes) != 1 || s.Attributes[0].Key != "pid" ||
+		s.Attributes[0].Value.StringValue != "<0.1.0>" {
+		t.Fatalf("unexpected span: %+v", s)
+	}
+
+	s = spans[1]
+	if s.ParentSpanID != "0000000000000002" ||
+		s.Status.Code != otlpStatusError || s.Status.Message != "failed" {
+		t.Fatalf("unexpected span: %+v", s)
+	}
+}